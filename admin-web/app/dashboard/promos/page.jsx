@@ -119,7 +119,9 @@ export default function PromosPage() {
         let message = `Upload failed (status ${response.status})`;
         try {
           const errorData = await response.json();
-          if (errorData && typeof errorData.error === 'string') {
+          if (errorData && typeof errorData.error?.message === 'string') {
+            message = errorData.error.message;
+          } else if (errorData && typeof errorData.error === 'string') {
             message = errorData.error;
           } else if (errorData && typeof errorData.message === 'string') {
             message = errorData.message;
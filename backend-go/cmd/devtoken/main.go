@@ -0,0 +1,91 @@
+// Command devtoken mints tokens accepted by a server whose auth.provider is
+// local, for trying the API in development without a Firebase project:
+//
+//	export MYPREMIER_AUTH_LOCAL_SECRET=$(openssl rand -hex 32)
+//	go run ./cmd/devtoken -uid alice -role admin
+//
+// The token is signed with the key in the auth.local section of the same
+// configuration the server loads, and printed to standard output.
+//
+// The server authorises these tokens from their role and active claims only
+// when access.change_store is memory or redis, which it is by default with
+// storage.backend memory:
+//
+//	export MYPREMIER_AUTH_PROVIDER=local
+//	go run ./cmd/server -storage memory
+//
+// With access.change_store storage, or with Firestore, every request reads
+// the users collection instead, and the UID needs a user document there or
+// admin routes answer 404 user_not_found.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+
+	"mypremier-backend/internal/auth"
+	"mypremier-backend/internal/config"
+)
+
+func main() {
+	if err := run(os.Args[1:], os.Stdout); err != nil {
+		fmt.Fprintf(os.Stderr, "devtoken: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+// run mints the token described by args and writes it to out
+func run(args []string, out io.Writer) error {
+	flags := flag.NewFlagSet("devtoken", flag.ContinueOnError)
+	configPath := flags.String("config", os.Getenv("MYPREMIER_CONFIG"), "path to a YAML or JSON config file")
+	uid := flags.String("uid", "dev-user", "UID of the user, the sub claim")
+	email := flags.String("email", "", "email of the user")
+	role := flags.String("role", "", "role claim, e.g. admin; empty for a customer without a role")
+	inactive := flags.Bool("inactive", false, "mark the user as deactivated in the claims")
+	ttl := flags.Duration("ttl", 0, "lifetime of the token (default auth.local.token_ttl)")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	cfg, err := config.Load(*configPath)
+	if err != nil {
+		return fmt.Errorf("failed to load configuration: %w", err)
+	}
+
+	p := auth.Principal{
+		UID:   *uid,
+		Email: *email,
+	}
+	if *role != "" {
+		names := make([]string, 0, len(cfg.Roles))
+		for _, r := range cfg.Roles {
+			names = append(names, r.Name)
+		}
+		if !slices.Contains(names, *role) {
+			return fmt.Errorf("unknown role %q, want one of %v", *role, names)
+		}
+		// The same claims user.ClaimsSync mirrors from the users collection
+		active := !*inactive
+		p.Role, p.Active = *role, &active
+	} else if *inactive {
+		return fmt.Errorf("-inactive needs -role: customers carry no status claim")
+	}
+
+	issuer, err := config.NewLocalIssuer(cfg.Auth.Local)
+	if err != nil {
+		return fmt.Errorf("invalid auth.local configuration: %w", err)
+	}
+	if *ttl <= 0 {
+		*ttl = cfg.Auth.Local.TokenTTL
+	}
+
+	token, err := issuer.Issue(p, *ttl)
+	if err != nil {
+		return fmt.Errorf("failed to mint token: %w", err)
+	}
+	_, err = fmt.Fprintln(out, token)
+	return err
+}
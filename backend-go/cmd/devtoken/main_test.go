@@ -0,0 +1,95 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"mypremier-backend/internal/config"
+)
+
+const testSecret = "0123456789abcdef0123456789abcdef"
+
+func TestRun(t *testing.T) {
+	t.Setenv("MYPREMIER_CONFIG", "")
+	t.Setenv("MYPREMIER_AUTH_LOCAL_SECRET", testSecret)
+
+	cfg, err := config.Load("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	issuer, err := config.NewLocalIssuer(cfg.Auth.Local)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name       string
+		args       []string
+		wantRole   string
+		wantActive interface{}
+		wantTTL    time.Duration
+	}{
+		{"customer", []string{"-uid", "bob"}, "", nil, cfg.Auth.Local.TokenTTL},
+		{"admin", []string{"-uid", "bob", "-role", "admin", "-email", "bob@example.com"}, "admin", true, cfg.Auth.Local.TokenTTL},
+		{"deactivated", []string{"-uid", "bob", "-role", "sales", "-inactive"}, "sales", false, cfg.Auth.Local.TokenTTL},
+		{"ttl", []string{"-uid", "bob", "-ttl", "5m"}, "", nil, 5 * time.Minute},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var out bytes.Buffer
+			if err := run(tt.args, &out); err != nil {
+				t.Fatal(err)
+			}
+
+			p, err := issuer.Verify(context.Background(), strings.TrimSpace(out.String()))
+			if err != nil {
+				t.Fatalf("Verify: %v", err)
+			}
+			if p.UID != "bob" || p.Role != tt.wantRole {
+				t.Errorf("principal = %+v, want bob with role %q", p, tt.wantRole)
+			}
+			if got := p.Claims["active"]; got != tt.wantActive {
+				t.Errorf("active claim = %v, want %v", got, tt.wantActive)
+			}
+			exp, _ := p.Claims["exp"].(float64)
+			if ttl := time.Unix(int64(exp), 0).Sub(p.IssuedAt); ttl != tt.wantTTL {
+				t.Errorf("lifetime = %s, want %s", ttl, tt.wantTTL)
+			}
+		})
+	}
+}
+
+func TestRunErrors(t *testing.T) {
+	t.Setenv("MYPREMIER_CONFIG", "")
+	t.Setenv("MYPREMIER_AUTH_LOCAL_SECRET", testSecret)
+
+	tests := []struct {
+		name    string
+		args    []string
+		env     string
+		wantErr string
+	}{
+		{"unknown role", []string{"-role", "root"}, testSecret, "unknown role"},
+		{"inactive customer", []string{"-inactive"}, testSecret, "-inactive needs -role"},
+		{"no secret", nil, "", "invalid auth.local configuration"},
+		{"missing config file", []string{"-config", "does-not-exist.yaml"}, testSecret, "failed to load configuration"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("MYPREMIER_AUTH_LOCAL_SECRET", tt.env)
+			var out bytes.Buffer
+			err := run(tt.args, &out)
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("run = %v, want %q", err, tt.wantErr)
+			}
+			if out.Len() > 0 {
+				t.Errorf("printed %q on error", out.String())
+			}
+		})
+	}
+}
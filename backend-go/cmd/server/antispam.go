@@ -0,0 +1,31 @@
+package main
+
+import (
+	"net/http"
+
+	"mypremier-backend/internal/antispam"
+	"mypremier-backend/internal/config"
+	"mypremier-backend/internal/modules/quarantine"
+)
+
+// newScreener builds the anti-spam pipeline for public submissions,
+// quarantining flagged ones in repo and recording solved challenges in used
+func newScreener(cfg config.AntispamConfig, repo quarantine.Repository, used antispam.ChallengeStore, clientIP func(*http.Request) string) *antispam.Screener {
+	signer := antispam.NewSigner(cfg.Secret)
+	pipeline := antispam.NewPipeline(cfg.Threshold,
+		antispam.Honeypot{Field: cfg.HoneypotField},
+		antispam.FormTiming{Signer: signer, MinDelay: cfg.MinSubmitTime, MaxAge: cfg.FormTokenTTL},
+		antispam.ProofOfWork{Signer: signer, MinDifficulty: cfg.PowDifficulty, Used: used},
+		antispam.Content{MaxLinks: cfg.MaxLinks, BlockedDomains: cfg.BlockedDomains},
+	)
+
+	return antispam.NewScreener(antispam.Options{
+		Pipeline:     pipeline,
+		Signer:       signer,
+		Quarantine:   quarantine.Hold(repo),
+		ClientIP:     clientIP,
+		Difficulty:   cfg.PowDifficulty,
+		ChallengeTTL: cfg.PowTTL,
+		MinDelay:     cfg.MinSubmitTime,
+	})
+}
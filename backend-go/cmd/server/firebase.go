@@ -0,0 +1,50 @@
+package main
+
+import (
+	"context"
+	"fmt"
+
+	"mypremier-backend/internal/config"
+
+	"cloud.google.com/go/firestore"
+	"firebase.google.com/go/auth"
+)
+
+// firebaseClients are created once at startup and shared by every
+// repository and middleware. Each client holds its own connections, so
+// creating one per repository or per request multiplies them.
+type firebaseClients struct {
+	firestore *firestore.Client
+	auth      *auth.Client
+}
+
+func newFirebaseClients(ctx context.Context, cfg config.FirebaseConfig) (*firebaseClients, error) {
+	app, err := config.NewFirebaseApp(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	firestoreClient, err := app.Firestore(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get firestore client: %w", err)
+	}
+	authClient, err := app.Auth(ctx)
+	if err != nil {
+		firestoreClient.Close()
+		return nil, fmt.Errorf("failed to get auth client: %w", err)
+	}
+
+	return &firebaseClients{
+		firestore: firestoreClient,
+		auth:      authClient,
+	}, nil
+}
+
+// pingAuth checks that Firebase Auth is reachable by looking up an account
+// that does not exist
+func (c *firebaseClients) pingAuth(ctx context.Context) error {
+	if _, err := c.auth.GetUser(ctx, "readiness-probe"); err != nil && !auth.IsUserNotFound(err) {
+		return err
+	}
+	return nil
+}
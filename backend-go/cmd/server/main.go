@@ -1,219 +1,333 @@
 package main
 
 import (
-	"log"
+	"context"
+	"flag"
+	"fmt"
+	"log/slog"
 	"net/http"
-	"strings"
+	"os"
+	"sort"
+	"time"
 
+	"mypremier-backend/internal/antispam"
+	"mypremier-backend/internal/auth"
+	"mypremier-backend/internal/cache"
+	"mypremier-backend/internal/clientip"
 	"mypremier-backend/internal/config"
+	"mypremier-backend/internal/health"
+	"mypremier-backend/internal/httpcache"
+	"mypremier-backend/internal/idempotency"
+	"mypremier-backend/internal/lifecycle"
+	"mypremier-backend/internal/logging"
+	"mypremier-backend/internal/metrics"
 	"mypremier-backend/internal/middleware"
-	"mypremier-backend/internal/modules/audit"
 	"mypremier-backend/internal/modules/category"
 	"mypremier-backend/internal/modules/product"
-	"mypremier-backend/internal/modules/request"
-	"mypremier-backend/internal/modules/stats"
-	"mypremier-backend/internal/modules/support"
 	"mypremier-backend/internal/modules/user"
+	"mypremier-backend/internal/ratelimit"
+	"mypremier-backend/internal/router"
+	"mypremier-backend/internal/tracing"
+
+	"github.com/redis/go-redis/v9"
 )
 
 func main() {
-	config.InitFirebase()
-
-	mux := http.NewServeMux()
+	configPath := flag.String("config", os.Getenv("MYPREMIER_CONFIG"), "path to a YAML or JSON config file")
+	printConfig := flag.Bool("print-config", false, "print the resolved configuration with secrets masked and exit")
+	storage := flag.String("storage", "", "storage backend, overrides the config file: firestore or memory")
+	flag.Parse()
 
-	mux.HandleFunc("/health", func(w http.ResponseWriter, r *http.Request) {
-		w.Write([]byte("MY PREMIER API is running"))
-	})
+	cfg, err := config.Load(*configPath)
+	if err != nil {
+		fatal("failed to load configuration", err)
+	}
+	if *storage != "" {
+		cfg.Storage.Backend = *storage
+	}
 
-	// protected test endpoint
-	protected := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		uid := middleware.GetUserUID(r.Context())
-		w.Write([]byte("Hello UID: " + uid))
-	})
+	if *printConfig {
+		out, err := cfg.Masked()
+		if err != nil {
+			fatal("failed to render configuration", err)
+		}
+		fmt.Print(out)
+		if err := cfg.Validate(); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		return
+	}
 
-	mux.Handle("/protected", middleware.AuthRequired(protected))
+	if err := cfg.Validate(); err != nil {
+		fatal("invalid configuration", err)
+	}
 
-	// Category endpoints
-	categoryHandler, err := category.NewHandler()
+	logger, err := logging.New(os.Stderr, cfg.Logging.Format, cfg.Logging.Level)
 	if err != nil {
-		log.Fatalf("Failed to initialize category handler: %v", err)
+		fatal("failed to create logger", err)
 	}
-	mux.HandleFunc("/categories", categoryHandler.GetCategories)
+	slog.SetDefault(logger)
 
-	// Admin Category endpoints
-	adminCategoryHandler, err := category.NewAdminHandler()
+	shutdownTracing, err := tracing.Setup(context.Background(), cfg.Tracing)
 	if err != nil {
-		log.Fatalf("Failed to initialize admin category handler: %v", err)
-	}
-	// Method router for /admin/categories (GET, POST)
-	adminCategoriesRouter := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		switch r.Method {
-		case http.MethodGet:
-			adminCategoryHandler.GetCategories(w, r)
-		case http.MethodPost:
-			adminCategoryHandler.CreateCategory(w, r)
-		default:
-			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
-		}
-	})
-	mux.Handle("/admin/categories", middleware.AuthRequired(adminCategoriesRouter))
-	// Method router for /admin/categories/{id} (PUT, DELETE)
-	adminCategoryRouter := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		switch r.Method {
-		case http.MethodPut:
-			adminCategoryHandler.UpdateCategory(w, r)
-		case http.MethodDelete:
-			adminCategoryHandler.DeleteCategory(w, r)
-		default:
-			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		fatal("failed to set up tracing", err)
+	}
+
+	// Firebase clients are created once, here, and passed to everything
+	// that needs them
+	var clients *firebaseClients
+	if cfg.Storage.Backend == config.StorageFirestore {
+		clients, err = newFirebaseClients(context.Background(), cfg.Firebase)
+		if err != nil {
+			fatal("failed to initialize firebase", err)
 		}
-	})
-	mux.Handle("/admin/categories/", middleware.AuthRequired(adminCategoryRouter))
+	}
 
-	// Product endpoints
-	productHandler, err := product.NewHandler()
+	repos, err := newRepositories(cfg, clients)
 	if err != nil {
-		log.Fatalf("Failed to initialize product handler: %v", err)
+		fatal("failed to initialize storage", err, "backend", cfg.Storage.Backend)
 	}
-	mux.HandleFunc("/products", productHandler.GetProducts)
-	mux.HandleFunc("/products/", productHandler.GetProduct)
+	slog.Info("storage ready", "backend", cfg.Storage.Backend)
 
-	// Admin Product endpoints
-	adminProductHandler, err := product.NewAdminHandler()
+	app := lifecycle.New(cfg.Server.ShutdownTimeout)
+	app.SetDrainDelay(cfg.Server.DrainDelay)
+
+	probes := health.New(app.ShuttingDown)
+	if repos.ping != nil {
+		probes.Add(cfg.Storage.Backend, cfg.Health.CheckTimeout, repos.ping)
+	}
+	if clients != nil {
+		probes.Add("firebase_auth", cfg.Health.CheckTimeout, clients.pingAuth)
+	}
+	probes.Add("workers", cfg.Health.CheckTimeout, app.CheckWorkers)
+
+	roles, err := newRoleRegistry(cfg.Roles)
 	if err != nil {
-		log.Fatalf("Failed to initialize admin product handler: %v", err)
-	}
-	// Method router for /admin/products (GET, POST)
-	adminProductsRouter := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		switch r.Method {
-		case http.MethodGet:
-			adminProductHandler.GetProducts(w, r)
-		case http.MethodPost:
-			adminProductHandler.CreateProduct(w, r)
-		default:
-			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		fatal("invalid roles", err)
+	}
+
+	opts := routerOptions{
+		probes:        probes,
+		roles:         roles,
+		publicMetrics: cfg.Metrics.Enabled && cfg.Metrics.Addr == "",
+		catalogCache:  httpcache.Policy{MaxAge: cfg.Catalog.CacheMaxAge},
+	}
+	if clients != nil {
+		opts.authAdmin = clients.auth
+	}
+	switch cfg.Auth.Provider {
+	case config.AuthFirebase:
+		if clients != nil {
+			opts.verifier = auth.NewFirebaseVerifier(clients.auth)
 		}
-	})
-	mux.Handle("/admin/products", middleware.AuthRequired(adminProductsRouter))
-	// Method router for /admin/products/{id} (PUT, DELETE)
-	adminProductRouter := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		switch r.Method {
-		case http.MethodPut:
-			adminProductHandler.UpdateProduct(w, r)
-		case http.MethodDelete:
-			adminProductHandler.DeleteProduct(w, r)
-		default:
-			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	case config.AuthLocal:
+		issuer, err := config.NewLocalIssuer(cfg.Auth.Local)
+		if err != nil {
+			fatal("invalid local auth", err)
 		}
-	})
-	mux.Handle("/admin/products/", middleware.AuthRequired(adminProductRouter))
+		opts.verifier = issuer
+		slog.Warn("accepting locally issued tokens; never use auth.provider local in production", "issuer", cfg.Auth.Local.Issuer)
+	}
 
-	// Request Info endpoints
-	requestHandler, err := request.NewHandler()
+	resolver, err := clientip.NewResolver(cfg.Server.TrustedProxies)
 	if err != nil {
-		log.Fatalf("Failed to initialize request handler: %v", err)
+		fatal("invalid trusted proxies", err)
 	}
-	mux.HandleFunc("/request-info", requestHandler.CreateRequest)
 
-	// Admin Request endpoints
-	adminRequestHandler, err := request.NewAdminHandler()
-	if err != nil {
-		log.Fatalf("Failed to initialize admin request handler: %v", err)
+	// One Redis client, created on first use, serves every feature storing
+	// state in Redis
+	var rdb *redis.Client
+	sharedRedis := func() *redis.Client {
+		if rdb == nil {
+			rdb = newRedisClient(cfg.Redis)
+			app.OnClose("redis client", rdb.Close)
+			probes.Add("redis", cfg.Health.CheckTimeout, func(ctx context.Context) error {
+				return rdb.Ping(ctx).Err()
+			})
+		}
+		return rdb
 	}
-	adminGetRequests := http.HandlerFunc(adminRequestHandler.GetRequests)
-	mux.Handle("/admin/requests", middleware.AuthRequired(adminGetRequests))
 
-	// Support endpoints
-	supportHandler, err := support.NewHandler()
-	if err != nil {
-		log.Fatalf("Failed to initialize support handler: %v", err)
+	// Roles and status are mirrored into custom claims; changes are recorded
+	// so that tokens issued before them are checked against storage. Without
+	// a change store every request is checked against storage.
+	changes := accessChanges(cfg, sharedRedis)
+	var claimsAdmin user.ClaimsAdmin
+	if clients != nil {
+		claimsAdmin = user.FirebaseClaimsAdmin{Client: clients.auth}
 	}
-	mux.HandleFunc("/support", supportHandler.CreateSupport)
+	opts.claims = user.NewClaimsSync(claimsAdmin, repos.users, changes)
+	if claimsAdmin != nil && cfg.Access.ReconcileInterval > 0 {
+		app.Go("claims reconciler", func(ctx context.Context) error {
+			return opts.claims.Run(ctx, cfg.Access.ReconcileInterval)
+		})
+	}
+	if clients != nil {
+		revocations := changes
+		if revocations == nil {
+			revocations = cache.NewMemoryCache()
+		}
+		opts.sessions = user.NewSessions(clients.auth, cache.NewLoader(revocations, "revocations", cfg.Access.RevocationCacheTTL))
+	}
+	opts.checkRevoked = cfg.Access.CheckRevoked
+	opts.adminMaxTokenAge = cfg.Access.AdminMaxTokenAge
 
-	// Admin Support endpoints
-	adminSupportHandler, err := support.NewAdminHandler()
-	if err != nil {
-		log.Fatalf("Failed to initialize admin support handler: %v", err)
+	if cfg.Catalog.ReadCache.Enabled {
+		var c cache.Cache = cache.NewMemoryCache()
+		if cfg.Catalog.ReadCache.Store == "redis" {
+			c = cache.NewRedisCache(sharedRedis(), "mypremier:cache:")
+		}
+
+		// Admin handlers use the same repositories, so their writes
+		// invalidate what the public handlers read
+		ttl := cfg.Catalog.ReadCache.TTL
+		repos.products = cache.NewCachedRepository[product.Product](repos.products, cache.NewLoader(c, "products", ttl))
+		repos.categories = cache.NewCachedRepository[category.Category](repos.categories, cache.NewLoader(c, "categories", ttl))
+		slog.Info("catalog read cache enabled", "store", cfg.Catalog.ReadCache.Store, "ttl", ttl)
 	}
-	adminGetSupports := http.HandlerFunc(adminSupportHandler.GetSupports)
-	mux.Handle("/admin/supports", middleware.AuthRequired(adminGetSupports))
-	adminUpdateSupport := http.HandlerFunc(adminSupportHandler.UpdateSupportStatus)
-	mux.Handle("/admin/support/", middleware.AuthRequired(adminUpdateSupport))
 
-	// Support Message endpoints
-	messageHandler, err := support.NewMessageHandler()
-	if err != nil {
-		log.Fatalf("Failed to initialize message handler: %v", err)
-	}
-	// Method router for /supports/{id}/messages (GET, POST)
-	supportMessagesRouter := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		switch r.Method {
-		case http.MethodGet:
-			messageHandler.GetMessages(w, r)
-		case http.MethodPost:
-			messageHandler.CreateMessage(w, r)
-		default:
-			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	if cfg.RateLimit.Enabled {
+		var store ratelimit.Store = ratelimit.NewMemoryStore()
+		if cfg.RateLimit.Store == "redis" {
+			store = ratelimit.NewRedisStore(sharedRedis(), "mypremier:ratelimit:")
 		}
-	})
-	mux.Handle("/supports/", middleware.AuthRequired(supportMessagesRouter))
 
-	// Admin User endpoints
-	adminUserHandler, err := user.NewAdminHandler()
-	if err != nil {
-		log.Fatalf("Failed to initialize admin user handler: %v", err)
-	}
-	adminGetUsers := http.HandlerFunc(adminUserHandler.GetUsers)
-	mux.Handle("/admin/users", middleware.AuthRequired(adminGetUsers))
-	// Method router for /admin/users/{uid}/role and /admin/users/{uid}/status (PATCH)
-	adminUserRouter := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		if r.Method != http.MethodPatch {
-			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
-			return
+		limiter := ratelimit.New(store, resolver.ClientIP)
+		opts.rateLimit = func(pattern string) router.Middleware {
+			l := cfg.RateLimit.For(pattern)
+			return limiter.Middleware(pattern, ratelimit.Limit{Requests: l.Requests, Per: l.Per, Burst: l.Burst})
+		}
+		slog.Info("rate limiting enabled", "store", cfg.RateLimit.Store)
+	}
+
+	if cfg.Idempotency.Enabled {
+		var store idempotency.Store = idempotency.NewMemoryStore()
+		if cfg.Idempotency.Store == "redis" {
+			store = idempotency.NewRedisStore(sharedRedis(), "mypremier:idempotency:")
+		}
+
+		// Public routes have no UID, so anonymous callers are told apart by
+		// address
+		keeper := idempotency.New(store, cfg.Idempotency.TTL, cfg.Idempotency.Lease, idempotency.Actor(func(r *http.Request) string {
+			return middleware.GetUserUID(r.Context())
+		}, resolver.ClientIP))
+		opts.idempotency = func(pattern string) router.Middleware {
+			return keeper.Middleware(pattern)
 		}
-		// Route based on path suffix
-		if strings.HasSuffix(r.URL.Path, "/role") {
-			adminUserHandler.UpdateUserRole(w, r)
-		} else if strings.HasSuffix(r.URL.Path, "/status") {
-			adminUserHandler.UpdateUserStatus(w, r)
-		} else {
-			http.Error(w, "Invalid endpoint", http.StatusBadRequest)
+		slog.Info("idempotency keys enabled", "store", cfg.Idempotency.Store, "ttl", cfg.Idempotency.TTL)
+	}
+
+	if cfg.Antispam.Enabled {
+		if cfg.Antispam.Secret == "" {
+			slog.Warn("antispam.secret is not set; form tokens and challenges only verify on this replica and expire on restart")
 		}
+		var used antispam.ChallengeStore = antispam.NewMemoryChallengeStore()
+		if cfg.Antispam.Store == "redis" {
+			used = antispam.NewRedisChallengeStore(sharedRedis(), "mypremier:antispam:")
+		}
+		opts.screener = newScreener(cfg.Antispam, repos.quarantine, used, resolver.ClientIP)
+		slog.Info("antispam enabled", "store", cfg.Antispam.Store)
+	}
+
+	mux := newRouter(repos, opts)
+
+	// Apply tracing, request IDs, access logging, metrics, security headers,
+	// CORS and body size limit globally
+	handler := router.Chain(
+		middleware.Tracing,
+		middleware.RequestID(logger),
+		middleware.AccessLog,
+		middleware.Metrics,
+		middleware.SecurityHeaders(middleware.SecurityHeadersOptions{
+			HSTSMaxAge:            cfg.SecurityHeaders.HSTSMaxAge,
+			HSTSIncludeSubdomains: cfg.SecurityHeaders.HSTSIncludeSubdomains,
+			ReferrerPolicy:        cfg.SecurityHeaders.ReferrerPolicy,
+		}),
+		middleware.CORS(corsPolicies(cfg.CORS)),
+		middleware.MaxBodySize(cfg.Limits.MaxBodyBytes),
+	)(mux)
+
+	server := &http.Server{
+		Addr:              cfg.Server.Addr,
+		Handler:           handler,
+		ReadTimeout:       cfg.Server.ReadTimeout,
+		ReadHeaderTimeout: cfg.Server.ReadHeaderTimeout,
+		WriteTimeout:      cfg.Server.WriteTimeout,
+		IdleTimeout:       cfg.Server.IdleTimeout,
+	}
+
+	// Registered first so it runs last and flushes spans from the shutdown
+	app.OnClose("trace exporter", func() error {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		return shutdownTracing(ctx)
 	})
-	mux.Handle("/admin/users/", middleware.AuthRequired(adminUserRouter))
+	app.AddServer("API server", server)
+	if opts.publicMetrics {
+		slog.Warn("metrics.addr is empty; /metrics is served on the API listener to anyone who can reach it")
+	}
+	if cfg.Metrics.Enabled && cfg.Metrics.Addr != "" {
+		metricsMux := http.NewServeMux()
+		metricsMux.Handle("GET /metrics", metrics.Handler())
+		app.AddServer("metrics server", &http.Server{
+			Addr:              cfg.Metrics.Addr,
+			Handler:           metricsMux,
+			ReadHeaderTimeout: cfg.Server.ReadHeaderTimeout,
+		})
+	}
+	if clients != nil {
+		app.OnClose("firestore client", clients.firestore.Close)
+	}
 
-	// Admin Me endpoint - returns current user info (uid, email, role)
-	meHandler, err := user.NewMeHandler()
-	if err != nil {
-		log.Fatalf("Failed to initialize me handler: %v", err)
+	if err := app.Run(context.Background()); err != nil {
+		fatal("server stopped with errors", err)
 	}
-	adminMeHandler := http.HandlerFunc(meHandler.GetMe)
-	mux.Handle("/admin/me", middleware.AuthRequired(middleware.LoadUserRole(adminMeHandler)))
+}
 
-	// Admin Stats endpoints
-	statsHandler, err := stats.NewHandler()
-	if err != nil {
-		log.Fatalf("Failed to initialize stats handler: %v", err)
+// corsPolicies converts the CORS configuration for middleware.CORS. Groups
+// are sorted by name so that equal prefixes resolve the same way every run.
+func corsPolicies(cfg config.CORSConfig) (middleware.CORSPolicy, []middleware.CORSGroup) {
+	names := make([]string, 0, len(cfg.Groups))
+	for name := range cfg.Groups {
+		names = append(names, name)
 	}
-	adminStatsSummary := http.HandlerFunc(statsHandler.GetSummary)
-	mux.Handle("/admin/stats/summary", middleware.AuthRequired(adminStatsSummary))
+	sort.Strings(names)
 
-	// Admin Audit Logs endpoints
-	auditHandler, err := audit.NewHandler()
-	if err != nil {
-		log.Fatalf("Failed to initialize audit handler: %v", err)
+	groups := make([]middleware.CORSGroup, 0, len(names))
+	for _, name := range names {
+		g := cfg.Groups[name]
+		groups = append(groups, middleware.CORSGroup{
+			PathPrefixes: g.PathPrefixes,
+			Policy: middleware.CORSPolicy{
+				AllowedOrigins:   g.AllowedOrigins,
+				AllowCredentials: g.AllowCredentials,
+				MaxAge:           g.MaxAge,
+			},
+		})
 	}
-	adminAuditLogs := http.HandlerFunc(auditHandler.GetAuditLogs)
-	mux.Handle("/admin/audit-logs", middleware.AuthRequired(adminAuditLogs))
 
-	// Apply CORS middleware globally
-	handler := middleware.CORS(mux)
+	return middleware.CORSPolicy{
+		AllowedOrigins:   cfg.AllowedOrigins,
+		AllowCredentials: cfg.AllowCredentials,
+		MaxAge:           cfg.MaxAge,
+	}, groups
+}
 
-	server := &http.Server{
-		Addr:    ":8080",
-		Handler: handler,
+// accessChanges returns the store recording role and status changes, or nil
+// when every request is checked against storage
+func accessChanges(cfg *config.Config, sharedRedis func() *redis.Client) cache.Cache {
+	switch cfg.AccessChangeStore() {
+	case "memory":
+		return cache.NewMemoryCache()
+	case "redis":
+		return cache.NewRedisCache(sharedRedis(), "mypremier:access:")
 	}
+	return nil
+}
 
-	log.Println("🚀 Server running on http://localhost:8080")
-	log.Fatal(server.ListenAndServe())
+// fatal logs err and exits
+func fatal(msg string, err error, args ...any) {
+	slog.Error(msg, append([]any{"err", err}, args...)...)
+	os.Exit(1)
 }
@@ -0,0 +1,237 @@
+package main
+
+import (
+	"net/http"
+
+	"mypremier-backend/internal/antispam"
+	"mypremier-backend/internal/health"
+	"mypremier-backend/internal/modules/audit"
+	"mypremier-backend/internal/modules/category"
+	"mypremier-backend/internal/modules/product"
+	"mypremier-backend/internal/modules/quarantine"
+	"mypremier-backend/internal/modules/request"
+	"mypremier-backend/internal/modules/stats"
+	"mypremier-backend/internal/modules/support"
+	"mypremier-backend/internal/modules/user"
+	"mypremier-backend/internal/openapi"
+	"mypremier-backend/internal/rbac"
+)
+
+var apiInfo = openapi.Info{
+	Title:   "MY PREMIER API",
+	Version: "1.0.0",
+	Description: "Backend for the MY PREMIER product catalog, used by the client and sales apps and the admin web. " +
+		"Authenticated routes take a Firebase ID token as a bearer token, or in development a token minted by cmd/devtoken.",
+}
+
+// apiDocs documents every route of the route table, keyed by pattern. The
+// route coverage test fails when a route is missing here.
+var apiDocs = map[string]openapi.Operation{
+	// System
+	"GET /health": {
+		Summary: "Legacy health check", Tags: []string{"system"},
+		Response: "", ContentType: "text/plain",
+	},
+	"GET /livez": {
+		Summary: "Liveness probe", Tags: []string{"system"},
+		Response: health.Report{},
+	},
+	"GET /readyz": {
+		Summary: "Readiness probe", Tags: []string{"system"},
+		Description: "Checks every dependency under its own timeout. Answers 503 when a check fails or the server is shutting down.",
+		Response:    health.Report{}, Errors: []int{http.StatusServiceUnavailable},
+	},
+	"GET /metrics": {
+		Summary: "Prometheus metrics", Tags: []string{"system"},
+		Response: "", ContentType: "text/plain",
+	},
+	"GET /openapi.json": {
+		Summary: "This document", Tags: []string{"system"},
+		Response: map[string]interface{}{},
+	},
+	"GET /docs": {
+		Summary: "API documentation page", Tags: []string{"system"},
+		Response: "", ContentType: "text/html",
+	},
+	"GET /protected": {
+		Summary: "Echo the caller's UID", Tags: []string{"system"}, Auth: true,
+		Response: "", ContentType: "text/plain",
+	},
+
+	// Public catalog
+	"GET /categories": {
+		Summary: "List categories", Tags: []string{"catalog"},
+		Response: []category.Category{}, Conditional: true,
+	},
+	"GET /products": {
+		Summary: "List products", Tags: []string{"catalog"},
+		Response: []product.Product{}, Conditional: true,
+	},
+	"GET /products/{id}": {
+		Summary: "Get a product", Tags: []string{"catalog"},
+		Response: product.Product{}, Conditional: true,
+	},
+
+	// Public submissions
+	"GET /antispam/form-token": {
+		Summary: "Get a form token", Tags: []string{"submissions"},
+		Description: "Fetch when showing the request-info or support form (form=request or form=support) and send it back as antispam.form_token. " +
+			"Submitting sooner than min_delay_seconds after fetching counts as spam.",
+		Response: antispam.FormTokenResponse{},
+		Errors:   []int{http.StatusBadRequest, http.StatusTooManyRequests},
+	},
+	"GET /antispam/challenge": {
+		Summary: "Get a proof-of-work challenge", Tags: []string{"submissions"},
+		Description: "Find a nonce such that SHA-256(challenge + \":\" + nonce) starts with difficulty zero bits, " +
+			"and send both as antispam.challenge and antispam.nonce. Each challenge is accepted once.",
+		Response: antispam.ChallengeResponse{},
+		Errors:   []int{http.StatusTooManyRequests},
+	},
+	"POST /request-info": {
+		Summary: "Submit a product information request", Tags: []string{"submissions"},
+		Description: "Submissions that look like spam are quarantined for review; the response does not tell.",
+		Request:     request.CreateRequestInput{}, Response: request.CreateRequestResponse{}, Status: http.StatusCreated,
+		Errors: []int{http.StatusTooManyRequests}, Idempotent: true,
+	},
+	"POST /support": {
+		Summary: "Open a support ticket", Tags: []string{"submissions"},
+		Description: "Submissions that look like spam are quarantined for review; the response does not tell.",
+		Request:     support.CreateSupportInput{}, Response: support.CreateSupportResponse{}, Status: http.StatusCreated,
+		Errors: []int{http.StatusTooManyRequests}, Idempotent: true,
+	},
+
+	// Support chat
+	"GET /supports/{id}/messages": {
+		Summary: "List the messages of a support ticket", Tags: []string{"support"}, Auth: true,
+		Response: []support.SupportMessage{},
+	},
+	"POST /supports/{id}/messages": {
+		Summary: "Post a message to a support ticket", Tags: []string{"support"}, Auth: true,
+		Request: support.CreateMessageInput{}, Response: support.CreateMessageResponse{}, Status: http.StatusCreated,
+		Idempotent: true,
+	},
+
+	// Admin categories
+	"GET /admin/categories": {
+		Summary: "List categories", Tags: []string{"admin"}, Auth: true,
+		Response: []category.Category{},
+	},
+	"POST /admin/categories": {
+		Summary: "Create a category", Tags: []string{"admin"}, Auth: true,
+		Request: category.CategoryInput{}, Response: category.CategoryResponse{}, Status: http.StatusCreated,
+		Idempotent: true,
+	},
+	"PUT /admin/categories/{id}": {
+		Summary: "Update a category", Tags: []string{"admin"}, Auth: true,
+		Request: category.CategoryInput{}, Response: category.CategoryResponse{},
+	},
+	"DELETE /admin/categories/{id}": {
+		Summary: "Delete a category", Tags: []string{"admin"}, Auth: true,
+		Response: category.DeleteCategoryResponse{},
+	},
+
+	// Admin products
+	"GET /admin/products": {
+		Summary: "List products, including inactive ones", Tags: []string{"admin"}, Auth: true,
+		Response: []product.Product{},
+	},
+	"POST /admin/products": {
+		Summary: "Create a product", Tags: []string{"admin"}, Auth: true,
+		Request: product.ProductInput{}, Response: product.ProductResponse{}, Status: http.StatusCreated,
+		Idempotent: true,
+	},
+	"PUT /admin/products/{id}": {
+		Summary: "Update a product", Tags: []string{"admin"}, Auth: true,
+		Request: product.ProductInput{}, Response: product.ProductResponse{},
+	},
+	"DELETE /admin/products/{id}": {
+		Summary: "Delete a product", Tags: []string{"admin"}, Auth: true,
+		Response: product.DeleteProductResponse{},
+	},
+
+	// Admin requests and supports
+	"GET /admin/requests": {
+		Summary: "List information requests", Tags: []string{"admin"}, Auth: true,
+		Response: []request.Request{},
+	},
+	"GET /admin/supports": {
+		Summary: "List support tickets", Tags: []string{"admin"}, Auth: true,
+		Response: []support.Support{},
+	},
+	"PATCH /admin/support/{id}": {
+		Summary: "Change the status of a support ticket", Tags: []string{"admin"}, Auth: true,
+		Request: support.UpdateSupportStatusInput{}, Response: support.UpdateSupportStatusResponse{},
+	},
+
+	// Admin spam quarantine
+	"GET /admin/quarantine": {
+		Summary: "List quarantined submissions", Tags: []string{"admin"}, Auth: true,
+		Response: []quarantine.Item{},
+	},
+	"POST /admin/quarantine/{id}/release": {
+		Summary: "Release a quarantined submission", Tags: []string{"admin"}, Auth: true,
+		Description: "Stores the submission as a request or support ticket, as if it had passed screening. An item is released once; releasing it again returns 404.",
+		Response:    quarantine.ReleaseResponse{},
+		Idempotent:  true,
+	},
+	"DELETE /admin/quarantine/{id}": {
+		Summary: "Discard a quarantined submission", Tags: []string{"admin"}, Auth: true,
+		Response: quarantine.DiscardResponse{},
+	},
+
+	// Admin users
+	"GET /admin/users": {
+		Summary: "List users", Tags: []string{"admin"}, Auth: true,
+		Response: []user.User{},
+	},
+	"POST /admin/users": {
+		Summary: "Create a user", Tags: []string{"admin"}, Auth: true,
+		Description: "Creates the Firebase Auth account and the user document together; if either fails, neither is kept.",
+		Request:     user.CreateUserInput{}, Response: user.User{}, Status: http.StatusCreated,
+		Idempotent: true, Errors: []int{http.StatusConflict, http.StatusServiceUnavailable},
+	},
+	"PUT /admin/users/{uid}": {
+		Summary: "Update a user's profile", Tags: []string{"admin"}, Auth: true,
+		Description: "Updates the email, name and phone, and the password if given, in Firebase Auth and the user document.",
+		Request:     user.UpdateUserInput{}, Response: user.User{},
+		Errors: []int{http.StatusConflict, http.StatusServiceUnavailable},
+	},
+	"DELETE /admin/users/{uid}": {
+		Summary: "Delete a user", Tags: []string{"admin"}, Auth: true,
+		Description: "Deletes the user document and the Firebase Auth account. Admins cannot delete themselves.",
+		Response:    user.DeleteUserResponse{}, Errors: []int{http.StatusServiceUnavailable},
+	},
+	"PATCH /admin/users/{uid}/role": {
+		Summary: "Change a user's role", Tags: []string{"admin"}, Auth: true,
+		Request: user.UpdateUserRoleInput{}, Response: user.UpdateUserRoleResponse{},
+	},
+	"PATCH /admin/users/{uid}/status": {
+		Summary: "Activate or deactivate a user", Tags: []string{"admin"}, Auth: true,
+		Description: "A deactivated user is refused from their next request on and signed out of every device.",
+		Request:     user.UpdateUserStatusInput{}, Response: user.UpdateUserStatusResponse{},
+	},
+	"POST /admin/users/{uid}/sessions/revoke": {
+		Summary: "Sign a user out of every device", Tags: []string{"admin"}, Auth: true,
+		Description: "Revokes the user's refresh tokens. ID tokens already issued are refused on admin routes " +
+			"once the cached revocation time of the user expires, within 30 seconds by default.",
+		Response: user.RevokeSessionsResponse{}, Errors: []int{http.StatusServiceUnavailable}, Idempotent: true,
+	},
+	"GET /admin/roles": {
+		Summary: "List the roles users can be assigned", Tags: []string{"admin"}, Auth: true,
+		Response: []rbac.Role{},
+	},
+	"GET /admin/me": {
+		Summary: "Get the signed-in user", Tags: []string{"admin"}, Auth: true,
+		Response: user.MeResponse{},
+	},
+
+	// Admin dashboard and audit trail
+	"GET /admin/stats/summary": {
+		Summary: "Dashboard counters", Tags: []string{"admin"}, Auth: true,
+		Response: stats.SummaryResponse{},
+	},
+	"GET /admin/audit-logs": {
+		Summary: "List audit log entries", Tags: []string{"admin"}, Auth: true,
+		Response: []audit.AuditLog{},
+	},
+}
@@ -0,0 +1,65 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"mypremier-backend/internal/antispam"
+	"mypremier-backend/internal/config"
+	"mypremier-backend/internal/health"
+	"mypremier-backend/internal/openapi"
+)
+
+// allRoutes returns what newRouter needs to register every optional route
+func allRoutes() (*repositories, routerOptions) {
+	repos := newMemoryRepositories()
+	clientIP := func(r *http.Request) string { return r.RemoteAddr }
+	roles, err := newRoleRegistry(config.Default().Roles)
+	if err != nil {
+		panic(err)
+	}
+	return repos, routerOptions{
+		probes:        health.New(nil),
+		roles:         roles,
+		publicMetrics: true,
+		screener:      newScreener(config.Default().Antispam, repos.quarantine, antispam.NewMemoryChallengeStore(), clientIP),
+	}
+}
+
+func TestOpenAPICoversEveryRoute(t *testing.T) {
+	rt := newRouter(allRoutes())
+
+	if _, err := openapi.Build(apiInfo, rt.Routes(), apiDocs); err != nil {
+		t.Fatalf("route table and apiDocs disagree:\n%v", err)
+	}
+}
+
+func TestOpenAPIServed(t *testing.T) {
+	rt := newRouter(allRoutes())
+
+	rec := httptest.NewRecorder()
+	rt.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/openapi.json", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("GET /openapi.json: status %d", rec.Code)
+	}
+
+	var doc struct {
+		OpenAPI string                                `json:"openapi"`
+		Paths   map[string]map[string]json.RawMessage `json:"paths"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &doc); err != nil {
+		t.Fatalf("decoding document: %v", err)
+	}
+	if doc.OpenAPI != openapi.Version {
+		t.Errorf("openapi = %q, want %q", doc.OpenAPI, openapi.Version)
+	}
+
+	for _, route := range rt.Routes() {
+		if _, ok := doc.Paths[route.Path][strings.ToLower(route.Method)]; !ok {
+			t.Errorf("served document is missing %s", route.Pattern())
+		}
+	}
+}
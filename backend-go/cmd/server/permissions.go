@@ -0,0 +1,60 @@
+package main
+
+import (
+	"mypremier-backend/internal/config"
+	"mypremier-backend/internal/rbac"
+)
+
+// routePermissions is the permission each /admin route requires. Every
+// /admin route must be listed; newRouter panics otherwise. An empty
+// permission only requires a signed-in user with a role.
+var routePermissions = map[string]rbac.Permission{
+	"GET /admin/categories":         rbac.CategoryRead,
+	"POST /admin/categories":        rbac.CategoryWrite,
+	"PUT /admin/categories/{id}":    rbac.CategoryWrite,
+	"DELETE /admin/categories/{id}": rbac.CategoryWrite,
+
+	"GET /admin/products":         rbac.ProductRead,
+	"POST /admin/products":        rbac.ProductWrite,
+	"PUT /admin/products/{id}":    rbac.ProductWrite,
+	"DELETE /admin/products/{id}": rbac.ProductWrite,
+
+	"GET /admin/requests":       rbac.RequestRead,
+	"GET /admin/supports":       rbac.SupportRead,
+	"PATCH /admin/support/{id}": rbac.SupportWrite,
+
+	"GET /admin/quarantine":               rbac.QuarantineRead,
+	"POST /admin/quarantine/{id}/release": rbac.QuarantineWrite,
+	"DELETE /admin/quarantine/{id}":       rbac.QuarantineWrite,
+
+	"GET /admin/users":                        rbac.UserRead,
+	"POST /admin/users":                       rbac.UserManage,
+	"PUT /admin/users/{uid}":                  rbac.UserManage,
+	"DELETE /admin/users/{uid}":               rbac.UserManage,
+	"PATCH /admin/users/{uid}/role":           rbac.UserManage,
+	"PATCH /admin/users/{uid}/status":         rbac.UserManage,
+	"POST /admin/users/{uid}/sessions/revoke": rbac.UserManage,
+	"GET /admin/me":                           "",
+	"GET /admin/roles":                        rbac.UserRead,
+
+	"GET /admin/stats/summary": rbac.StatsRead,
+	"GET /admin/audit-logs":    rbac.AuditRead,
+}
+
+// newRoleRegistry builds the role registry from the roles configuration
+func newRoleRegistry(roles []config.RoleConfig) (*rbac.Registry, error) {
+	defs := make([]rbac.Role, len(roles))
+	for i, role := range roles {
+		perms := make([]rbac.Permission, len(role.Permissions))
+		for j, perm := range role.Permissions {
+			perms[j] = rbac.Permission(perm)
+		}
+		defs[i] = rbac.Role{
+			Name:        role.Name,
+			DisplayName: role.DisplayName,
+			Description: role.Description,
+			Permissions: perms,
+		}
+	}
+	return rbac.NewRegistry(defs)
+}
@@ -0,0 +1,150 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"slices"
+	"strings"
+	"testing"
+
+	"mypremier-backend/internal/auth"
+	"mypremier-backend/internal/modules/user"
+	"mypremier-backend/internal/router"
+)
+
+// uidVerifier accepts any token and uses it as the UID
+type uidVerifier struct{}
+
+func (uidVerifier) Verify(ctx context.Context, token string) (*auth.Principal, error) {
+	return &auth.Principal{UID: token}, nil
+}
+
+// TestAdminRoutePermissions sends a request to every /admin route as every
+// role. The table is the intended access matrix, written out independently
+// of routePermissions so that a change to either shows up here.
+func TestAdminRoutePermissions(t *testing.T) {
+	const (
+		admin    = "admin"
+		sales    = "sales"
+		engineer = "engineer"
+		client   = "client"
+	)
+	all := []string{admin, sales, engineer, client}
+	allowed := map[string][]string{
+		"GET /admin/categories":         {admin, sales, engineer},
+		"POST /admin/categories":        {admin, sales},
+		"PUT /admin/categories/{id}":    {admin, sales},
+		"DELETE /admin/categories/{id}": {admin, sales},
+
+		"GET /admin/products":         {admin, sales, engineer},
+		"POST /admin/products":        {admin, sales},
+		"PUT /admin/products/{id}":    {admin, sales},
+		"DELETE /admin/products/{id}": {admin, sales},
+
+		"GET /admin/requests":       {admin, sales},
+		"GET /admin/supports":       {admin, sales, engineer},
+		"PATCH /admin/support/{id}": {admin, sales, engineer},
+
+		"GET /admin/quarantine":               {admin, sales},
+		"POST /admin/quarantine/{id}/release": {admin, sales},
+		"DELETE /admin/quarantine/{id}":       {admin, sales},
+
+		"GET /admin/users":                        {admin},
+		"POST /admin/users":                       {admin},
+		"PUT /admin/users/{uid}":                  {admin},
+		"DELETE /admin/users/{uid}":               {admin},
+		"PATCH /admin/users/{uid}/role":           {admin},
+		"PATCH /admin/users/{uid}/status":         {admin},
+		"POST /admin/users/{uid}/sessions/revoke": {admin},
+		"GET /admin/me":                           all,
+		"GET /admin/roles":                        {admin},
+
+		"GET /admin/stats/summary": {admin, sales},
+		"GET /admin/audit-logs":    {admin},
+	}
+
+	repos, opts := allRoutes()
+	var seeded []user.User
+	for _, role := range all {
+		seeded = append(seeded, user.User{UID: role + "-uid", Email: role + "@example.com", Role: role, IsActive: true})
+	}
+	repos.users = user.NewMemoryRepository(seeded...)
+	opts.verifier = uidVerifier{}
+	rt := newRouter(repos, opts)
+
+	wildcard := regexp.MustCompile(`\{[^}]+\}`)
+	denied := 0
+	for _, route := range rt.Routes() {
+		if !strings.HasPrefix(route.Path, "/admin/") {
+			continue
+		}
+		pattern := route.Pattern()
+		roles, ok := allowed[pattern]
+		if !ok {
+			t.Errorf("%s is missing from the test table", pattern)
+			continue
+		}
+		path := wildcard.ReplaceAllString(route.Path, "missing")
+
+		newRequest := func(uid string) *http.Request {
+			var req *http.Request
+			switch route.Method {
+			case http.MethodPost, http.MethodPut, http.MethodPatch:
+				req = httptest.NewRequest(route.Method, path, strings.NewReader("{}"))
+				req.Header.Set("Content-Type", "application/json")
+			default:
+				req = httptest.NewRequest(route.Method, path, nil)
+			}
+			if uid != "" {
+				req.Header.Set("Authorization", "Bearer "+uid)
+			}
+			return req
+		}
+
+		t.Run(pattern+"/anonymous", func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			rt.ServeHTTP(rec, newRequest(""))
+			if rec.Code != http.StatusUnauthorized {
+				t.Errorf("status %d, want 401", rec.Code)
+			}
+		})
+
+		for _, role := range all {
+			t.Run(pattern+"/"+role, func(t *testing.T) {
+				rec := httptest.NewRecorder()
+				rt.ServeHTTP(rec, newRequest(role+"-uid"))
+
+				switch {
+				case slices.Contains(roles, role) && (rec.Code == http.StatusUnauthorized || rec.Code == http.StatusForbidden):
+					t.Errorf("status %d, want access: %s", rec.Code, rec.Body)
+				case !slices.Contains(roles, role):
+					denied++
+					if rec.Code != http.StatusForbidden {
+						t.Errorf("status %d, want 403", rec.Code)
+					}
+				}
+			})
+		}
+	}
+	for pattern := range allowed {
+		if !slices.ContainsFunc(rt.Routes(), func(r router.Route) bool { return r.Pattern() == pattern }) {
+			t.Errorf("%s is in the test table but not registered", pattern)
+		}
+	}
+
+	logs, err := repos.audit.GetAll(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	audited := 0
+	for _, entry := range logs {
+		if entry.Action == "access_denied" {
+			audited++
+		}
+	}
+	if audited != denied {
+		t.Errorf("%d denied attempts were audited, want %d", audited, denied)
+	}
+}
@@ -0,0 +1,25 @@
+package main
+
+import (
+	"crypto/tls"
+
+	"mypremier-backend/internal/config"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// newRedisClient connects to the shared Redis used by the rate limiter and
+// idempotency keys. The connection is made lazily on first use.
+func newRedisClient(cfg config.RedisConfig) *redis.Client {
+	opts := &redis.Options{
+		Addr:     cfg.Addr,
+		Username: cfg.Username,
+		Password: cfg.Password,
+		DB:       cfg.DB,
+	}
+	if cfg.TLS {
+		opts.TLSConfig = &tls.Config{MinVersion: tls.VersionTLS12}
+	}
+
+	return redis.NewClient(opts)
+}
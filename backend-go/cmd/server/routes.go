@@ -0,0 +1,241 @@
+package main
+
+import (
+	"log/slog"
+	"maps"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	"mypremier-backend/internal/antispam"
+	"mypremier-backend/internal/auth"
+	"mypremier-backend/internal/health"
+	"mypremier-backend/internal/httpcache"
+	"mypremier-backend/internal/metrics"
+	"mypremier-backend/internal/middleware"
+	"mypremier-backend/internal/modules/audit"
+	"mypremier-backend/internal/modules/category"
+	"mypremier-backend/internal/modules/product"
+	"mypremier-backend/internal/modules/quarantine"
+	"mypremier-backend/internal/modules/request"
+	"mypremier-backend/internal/modules/stats"
+	"mypremier-backend/internal/modules/support"
+	"mypremier-backend/internal/modules/user"
+	"mypremier-backend/internal/openapi"
+	"mypremier-backend/internal/rbac"
+	"mypremier-backend/internal/router"
+)
+
+// routerOptions carries the cross-cutting pieces the route table needs
+type routerOptions struct {
+	probes *health.Checker
+	// verifier checks bearer tokens; nil when no identity provider is
+	// configured
+	verifier auth.Verifier
+	// authAdmin manages Auth accounts; nil when Firebase is not configured
+	authAdmin user.AuthAdmin
+	// roles is the role registry admin routes are checked against
+	roles *rbac.Registry
+	// claims authorises signed-in users and mirrors role and status changes
+	// into their custom claims; nil checks every request against storage
+	claims *user.ClaimsSync
+	// sessions revokes sessions and tells when they were revoked; nil when
+	// Firebase is not configured
+	sessions *user.Sessions
+	// checkRevoked refuses revoked tokens on admin routes
+	checkRevoked bool
+	// adminMaxTokenAge is the longest time since sign-in accepted on admin
+	// routes; 0 accepts any
+	adminMaxTokenAge time.Duration
+	// publicMetrics serves /metrics on the API listener
+	publicMetrics bool
+	// catalogCache is the caching policy of the public catalog
+	catalogCache httpcache.Policy
+	// rateLimit returns the rate limiting middleware for a route pattern; nil
+	// disables rate limiting
+	rateLimit func(pattern string) router.Middleware
+	// screener screens public submissions for spam; nil accepts everything
+	screener *antispam.Screener
+	// idempotency returns the Idempotency-Key middleware for a POST route
+	// pattern; nil disables idempotency keys
+	idempotency func(pattern string) router.Middleware
+}
+
+// newRouter builds the handlers on top of repos and returns the API route table
+func newRouter(repos *repositories, opts routerOptions) *router.Router {
+	auditHandler := audit.NewHandler(repos.audit)
+	categoryHandler := category.NewHandler(repos.categories, opts.catalogCache)
+	adminCategoryHandler := category.NewAdminHandler(repos.categories, auditHandler)
+	productHandler := product.NewHandler(repos.products, opts.catalogCache)
+	adminProductHandler := product.NewAdminHandler(repos.products, auditHandler)
+	requestHandler := request.NewHandler(repos.requests, opts.screener)
+	adminRequestHandler := request.NewAdminHandler(repos.requests)
+	supportHandler := support.NewHandler(repos.supports, opts.screener)
+	adminSupportHandler := support.NewAdminHandler(repos.supports, auditHandler)
+	messageHandler := support.NewMessageHandler(repos.messages)
+	var accounts *user.Accounts
+	if opts.authAdmin != nil {
+		accounts = user.NewAccounts(opts.authAdmin, repos.users)
+	}
+	claims := opts.claims
+	if claims == nil {
+		claims = user.NewClaimsSync(nil, repos.users, nil)
+	}
+	adminUserHandler := user.NewAdminHandler(repos.users, opts.roles, accounts, claims, opts.sessions, auditHandler)
+	meHandler := user.NewMeHandler(repos.users)
+	statsHandler := stats.NewHandler(repos.stats)
+	adminQuarantineHandler := quarantine.NewAdminHandler(repos.quarantine, map[string]quarantine.Releaser{
+		antispam.FormRequest: repos.requests.Create,
+		antispam.FormSupport: repos.supports.Create,
+	}, auditHandler)
+
+	requireAuth := router.Middleware(middleware.AuthRequired(opts.verifier))
+	requireActive := router.Middleware(middleware.RequireActive(claims.Access))
+	loadUserRole := router.Middleware(middleware.LoadUserRole(claims.Access, opts.roles.Valid))
+	policy := middleware.SessionPolicy{MaxAge: opts.adminMaxTokenAge}
+	if opts.sessions != nil && opts.checkRevoked {
+		policy.RevokedAt = opts.sessions.RevokedAt
+	}
+	checkSession := router.Middleware(middleware.CheckSession(policy))
+
+	// Authenticated routes serve any signed-in user, staff routes need a
+	// role and also refuse revoked and too old sessions. Both refuse
+	// deactivated users.
+	authenticated := []router.Middleware{requireAuth, requireActive}
+	staff := []router.Middleware{requireAuth, checkSession, loadUserRole}
+
+	// limited rate limits the route with the given pattern per client IP
+	limited := func(pattern string) []router.Middleware {
+		if opts.rateLimit == nil {
+			return nil
+		}
+		return []router.Middleware{opts.rateLimit(pattern)}
+	}
+
+	routes := []router.Route{
+		{Method: http.MethodGet, Path: "/health", Handler: func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte("MY PREMIER API is running"))
+		}},
+		{Method: http.MethodGet, Path: "/livez", Handler: opts.probes.Livez},
+		{Method: http.MethodGet, Path: "/readyz", Handler: opts.probes.Readyz},
+		// protected test endpoint
+		{Method: http.MethodGet, Path: "/protected", Middleware: authenticated, Handler: func(w http.ResponseWriter, r *http.Request) {
+			uid := middleware.GetUserUID(r.Context())
+			w.Write([]byte("Hello UID: " + uid))
+		}},
+
+		// Public catalog
+		{Method: http.MethodGet, Path: "/categories", Handler: categoryHandler.GetCategories},
+		{Method: http.MethodGet, Path: "/products", Handler: productHandler.GetProducts},
+		{Method: http.MethodGet, Path: "/products/{id}", Handler: productHandler.GetProduct},
+
+		// Public submissions
+		{Method: http.MethodPost, Path: "/request-info", Middleware: limited("POST /request-info"), Handler: requestHandler.CreateRequest},
+		{Method: http.MethodPost, Path: "/support", Middleware: limited("POST /support"), Handler: supportHandler.CreateSupport},
+
+		// Support chat
+		{Method: http.MethodGet, Path: "/supports/{id}/messages", Middleware: authenticated, Handler: messageHandler.GetMessages},
+		{Method: http.MethodPost, Path: "/supports/{id}/messages", Middleware: authenticated, Handler: messageHandler.CreateMessage},
+
+		// Admin categories
+		{Method: http.MethodGet, Path: "/admin/categories", Middleware: staff, Handler: adminCategoryHandler.GetCategories},
+		{Method: http.MethodPost, Path: "/admin/categories", Middleware: staff, Handler: adminCategoryHandler.CreateCategory},
+		{Method: http.MethodPut, Path: "/admin/categories/{id}", Middleware: staff, Handler: adminCategoryHandler.UpdateCategory},
+		{Method: http.MethodDelete, Path: "/admin/categories/{id}", Middleware: staff, Handler: adminCategoryHandler.DeleteCategory},
+
+		// Admin products
+		{Method: http.MethodGet, Path: "/admin/products", Middleware: staff, Handler: adminProductHandler.GetProducts},
+		{Method: http.MethodPost, Path: "/admin/products", Middleware: staff, Handler: adminProductHandler.CreateProduct},
+		{Method: http.MethodPut, Path: "/admin/products/{id}", Middleware: staff, Handler: adminProductHandler.UpdateProduct},
+		{Method: http.MethodDelete, Path: "/admin/products/{id}", Middleware: staff, Handler: adminProductHandler.DeleteProduct},
+
+		// Admin requests and supports
+		{Method: http.MethodGet, Path: "/admin/requests", Middleware: staff, Handler: adminRequestHandler.GetRequests},
+		{Method: http.MethodGet, Path: "/admin/supports", Middleware: staff, Handler: adminSupportHandler.GetSupports},
+		{Method: http.MethodPatch, Path: "/admin/support/{id}", Middleware: staff, Handler: adminSupportHandler.UpdateSupportStatus},
+
+		// Admin spam quarantine
+		{Method: http.MethodGet, Path: "/admin/quarantine", Middleware: staff, Handler: adminQuarantineHandler.GetItems},
+		{Method: http.MethodPost, Path: "/admin/quarantine/{id}/release", Middleware: staff, Handler: adminQuarantineHandler.ReleaseItem},
+		{Method: http.MethodDelete, Path: "/admin/quarantine/{id}", Middleware: staff, Handler: adminQuarantineHandler.DiscardItem},
+
+		// Admin users
+		{Method: http.MethodGet, Path: "/admin/users", Middleware: staff, Handler: adminUserHandler.GetUsers},
+		{Method: http.MethodPost, Path: "/admin/users", Middleware: staff, Handler: adminUserHandler.CreateUser},
+		{Method: http.MethodPut, Path: "/admin/users/{uid}", Middleware: staff, Handler: adminUserHandler.UpdateUser},
+		{Method: http.MethodDelete, Path: "/admin/users/{uid}", Middleware: staff, Handler: adminUserHandler.DeleteUser},
+		{Method: http.MethodPatch, Path: "/admin/users/{uid}/role", Middleware: staff, Handler: adminUserHandler.UpdateUserRole},
+		{Method: http.MethodPatch, Path: "/admin/users/{uid}/status", Middleware: staff, Handler: adminUserHandler.UpdateUserStatus},
+		{Method: http.MethodPost, Path: "/admin/users/{uid}/sessions/revoke", Middleware: staff, Handler: adminUserHandler.RevokeSessions},
+		{Method: http.MethodGet, Path: "/admin/roles", Middleware: staff, Handler: opts.roles.GetRoles},
+		// Current user info (uid, email, role)
+		{Method: http.MethodGet, Path: "/admin/me", Middleware: staff, Handler: meHandler.GetMe},
+
+		// Admin dashboard and audit trail
+		{Method: http.MethodGet, Path: "/admin/stats/summary", Middleware: staff, Handler: statsHandler.GetSummary},
+		{Method: http.MethodGet, Path: "/admin/audit-logs", Middleware: staff, Handler: auditHandler.GetAuditLogs},
+	}
+
+	if opts.screener != nil {
+		routes = append(routes,
+			router.Route{Method: http.MethodGet, Path: "/antispam/form-token", Middleware: limited("GET /antispam/form-token"), Handler: opts.screener.FormToken},
+			router.Route{Method: http.MethodGet, Path: "/antispam/challenge", Middleware: limited("GET /antispam/challenge"), Handler: opts.screener.Challenge},
+		)
+	}
+
+	// Admin routes require the permission listed for them, checked after
+	// the role of the caller is loaded
+	enforcer := rbac.NewEnforcer(opts.roles, auditHandler)
+	for i, rt := range routes {
+		if !strings.HasPrefix(rt.Path, "/admin/") {
+			continue
+		}
+		perm, ok := routePermissions[rt.Pattern()]
+		if !ok {
+			panic("route " + rt.Pattern() + " has no entry in routePermissions")
+		}
+		if perm != "" {
+			routes[i].Middleware = append(slices.Clip(rt.Middleware), router.Middleware(enforcer.Require(perm)))
+		}
+	}
+
+	// Idempotency keys apply to every POST, inside auth and rate limiting so
+	// that the caller is known and rejected requests are not stored
+	if opts.idempotency != nil {
+		for i, rt := range routes {
+			if rt.Method == http.MethodPost {
+				routes[i].Middleware = append(slices.Clip(rt.Middleware), opts.idempotency(rt.Pattern()))
+			}
+		}
+	}
+
+	if opts.publicMetrics {
+		routes = append(routes, router.Route{Method: http.MethodGet, Path: "/metrics", Handler: metrics.Handler().ServeHTTP})
+	}
+
+	// The document describes the final table, including its own routes
+	var spec http.HandlerFunc
+	routes = append(routes,
+		router.Route{Method: http.MethodGet, Path: "/openapi.json", Handler: func(w http.ResponseWriter, r *http.Request) {
+			spec(w, r)
+		}},
+		router.Route{Method: http.MethodGet, Path: "/docs", Handler: openapi.DocsHandler(apiInfo.Title, "/openapi.json")},
+	)
+
+	rt := router.New(routes)
+	docs := maps.Clone(apiDocs)
+	for pattern, perm := range routePermissions {
+		if op, ok := docs[pattern]; ok && perm != "" {
+			op.Permission = string(perm)
+			docs[pattern] = op
+		}
+	}
+	doc, err := openapi.Build(apiInfo, rt.Routes(), docs)
+	if err != nil {
+		slog.Warn("openapi document is incomplete", "err", err)
+	}
+	spec = openapi.SpecHandler(doc)
+
+	return rt
+}
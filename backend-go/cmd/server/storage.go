@@ -0,0 +1,92 @@
+package main
+
+import (
+	"context"
+	"fmt"
+
+	"mypremier-backend/internal/config"
+	"mypremier-backend/internal/modules/audit"
+	"mypremier-backend/internal/modules/category"
+	"mypremier-backend/internal/modules/product"
+	"mypremier-backend/internal/modules/quarantine"
+	"mypremier-backend/internal/modules/request"
+	"mypremier-backend/internal/modules/stats"
+	"mypremier-backend/internal/modules/support"
+	"mypremier-backend/internal/modules/user"
+
+	"cloud.google.com/go/firestore"
+	"google.golang.org/api/iterator"
+)
+
+// repositories holds the storage implementation used by every module
+type repositories struct {
+	products   product.Repository
+	categories category.Repository
+	requests   request.Repository
+	supports   support.Repository
+	messages   support.MessageRepository
+	users      user.Repository
+	audit      audit.Repository
+	stats      stats.Repository
+	quarantine quarantine.Repository
+
+	// ping checks that the storage backend is reachable; nil when there is
+	// nothing to check
+	ping func(ctx context.Context) error
+}
+
+func newRepositories(cfg *config.Config, clients *firebaseClients) (*repositories, error) {
+	switch cfg.Storage.Backend {
+	case config.StorageFirestore:
+		return newFirestoreRepositories(clients.firestore, cfg.Collections), nil
+	case config.StorageMemory:
+		return newMemoryRepositories(), nil
+	default:
+		return nil, fmt.Errorf("unknown storage backend %q", cfg.Storage.Backend)
+	}
+}
+
+// newFirestoreRepositories builds every repository on client, which they
+// share
+func newFirestoreRepositories(client *firestore.Client, cols config.CollectionsConfig) *repositories {
+	return &repositories{
+		products:   product.NewFirestoreRepository(client, cols.Products),
+		categories: category.NewFirestoreRepository(client, cols.Categories),
+		requests:   request.NewFirestoreRepository(client, cols.Requests),
+		supports:   support.NewFirestoreRepository(client, cols.Supports),
+		messages:   support.NewFirestoreMessageRepository(client, cols.SupportMessages),
+		users:      user.NewFirestoreRepository(client, cols.Users),
+		audit:      audit.NewFirestoreRepository(client, cols.AuditLogs),
+		quarantine: quarantine.NewFirestoreRepository(client, cols.Quarantine),
+		stats: stats.NewFirestoreRepository(client, stats.Collections{
+			Products:   cols.Products,
+			Categories: cols.Categories,
+			Requests:   cols.Requests,
+			Supports:   cols.Supports,
+		}),
+		ping: func(ctx context.Context) error {
+			iter := client.Collection(cols.Products).Limit(1).Documents(ctx)
+			defer iter.Stop()
+			if _, err := iter.Next(); err != nil && err != iterator.Done {
+				return err
+			}
+			return nil
+		},
+	}
+}
+
+func newMemoryRepositories() *repositories {
+	repos := &repositories{
+		products:   product.NewMemoryRepository(),
+		categories: category.NewMemoryRepository(),
+		requests:   request.NewMemoryRepository(),
+		supports:   support.NewMemoryRepository(),
+		messages:   support.NewMemoryMessageRepository(),
+		users:      user.NewMemoryRepository(),
+		audit:      audit.NewMemoryRepository(),
+		quarantine: quarantine.NewMemoryRepository(),
+	}
+	repos.stats = stats.NewMemoryRepository(repos.products, repos.categories, repos.requests, repos.supports)
+
+	return repos
+}
@@ -0,0 +1,179 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"mypremier-backend/internal/auth"
+	"mypremier-backend/internal/cache"
+	"mypremier-backend/internal/config"
+	"mypremier-backend/internal/modules/category"
+	"mypremier-backend/internal/modules/product"
+	"mypremier-backend/internal/modules/user"
+)
+
+// TestMemoryCatalog runs the catalog through the API on the in-memory
+// backend, with and without the read cache: what admins write is what the
+// public reads
+func TestMemoryCatalog(t *testing.T) {
+	for _, name := range []string{"uncached", "cached"} {
+		t.Run(name, func(t *testing.T) {
+			repos, opts := allRoutes()
+			repos.users = user.NewMemoryRepository(user.User{UID: "admin-uid", Email: "admin@example.com", Role: "admin", IsActive: true})
+			opts.verifier = uidVerifier{}
+			if name == "cached" {
+				// Admin writes go through the same cached repositories
+				c := cache.NewMemoryCache()
+				repos.products = cache.NewCachedRepository[product.Product](repos.products, cache.NewLoader(c, "products", time.Hour))
+				repos.categories = cache.NewCachedRepository[category.Category](repos.categories, cache.NewLoader(c, "categories", time.Hour))
+			}
+			rt := newRouter(repos, opts)
+
+			send := func(method, path, body string) *httptest.ResponseRecorder {
+				t.Helper()
+				var req *http.Request
+				if body != "" {
+					req = httptest.NewRequest(method, path, strings.NewReader(body))
+					req.Header.Set("Content-Type", "application/json")
+				} else {
+					req = httptest.NewRequest(method, path, nil)
+				}
+				if strings.HasPrefix(path, "/admin/") {
+					req.Header.Set("Authorization", "Bearer admin-uid")
+				}
+				rec := httptest.NewRecorder()
+				rt.ServeHTTP(rec, req)
+				return rec
+			}
+			decode := func(rec *httptest.ResponseRecorder, wantCode int, v interface{}) {
+				t.Helper()
+				if rec.Code != wantCode {
+					t.Fatalf("status %d, want %d: %s", rec.Code, wantCode, rec.Body)
+				}
+				if v != nil {
+					if err := json.Unmarshal(rec.Body.Bytes(), v); err != nil {
+						t.Fatalf("decoding %s: %v", rec.Body, err)
+					}
+				}
+			}
+
+			var created struct {
+				ID string `json:"id"`
+			}
+			decode(send(http.MethodPost, "/admin/categories", `{"name":"Pumps"}`), http.StatusCreated, &created)
+			categoryID := created.ID
+			decode(send(http.MethodPost, "/admin/products", `{"name":"P-100","category_id":"`+categoryID+`","is_active":true}`), http.StatusCreated, &created)
+			productID := created.ID
+			if productID == "" || categoryID == "" {
+				t.Fatalf("created IDs %q and %q", categoryID, productID)
+			}
+
+			var products []struct {
+				ID         string `json:"id"`
+				Name       string `json:"name"`
+				CategoryID string `json:"category_id"`
+				UpdatedAt  string `json:"updated_at"`
+			}
+			decode(send(http.MethodGet, "/products", ""), http.StatusOK, &products)
+			if len(products) != 1 || products[0].ID != productID || products[0].CategoryID != categoryID || products[0].UpdatedAt == "" {
+				t.Fatalf("GET /products = %+v", products)
+			}
+			var categories []struct {
+				Name string `json:"name"`
+			}
+			decode(send(http.MethodGet, "/categories", ""), http.StatusOK, &categories)
+			if len(categories) != 1 || categories[0].Name != "Pumps" {
+				t.Errorf("GET /categories = %+v", categories)
+			}
+
+			var product struct {
+				Name string `json:"name"`
+			}
+			decode(send(http.MethodGet, "/products/"+productID, ""), http.StatusOK, &product)
+			decode(send(http.MethodPut, "/admin/products/"+productID, `{"name":"P-200"}`), http.StatusOK, nil)
+			decode(send(http.MethodGet, "/products/"+productID, ""), http.StatusOK, &product)
+			if product.Name != "P-200" {
+				t.Errorf("after update: name %q", product.Name)
+			}
+
+			decode(send(http.MethodPut, "/admin/products/missing", `{"name":"P-300"}`), http.StatusNotFound, nil)
+			decode(send(http.MethodDelete, "/admin/products/"+productID, ""), http.StatusOK, nil)
+			rec := send(http.MethodGet, "/products/"+productID, "")
+			if rec.Code != http.StatusNotFound || !strings.Contains(rec.Body.String(), `"code":"product_not_found"`) {
+				t.Errorf("after delete: status %d, body %s; want a JSON 404", rec.Code, rec.Body)
+			}
+
+			logs, err := repos.audit.GetAll(context.Background())
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(logs) != 4 {
+				t.Errorf("%d audit entries for 4 writes: %+v", len(logs), logs)
+			}
+
+		})
+	}
+}
+
+// TestDevTokens signs in with tokens like those of cmd/devtoken on the
+// memory backend, whose users collection starts empty
+func TestDevTokens(t *testing.T) {
+	t.Setenv("MYPREMIER_CONFIG", "")
+	t.Setenv("MYPREMIER_AUTH_PROVIDER", "local")
+	t.Setenv("MYPREMIER_AUTH_LOCAL_SECRET", "0123456789abcdef0123456789abcdef")
+	t.Setenv("MYPREMIER_STORAGE", "memory")
+
+	tests := []struct {
+		name        string
+		changeStore string
+		role        string
+		active      bool
+		wantCode    int
+	}{
+		{"admin", "", "admin", true, http.StatusOK},
+		{"sales", "", "sales", true, http.StatusOK},
+		{"engineer without the permission", "", "engineer", true, http.StatusForbidden},
+		{"deactivated admin", "", "admin", false, http.StatusForbidden},
+		// With storage the UID needs a user document
+		{"storage", "storage", "admin", true, http.StatusNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("MYPREMIER_ACCESS_CHANGE_STORE", tt.changeStore)
+			cfg, err := config.Load("")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := cfg.Validate(); err != nil {
+				t.Fatal(err)
+			}
+			issuer, err := config.NewLocalIssuer(cfg.Auth.Local)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			repos, opts := allRoutes()
+			opts.verifier = issuer
+			opts.claims = user.NewClaimsSync(nil, repos.users, accessChanges(cfg, nil))
+			rt := newRouter(repos, opts)
+
+			// The claims cmd/devtoken puts in its tokens
+			token, err := issuer.Issue(auth.Principal{UID: "alice", Role: tt.role, Active: &tt.active}, time.Hour)
+			if err != nil {
+				t.Fatal(err)
+			}
+			req := httptest.NewRequest(http.MethodGet, "/admin/requests", nil)
+			req.Header.Set("Authorization", "Bearer "+token)
+			rec := httptest.NewRecorder()
+			rt.ServeHTTP(rec, req)
+			if rec.Code != tt.wantCode {
+				t.Errorf("GET /admin/requests: status %d, want %d: %s", rec.Code, tt.wantCode, rec.Body)
+			}
+		})
+	}
+}
@@ -0,0 +1,84 @@
+package antispam
+
+import (
+	"context"
+	"time"
+)
+
+// Forms protected by the pipeline
+const (
+	FormRequest = "request"
+	FormSupport = "support"
+)
+
+// Fields are the anti-spam proofs a client sends next to a submission
+type Fields struct {
+	// FormToken is the token from GET /antispam/form-token, fetched when the
+	// form was shown
+	FormToken string `json:"form_token,omitempty" validate:"max=256"`
+	// Challenge and Nonce are a solved proof-of-work from GET /antispam/challenge
+	Challenge string `json:"challenge,omitempty" validate:"max=256"`
+	Nonce     string `json:"nonce,omitempty" validate:"max=64"`
+}
+
+// Submission is one form post under evaluation
+type Submission struct {
+	Form       string
+	Data       map[string]interface{}
+	Fields     Fields
+	ClientIP   string
+	UserAgent  string
+	ReceivedAt time.Time
+}
+
+// Reason explains the part of a score contributed by one check
+type Reason struct {
+	Check  string  `firestore:"check" json:"check"`
+	Score  float64 `firestore:"score" json:"score"`
+	Detail string  `firestore:"detail" json:"detail"`
+}
+
+// Verdict is the outcome of running the pipeline
+type Verdict struct {
+	Score   float64
+	Reasons []Reason
+	// Flagged is set when Score reaches the pipeline's threshold
+	Flagged bool
+}
+
+// Check scores one aspect of a submission. A score of 0 means nothing
+// suspicious was found; detail is recorded for admins when the score is not 0.
+type Check interface {
+	Name() string
+	Score(ctx context.Context, s *Submission) (score float64, detail string)
+}
+
+// Pipeline runs checks and adds up their scores
+type Pipeline struct {
+	checks    []Check
+	threshold float64
+}
+
+// NewPipeline flags submissions scoring threshold or more
+func NewPipeline(threshold float64, checks ...Check) *Pipeline {
+	return &Pipeline{
+		checks:    checks,
+		threshold: threshold,
+	}
+}
+
+// Evaluate runs every check on s
+func (p *Pipeline) Evaluate(ctx context.Context, s *Submission) Verdict {
+	var v Verdict
+	for _, c := range p.checks {
+		score, detail := c.Score(ctx, s)
+		if score == 0 {
+			continue
+		}
+		v.Score += score
+		v.Reasons = append(v.Reasons, Reason{Check: c.Name(), Score: score, Detail: detail})
+	}
+	v.Flagged = v.Score >= p.threshold
+
+	return v
+}
@@ -0,0 +1,318 @@
+package antispam
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+)
+
+var start = time.Unix(1700000000, 0)
+
+func TestFormToken(t *testing.T) {
+	signer := NewSigner("secret")
+	token := signer.IssueFormToken(FormRequest, start)
+
+	issued, err := signer.ParseFormToken(token, FormRequest)
+	if err != nil || !issued.Equal(start) {
+		t.Fatalf("ParseFormToken = %s, %v; want %s", issued, err, start)
+	}
+
+	parts := strings.Split(token, ".")
+	tests := []struct {
+		name  string
+		token string
+		form  string
+	}{
+		{"other form", token, FormSupport},
+		{"earlier time", strings.Join([]string{parts[0], "1", parts[2], parts[3]}, "."), FormRequest},
+		{"other form field", strings.Join([]string{FormSupport, parts[1], parts[2], parts[3]}, "."), FormSupport},
+		{"bad signature", strings.Join(append(parts[:3:3], "AAAA"), "."), FormRequest},
+		{"other secret", NewSigner("other").IssueFormToken(FormRequest, start), FormRequest},
+		{"challenge as form token", signer.IssueChallenge(1, start), FormRequest},
+		{"missing signature", strings.Join(parts[:3], "."), FormRequest},
+		{"empty", "", FormRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := signer.ParseFormToken(tt.token, tt.form); err == nil {
+				t.Error("ParseFormToken accepted the token")
+			}
+		})
+	}
+}
+
+func TestRandomSecretsDiffer(t *testing.T) {
+	token := NewSigner("").IssueFormToken(FormRequest, start)
+	if _, err := NewSigner("").ParseFormToken(token, FormRequest); err == nil {
+		t.Error("a token verified under another random secret")
+	}
+}
+
+func TestFormTiming(t *testing.T) {
+	signer := NewSigner("secret")
+	check := FormTiming{Signer: signer, MinDelay: 3 * time.Second, MaxAge: time.Hour}
+	token := signer.IssueFormToken(FormRequest, start)
+
+	tests := []struct {
+		name      string
+		token     string
+		after     time.Duration
+		wantScore float64
+	}{
+		{"human pace", token, 10 * time.Second, 0},
+		{"exactly the minimum", token, 3 * time.Second, 0},
+		{"too fast", token, time.Second, scoreTooFast},
+		{"expired", token, 2 * time.Hour, scoreExpiredToken},
+		{"missing", "", time.Minute, scoreMissingProof},
+		{"tampered", token + "x", time.Minute, scoreInvalidProof},
+		{"other form", signer.IssueFormToken(FormSupport, start), time.Minute, scoreInvalidProof},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := &Submission{Form: FormRequest, Fields: Fields{FormToken: tt.token}, ReceivedAt: start.Add(tt.after)}
+			if score, detail := check.Score(context.Background(), s); score != tt.wantScore {
+				t.Errorf("score = %g (%s), want %g", score, detail, tt.wantScore)
+			}
+		})
+	}
+}
+
+func TestSolves(t *testing.T) {
+	challenge := NewSigner("secret").IssueChallenge(12, start)
+	nonce := Solve(challenge, 12)
+
+	if !Solves(challenge, nonce, 12) {
+		t.Fatalf("Solve returned %q, which does not solve the challenge", nonce)
+	}
+	if !Solves(challenge, nonce, 8) || !Solves(challenge, "anything", 0) {
+		t.Error("an easier difficulty is not solved")
+	}
+	if Solves(challenge, nonce, 257) {
+		t.Error("a difficulty beyond the hash length is solved")
+	}
+	if Solves(challenge+"x", nonce, 12) {
+		t.Error("the nonce solves another challenge")
+	}
+}
+
+func challengeStores(t *testing.T) map[string]func() ChallengeStore {
+	mr := miniredis.RunT(t)
+	rdb := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() { rdb.Close() })
+
+	// Each call is a replica: memory stores are their own, Redis stores
+	// share the server
+	return map[string]func() ChallengeStore{
+		"memory": func() ChallengeStore { return NewMemoryChallengeStore() },
+		"redis":  func() ChallengeStore { return NewRedisChallengeStore(rdb, "test:") },
+	}
+}
+
+// failingChallengeStore fails every operation
+type failingChallengeStore struct{}
+
+func (failingChallengeStore) MarkUsed(ctx context.Context, challenge string, expires time.Time) (bool, error) {
+	return false, errors.New("store down")
+}
+
+func TestProofOfWork(t *testing.T) {
+	signer := NewSigner("secret")
+	now := time.Now()
+	expires := now.Add(10 * time.Minute).Truncate(time.Second)
+	submit := func(check ProofOfWork, challenge, nonce string) (float64, string) {
+		s := &Submission{Fields: Fields{Challenge: challenge, Nonce: nonce}, ReceivedAt: now}
+		return check.Score(context.Background(), s)
+	}
+
+	for name, newStore := range challengeStores(t) {
+		t.Run(name, func(t *testing.T) {
+			check := ProofOfWork{Signer: signer, MinDifficulty: 8, Used: newStore()}
+
+			challenge := signer.IssueChallenge(8, expires)
+			nonce := Solve(challenge, 8)
+			if score, detail := submit(check, challenge, nonce); score != 0 {
+				t.Fatalf("solved challenge: score %g (%s)", score, detail)
+			}
+			if score, detail := submit(check, challenge, nonce); score != scoreInvalidProof || detail != "challenge already used" {
+				t.Errorf("replay: score %g (%s)", score, detail)
+			}
+
+			// Another replica sees the replay only through a shared store
+			other := ProofOfWork{Signer: signer, MinDifficulty: 8, Used: newStore()}
+			score, _ := submit(other, challenge, nonce)
+			if shared := name == "redis"; (score == scoreInvalidProof) != shared {
+				t.Errorf("replay on another replica: score %g, shared store %v", score, shared)
+			}
+
+			easy := signer.IssueChallenge(4, expires)
+			expired := signer.IssueChallenge(8, now.Add(-time.Second))
+			unsolved := signer.IssueChallenge(8, expires)
+			wrongNonce := "0"
+			for Solves(unsolved, wrongNonce, 8) {
+				wrongNonce += "0"
+			}
+			tests := []struct {
+				name      string
+				challenge string
+				nonce     string
+				wantScore float64
+			}{
+				{"missing", "", "", scoreMissingProof},
+				{"missing nonce", unsolved, "", scoreMissingProof},
+				{"forged", "8.9999999999.abc.sig", "1", scoreInvalidProof},
+				{"other secret", NewSigner("other").IssueChallenge(8, expires), "1", scoreInvalidProof},
+				{"too easy", easy, Solve(easy, 4), scoreInvalidProof},
+				{"expired", expired, Solve(expired, 8), scoreInvalidProof},
+				{"wrong nonce", unsolved, wrongNonce, scoreInvalidProof},
+			}
+			for _, tt := range tests {
+				if score, detail := submit(check, tt.challenge, tt.nonce); score != tt.wantScore {
+					t.Errorf("%s: score %g (%s), want %g", tt.name, score, detail, tt.wantScore)
+				}
+			}
+		})
+	}
+
+	// Without its store, a valid proof is still accepted
+	check := ProofOfWork{Signer: signer, MinDifficulty: 8, Used: failingChallengeStore{}}
+	challenge := signer.IssueChallenge(8, expires)
+	if score, detail := submit(check, challenge, Solve(challenge, 8)); score != 0 {
+		t.Errorf("failing store: score %g (%s), want 0", score, detail)
+	}
+}
+
+func TestMemoryChallengeStoreForgets(t *testing.T) {
+	s := NewMemoryChallengeStore()
+	now := start
+	s.now = func() time.Time { return now }
+	ctx := context.Background()
+
+	s.MarkUsed(ctx, "a", start.Add(time.Minute))
+	now = start.Add(2 * time.Minute)
+	s.MarkUsed(ctx, "b", now.Add(time.Minute))
+	if _, ok := s.used["a"]; ok {
+		t.Error("expired challenge kept")
+	}
+}
+
+func TestHoneypot(t *testing.T) {
+	check := Honeypot{Field: "website"}
+	tests := []struct {
+		name      string
+		data      map[string]interface{}
+		wantScore float64
+	}{
+		{"filled", map[string]interface{}{"name": "a", "website": "http://spam.example"}, scoreHoneypot},
+		{"blank", map[string]interface{}{"name": "a", "website": "  "}, 0},
+		{"null", map[string]interface{}{"name": "a", "website": nil}, 0},
+		{"absent", map[string]interface{}{"name": "a"}, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := &Submission{Data: tt.data}
+			if score, _ := check.Score(context.Background(), s); score != tt.wantScore {
+				t.Errorf("score = %g, want %g", score, tt.wantScore)
+			}
+			if _, ok := s.Data["website"]; ok {
+				t.Error("honeypot field left in the data")
+			}
+		})
+	}
+}
+
+func TestContent(t *testing.T) {
+	check := Content{MaxLinks: 2, BlockedDomains: []string{"spam.example"}}
+	tests := []struct {
+		name      string
+		data      map[string]interface{}
+		wantScore float64
+	}{
+		{"no links", map[string]interface{}{"message": "Hello"}, 0},
+		{"allowed links", map[string]interface{}{"message": "see https://a.example and www.b.example"}, 0},
+		{"one link too many", map[string]interface{}{"message": "http://a.example http://b.example http://c.example"}, 1},
+		{"links in nested fields", map[string]interface{}{
+			"a": "http://a.example", "b": []interface{}{"http://b.example", map[string]interface{}{"c": "http://c.example"}},
+		}, 1},
+		{"link score is capped", map[string]interface{}{"message": strings.Repeat("http://a.example ", 20)}, maxLinkScoreCounted},
+		{"blocked domain", map[string]interface{}{"message": "https://spam.example/buy"}, scoreBlockedDomain},
+		{"blocked subdomain", map[string]interface{}{"message": "www.shop.SPAM.example"}, scoreBlockedDomain},
+		{"blocked domain counted once", map[string]interface{}{"message": "http://spam.example http://spam.example/x"}, scoreBlockedDomain},
+		{"lookalike domain", map[string]interface{}{"message": "http://notspam.example"}, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if score, detail := check.Score(context.Background(), &Submission{Data: tt.data}); score != tt.wantScore {
+				t.Errorf("score = %g (%s), want %g", score, detail, tt.wantScore)
+			}
+		})
+	}
+}
+
+func TestScreen(t *testing.T) {
+	signer := NewSigner("secret")
+	now := time.Now()
+	pipeline := NewPipeline(5,
+		Honeypot{Field: "website"},
+		FormTiming{Signer: signer, MinDelay: 3 * time.Second, MaxAge: time.Hour},
+		ProofOfWork{Signer: signer, MinDifficulty: 8, Used: NewMemoryChallengeStore()},
+		Content{MaxLinks: 2},
+	)
+	var quarantined []Verdict
+	screener := NewScreener(Options{
+		Pipeline: pipeline,
+		Signer:   signer,
+		Quarantine: func(ctx context.Context, s Submission, v Verdict) (string, error) {
+			quarantined = append(quarantined, v)
+			return "q1", nil
+		},
+		ClientIP: func(r *http.Request) string { return "192.0.2.1" },
+	})
+	screener.now = func() time.Time { return now }
+
+	proofs := func() Fields {
+		challenge := signer.IssueChallenge(8, now.Add(time.Minute))
+		return Fields{
+			FormToken: signer.IssueFormToken(FormRequest, now.Add(-time.Minute)),
+			Challenge: challenge,
+			Nonce:     Solve(challenge, 8),
+		}
+	}
+	links := func(n int) string { return strings.Repeat("http://a.example ", n) }
+
+	tests := []struct {
+		name        string
+		data        map[string]interface{}
+		fields      Fields
+		wantFlagged bool
+	}{
+		{"with proofs", map[string]interface{}{"message": "Hello"}, proofs(), false},
+		{"legacy client", map[string]interface{}{"message": "Hello"}, Fields{}, false},
+		{"legacy client with one link too many", map[string]interface{}{"message": links(3)}, Fields{}, false},
+		{"legacy client with many links", map[string]interface{}{"message": links(6)}, Fields{}, true},
+		{"honeypot", map[string]interface{}{"website": "x"}, proofs(), true},
+		{"forged form token", map[string]interface{}{"message": "Hello"}, Fields{FormToken: "request.1.x.y"}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			quarantined = nil
+			req := httptest.NewRequest("POST", "/request-info", nil)
+			id, err := screener.Screen(req, FormRequest, tt.data, tt.fields)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if flagged := id != ""; flagged != tt.wantFlagged {
+				t.Errorf("quarantine ID %q, want flagged %v", id, tt.wantFlagged)
+			}
+			if (len(quarantined) == 1) != tt.wantFlagged {
+				t.Errorf("quarantined %v", quarantined)
+			}
+		})
+	}
+}
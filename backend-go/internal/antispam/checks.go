@@ -0,0 +1,190 @@
+package antispam
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"regexp"
+	"strings"
+	"time"
+
+	"mypremier-backend/internal/logging"
+)
+
+// Scores of the built-in checks. A submission is flagged at the pipeline
+// threshold, 5 by default, so one strong signal or a few weak ones flag it.
+// A missing proof is a soft signal: clients that predate form tokens and
+// challenges send neither, and must not be flagged for one link too many.
+const (
+	scoreHoneypot       = 10
+	scoreMissingProof   = 1
+	scoreInvalidProof   = 5
+	scoreTooFast        = 5
+	scoreExpiredToken   = 2
+	scorePerExtraLink   = 1
+	scoreBlockedDomain  = 5
+	maxLinkScoreCounted = 5
+)
+
+// Honeypot flags submissions that fill a field humans never see. The field
+// is removed from the data once checked.
+type Honeypot struct {
+	Field string
+}
+
+func (c Honeypot) Name() string { return "honeypot" }
+
+func (c Honeypot) Score(ctx context.Context, s *Submission) (float64, string) {
+	v, ok := s.Data[c.Field]
+	if !ok {
+		return 0, ""
+	}
+	delete(s.Data, c.Field)
+	if str, isString := v.(string); v == nil || isString && strings.TrimSpace(str) == "" {
+		return 0, ""
+	}
+	return scoreHoneypot, fmt.Sprintf("hidden field %q was filled", c.Field)
+}
+
+// FormTiming checks the signed form token: people take a while to fill a
+// form, scripts post at once
+type FormTiming struct {
+	Signer *Signer
+	// MinDelay is the least time between showing and submitting a form
+	MinDelay time.Duration
+	// MaxAge is how long a form token stays valid
+	MaxAge time.Duration
+}
+
+func (c FormTiming) Name() string { return "form_token" }
+
+func (c FormTiming) Score(ctx context.Context, s *Submission) (float64, string) {
+	if s.Fields.FormToken == "" {
+		return scoreMissingProof, "no form token"
+	}
+	issued, err := c.Signer.ParseFormToken(s.Fields.FormToken, s.Form)
+	if err != nil {
+		return scoreInvalidProof, "invalid form token: " + err.Error()
+	}
+
+	elapsed := s.ReceivedAt.Sub(issued)
+	switch {
+	case elapsed < c.MinDelay:
+		return scoreTooFast, fmt.Sprintf("submitted %s after the form was shown", elapsed.Round(time.Millisecond))
+	case elapsed > c.MaxAge:
+		return scoreExpiredToken, fmt.Sprintf("form token is %s old", elapsed.Round(time.Second))
+	}
+	return 0, ""
+}
+
+// ProofOfWork checks a solved challenge. Each challenge is accepted once
+// by Used, per replica or across replicas depending on the store.
+type ProofOfWork struct {
+	Signer *Signer
+	// MinDifficulty rejects challenges issued before the difficulty was raised
+	MinDifficulty int
+	Used          ChallengeStore
+}
+
+func (c ProofOfWork) Name() string { return "proof_of_work" }
+
+func (c ProofOfWork) Score(ctx context.Context, s *Submission) (float64, string) {
+	challenge, nonce := s.Fields.Challenge, s.Fields.Nonce
+	if challenge == "" || nonce == "" {
+		return scoreMissingProof, "no proof of work"
+	}
+
+	difficulty, expires, err := c.Signer.ParseChallenge(challenge)
+	switch {
+	case err != nil:
+		return scoreInvalidProof, "invalid challenge: " + err.Error()
+	case s.ReceivedAt.After(expires):
+		return scoreInvalidProof, "challenge expired"
+	case difficulty < c.MinDifficulty:
+		return scoreInvalidProof, "challenge too easy"
+	case !Solves(challenge, nonce, difficulty):
+		return scoreInvalidProof, "nonce does not solve the challenge"
+	}
+
+	fresh, err := c.Used.MarkUsed(ctx, challenge, expires)
+	if err != nil {
+		// The work was done; only a replay goes unnoticed
+		logging.FromContext(ctx).Warn("challenge store unavailable, accepting proof of work", "err", err)
+		return 0, ""
+	}
+	if !fresh {
+		return scoreInvalidProof, "challenge already used"
+	}
+	return 0, ""
+}
+
+var linkPattern = regexp.MustCompile(`(?i)\b(?:https?://|www\.)[^\s<>"']+`)
+
+// Content scores the text of a submission: many links, or links to
+// blocklisted domains, are what spam looks like
+type Content struct {
+	MaxLinks int
+	// BlockedDomains match the domain itself and its subdomains
+	BlockedDomains []string
+}
+
+func (c Content) Name() string { return "content" }
+
+func (c Content) Score(ctx context.Context, s *Submission) (float64, string) {
+	var links []string
+	walkStrings(s.Data, func(text string) {
+		links = append(links, linkPattern.FindAllString(text, -1)...)
+	})
+
+	var score float64
+	var details []string
+	if extra := len(links) - c.MaxLinks; extra > 0 {
+		score += scorePerExtraLink * float64(min(extra, maxLinkScoreCounted))
+		details = append(details, fmt.Sprintf("%d links", len(links)))
+	}
+
+	blocked := make(map[string]bool)
+	for _, link := range links {
+		if d := c.blockedDomain(link); d != "" && !blocked[d] {
+			blocked[d] = true
+			score += scoreBlockedDomain
+			details = append(details, "links to blocked domain "+d)
+		}
+	}
+
+	return score, strings.Join(details, "; ")
+}
+
+func (c Content) blockedDomain(link string) string {
+	if !strings.Contains(link, "://") {
+		link = "http://" + link
+	}
+	u, err := url.Parse(link)
+	if err != nil {
+		return ""
+	}
+	host := strings.ToLower(u.Hostname())
+	for _, d := range c.BlockedDomains {
+		d = strings.ToLower(d)
+		if host == d || strings.HasSuffix(host, "."+d) {
+			return d
+		}
+	}
+	return ""
+}
+
+// walkStrings calls fn for every string in a decoded JSON value
+func walkStrings(v interface{}, fn func(string)) {
+	switch v := v.(type) {
+	case string:
+		fn(v)
+	case map[string]interface{}:
+		for _, item := range v {
+			walkStrings(item, fn)
+		}
+	case []interface{}:
+		for _, item := range v {
+			walkStrings(item, fn)
+		}
+	}
+}
@@ -0,0 +1,148 @@
+package antispam
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"time"
+
+	"mypremier-backend/internal/apierror"
+	"mypremier-backend/internal/logging"
+	"mypremier-backend/internal/metrics"
+)
+
+// QuarantineFunc stores a flagged submission away from the main collection
+// and returns its quarantine ID
+type QuarantineFunc func(ctx context.Context, s Submission, v Verdict) (string, error)
+
+// Screener runs the pipeline on submissions and quarantines flagged ones.
+// It also serves the endpoints that hand out form tokens and challenges.
+type Screener struct {
+	pipeline   *Pipeline
+	signer     *Signer
+	quarantine QuarantineFunc
+	clientIP   func(*http.Request) string
+
+	difficulty   int
+	challengeTTL time.Duration
+	minDelay     time.Duration
+	now          func() time.Time
+}
+
+// Options configures a Screener
+type Options struct {
+	Pipeline   *Pipeline
+	Signer     *Signer
+	Quarantine QuarantineFunc
+	ClientIP   func(*http.Request) string
+	// Difficulty of issued challenges, in leading zero bits
+	Difficulty   int
+	ChallengeTTL time.Duration
+	// MinDelay is advertised with form tokens so clients can avoid it
+	MinDelay time.Duration
+}
+
+func NewScreener(opts Options) *Screener {
+	return &Screener{
+		pipeline:     opts.Pipeline,
+		signer:       opts.Signer,
+		quarantine:   opts.Quarantine,
+		clientIP:     opts.ClientIP,
+		difficulty:   opts.Difficulty,
+		challengeTTL: opts.ChallengeTTL,
+		minDelay:     opts.MinDelay,
+		now:          time.Now,
+	}
+}
+
+// Screen evaluates a submission of form. Flagged submissions are
+// quarantined and their quarantine ID returned; otherwise the ID is empty
+// and the caller stores data as usual. Checks may remove fields from data,
+// such as the honeypot.
+func (s *Screener) Screen(r *http.Request, form string, data map[string]interface{}, fields Fields) (string, error) {
+	ctx := r.Context()
+	sub := Submission{
+		Form:       form,
+		Data:       data,
+		Fields:     fields,
+		ClientIP:   s.clientIP(r),
+		UserAgent:  r.UserAgent(),
+		ReceivedAt: s.now(),
+	}
+
+	v := s.pipeline.Evaluate(ctx, &sub)
+	metrics.SpamScore.WithLabelValues(form).Observe(v.Score)
+	if !v.Flagged {
+		return "", nil
+	}
+
+	id, err := s.quarantine(ctx, sub, v)
+	if err != nil {
+		return "", err
+	}
+	metrics.SubmissionsQuarantined.WithLabelValues(form).Inc()
+	logging.FromContext(ctx).Info("submission quarantined", "form", form, "quarantine_id", id, "score", v.Score, "client_ip", sub.ClientIP)
+
+	return id, nil
+}
+
+// FormTokenResponse is returned by GET /antispam/form-token
+type FormTokenResponse struct {
+	Token string `json:"token"`
+	// MinDelaySeconds is how long to wait before submitting with the token
+	MinDelaySeconds float64 `json:"min_delay_seconds"`
+}
+
+// ChallengeResponse is returned by GET /antispam/challenge
+type ChallengeResponse struct {
+	Challenge string `json:"challenge"`
+	// Difficulty is the number of leading zero bits required of
+	// SHA-256(challenge + ":" + nonce)
+	Difficulty int       `json:"difficulty"`
+	ExpiresAt  time.Time `json:"expires_at"`
+}
+
+// FormToken issues a token for the form named in the "form" query parameter,
+// to be fetched when the form is shown
+func (s *Screener) FormToken(w http.ResponseWriter, r *http.Request) {
+	form := r.URL.Query().Get("form")
+	if form != FormRequest && form != FormSupport {
+		apierror.Write(w, r, apierror.Validation(apierror.FieldError{Field: "form", Message: "must be one of: " + FormRequest + ", " + FormSupport}))
+		return
+	}
+
+	writeJSON(w, r, FormTokenResponse{
+		Token:           s.signer.IssueFormToken(form, s.now()),
+		MinDelaySeconds: s.minDelay.Seconds(),
+	})
+}
+
+// Challenge issues a proof-of-work challenge
+func (s *Screener) Challenge(w http.ResponseWriter, r *http.Request) {
+	expires := s.now().Add(s.challengeTTL).Truncate(time.Second)
+	writeJSON(w, r, ChallengeResponse{
+		Challenge:  s.signer.IssueChallenge(s.difficulty, expires),
+		Difficulty: s.difficulty,
+		ExpiresAt:  expires.UTC(),
+	})
+}
+
+func writeJSON(w http.ResponseWriter, r *http.Request, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		logging.FromContext(r.Context()).Error("encoding response", "err", err)
+	}
+}
+
+// Solve finds a nonce for challenge. It is what clients do, and is used by
+// tests and tools.
+func Solve(challenge string, difficulty int) string {
+	for n := 0; ; n++ {
+		nonce := strconv.Itoa(n)
+		if Solves(challenge, nonce, difficulty) {
+			return nonce
+		}
+	}
+}
@@ -0,0 +1,77 @@
+package antispam
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// ChallengeStore remembers solved challenges until they expire, so that
+// each is accepted once
+type ChallengeStore interface {
+	// MarkUsed records challenge until expires, reporting false if it was
+	// recorded already
+	MarkUsed(ctx context.Context, challenge string, expires time.Time) (bool, error)
+}
+
+// MemoryChallengeStore keeps challenges in process memory. A challenge
+// solved once can be replayed on each other replica.
+type MemoryChallengeStore struct {
+	mu   sync.Mutex
+	used map[string]time.Time
+	now  func() time.Time
+}
+
+func NewMemoryChallengeStore() *MemoryChallengeStore {
+	return &MemoryChallengeStore{
+		used: make(map[string]time.Time),
+		now:  time.Now,
+	}
+}
+
+func (s *MemoryChallengeStore) MarkUsed(ctx context.Context, challenge string, expires time.Time) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.now()
+	for k, exp := range s.used {
+		if now.After(exp) {
+			delete(s.used, k)
+		}
+	}
+	if _, seen := s.used[challenge]; seen {
+		return false, nil
+	}
+	s.used[challenge] = expires
+
+	return true, nil
+}
+
+// RedisChallengeStore keeps challenges in Redis, so each is accepted once
+// across replicas
+type RedisChallengeStore struct {
+	client redis.Cmdable
+	prefix string
+}
+
+// NewRedisChallengeStore stores challenges under keys starting with prefix
+func NewRedisChallengeStore(client redis.Cmdable, prefix string) *RedisChallengeStore {
+	return &RedisChallengeStore{
+		client: client,
+		prefix: prefix,
+	}
+}
+
+func (s *RedisChallengeStore) MarkUsed(ctx context.Context, challenge string, expires time.Time) (bool, error) {
+	// Expired challenges are refused before they are recorded; the floor
+	// keeps a zero TTL from storing the key forever
+	ttl := max(time.Until(expires), time.Second)
+	stored, err := s.client.SetNX(ctx, s.prefix+challenge, 1, ttl).Result()
+	if err != nil {
+		return false, fmt.Errorf("recording challenge: %w", err)
+	}
+	return stored, nil
+}
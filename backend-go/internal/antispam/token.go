@@ -0,0 +1,130 @@
+package antispam
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	errMalformed = errors.New("malformed")
+	errSignature = errors.New("bad signature")
+)
+
+// Signer signs the tokens the server hands out, so it can check later that
+// it issued them without storing anything
+type Signer struct {
+	secret []byte
+}
+
+// NewSigner signs with secret. An empty secret picks a random one, which
+// only works with a single replica and invalidates tokens on restart.
+func NewSigner(secret string) *Signer {
+	key := []byte(secret)
+	if len(key) == 0 {
+		key = make([]byte, 32)
+		rand.Read(key)
+	}
+	return &Signer{secret: key}
+}
+
+// sign joins fields with dots and appends a signature. purpose keeps a token
+// of one kind from passing as another.
+func (s *Signer) sign(purpose string, fields ...string) string {
+	payload := strings.Join(fields, ".")
+	return payload + "." + s.mac(purpose, payload)
+}
+
+// verify checks the signature of token and returns its n fields
+func (s *Signer) verify(purpose, token string, n int) ([]string, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != n+1 {
+		return nil, errMalformed
+	}
+	payload := strings.Join(parts[:n], ".")
+	if !hmac.Equal([]byte(parts[n]), []byte(s.mac(purpose, payload))) {
+		return nil, errSignature
+	}
+	return parts[:n], nil
+}
+
+func (s *Signer) mac(purpose, payload string) string {
+	m := hmac.New(sha256.New, s.secret)
+	m.Write([]byte(purpose + "|" + payload))
+	return base64.RawURLEncoding.EncodeToString(m.Sum(nil))
+}
+
+func randomHex(n int) string {
+	b := make([]byte, n)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// IssueFormToken returns a token recording when form was shown
+func (s *Signer) IssueFormToken(form string, now time.Time) string {
+	return s.sign("form", form, strconv.FormatInt(now.UnixMilli(), 10), randomHex(8))
+}
+
+// ParseFormToken verifies a form token and returns when it was issued
+func (s *Signer) ParseFormToken(token, form string) (time.Time, error) {
+	fields, err := s.verify("form", token, 3)
+	if err != nil {
+		return time.Time{}, err
+	}
+	if fields[0] != form {
+		return time.Time{}, errors.New("issued for form " + fields[0])
+	}
+	ms, err := strconv.ParseInt(fields[1], 10, 64)
+	if err != nil {
+		return time.Time{}, errMalformed
+	}
+	return time.UnixMilli(ms), nil
+}
+
+// IssueChallenge returns a proof-of-work challenge of the given difficulty
+// that expires at expires
+func (s *Signer) IssueChallenge(difficulty int, expires time.Time) string {
+	return s.sign("pow", strconv.Itoa(difficulty), strconv.FormatInt(expires.Unix(), 10), randomHex(16))
+}
+
+// ParseChallenge verifies a challenge and returns its difficulty and expiry
+func (s *Signer) ParseChallenge(challenge string) (difficulty int, expires time.Time, err error) {
+	fields, err := s.verify("pow", challenge, 3)
+	if err != nil {
+		return 0, time.Time{}, err
+	}
+	difficulty, err = strconv.Atoi(fields[0])
+	if err != nil {
+		return 0, time.Time{}, errMalformed
+	}
+	unix, err := strconv.ParseInt(fields[1], 10, 64)
+	if err != nil {
+		return 0, time.Time{}, errMalformed
+	}
+	return difficulty, time.Unix(unix, 0), nil
+}
+
+// Solves reports whether SHA-256(challenge + ":" + nonce) starts with at
+// least difficulty zero bits
+func Solves(challenge, nonce string, difficulty int) bool {
+	sum := sha256.Sum256([]byte(challenge + ":" + nonce))
+	for _, b := range sum {
+		if difficulty <= 0 {
+			return true
+		}
+		if difficulty < 8 {
+			return b>>(8-difficulty) == 0
+		}
+		if b != 0 {
+			return false
+		}
+		difficulty -= 8
+	}
+	return difficulty <= 0
+}
@@ -0,0 +1,126 @@
+package apierror
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"mypremier-backend/internal/logging"
+	"mypremier-backend/internal/requestid"
+)
+
+// Error is an API failure rendered as
+//
+//	{"error": {"code": "...", "message": "...", "details": ..., "request_id": "..."}}
+//
+// Code is a stable, machine-readable snake_case identifier that clients can
+// branch on. Message is meant for humans and may change.
+type Error struct {
+	Status  int         `json:"-"`
+	Code    string      `json:"code"`
+	Message string      `json:"message"`
+	Details interface{} `json:"details,omitempty"`
+}
+
+func (e *Error) Error() string {
+	return e.Code + ": " + e.Message
+}
+
+// FieldError describes one invalid input field
+type FieldError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+// ValidationDetails is the details payload of a validation_failed error
+type ValidationDetails struct {
+	Fields []FieldError `json:"fields"`
+}
+
+type envelope struct {
+	Error body `json:"error"`
+}
+
+type body struct {
+	*Error
+	RequestID string `json:"request_id,omitempty"`
+}
+
+// New creates an error with the given status, code and message
+func New(status int, code, message string) *Error {
+	return &Error{Status: status, Code: code, Message: message}
+}
+
+// WithDetails returns a copy of e carrying extra details
+func (e *Error) WithDetails(details interface{}) *Error {
+	cp := *e
+	cp.Details = details
+	return &cp
+}
+
+func BadRequest(code, message string) *Error {
+	return New(http.StatusBadRequest, code, message)
+}
+
+func Unauthorized(code, message string) *Error {
+	return New(http.StatusUnauthorized, code, message)
+}
+
+func Forbidden(code, message string) *Error {
+	return New(http.StatusForbidden, code, message)
+}
+
+func NotFound(code, message string) *Error {
+	return New(http.StatusNotFound, code, message)
+}
+
+// Internal hides the cause of a server-side failure from the client
+func Internal() *Error {
+	return New(http.StatusInternalServerError, "internal_error", "Internal server error")
+}
+
+// InvalidBody reports a request body that could not be decoded
+func InvalidBody() *Error {
+	return BadRequest("invalid_request_body", "Invalid request body")
+}
+
+// Validation reports every invalid field at once
+func Validation(fields ...FieldError) *Error {
+	return BadRequest("validation_failed", "One or more fields are invalid").
+		WithDetails(ValidationDetails{Fields: fields})
+}
+
+// Required is the FieldError for a missing required field
+func Required(field string) FieldError {
+	return FieldError{Field: field, Message: "is required"}
+}
+
+// Write renders err as the JSON error envelope
+func Write(w http.ResponseWriter, r *http.Request, err *Error) {
+	data, marshalErr := json.Marshal(envelope{Error: body{
+		Error:     err,
+		RequestID: requestid.FromContext(r.Context()),
+	}})
+	if marshalErr != nil {
+		logging.FromContext(r.Context()).Error("encoding error response", "err", marshalErr)
+		data = []byte(`{"error":{"code":"internal_error","message":"Internal server error"}}`)
+		err = Internal()
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.WriteHeader(err.Status)
+	w.Write(append(data, '\n'))
+}
+
+// NotFoundHandler answers requests for unknown paths
+func NotFoundHandler(w http.ResponseWriter, r *http.Request) {
+	Write(w, r, NotFound("not_found", "No route matches "+r.URL.Path))
+}
+
+// MethodNotAllowed answers requests whose path exists under other methods
+func MethodNotAllowed(w http.ResponseWriter, r *http.Request, allow []string) {
+	w.Header().Set("Allow", strings.Join(allow, ", "))
+	Write(w, r, New(http.StatusMethodNotAllowed, "method_not_allowed", r.Method+" is not allowed on "+r.URL.Path).
+		WithDetails(map[string][]string{"allow": allow}))
+}
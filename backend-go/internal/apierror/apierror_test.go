@@ -0,0 +1,87 @@
+package apierror
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"mypremier-backend/internal/requestid"
+)
+
+func TestWrite(t *testing.T) {
+	tests := []struct {
+		name      string
+		err       *Error
+		requestID string
+		wantCode  int
+		wantBody  string
+	}{
+		{"plain", NotFound("product_not_found", "Product not found"), "",
+			http.StatusNotFound, `{"error":{"code":"product_not_found","message":"Product not found"}}`},
+		{"request ID", Unauthorized("missing_token", "Missing token"), "req-1",
+			http.StatusUnauthorized, `{"error":{"code":"missing_token","message":"Missing token","request_id":"req-1"}}`},
+		{"validation", Validation(Required("name"), FieldError{Field: "email", Message: "must be an email address"}), "",
+			http.StatusBadRequest, `{"error":{"code":"validation_failed","message":"One or more fields are invalid","details":` +
+				`{"fields":[{"field":"name","message":"is required"},{"field":"email","message":"must be an email address"}]}}}`},
+		{"internal", Internal(), "req-2",
+			http.StatusInternalServerError, `{"error":{"code":"internal_error","message":"Internal server error","request_id":"req-2"}}`},
+		{"details that cannot be encoded", Forbidden("forbidden", "Forbidden").WithDetails(make(chan int)), "",
+			http.StatusInternalServerError, `{"error":{"code":"internal_error","message":"Internal server error"}}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/x", nil)
+			if tt.requestID != "" {
+				req = req.WithContext(requestid.WithID(req.Context(), tt.requestID))
+			}
+			rec := httptest.NewRecorder()
+			Write(rec, req, tt.err)
+
+			if rec.Code != tt.wantCode {
+				t.Errorf("status %d, want %d", rec.Code, tt.wantCode)
+			}
+			if got := rec.Body.String(); got != tt.wantBody+"\n" {
+				t.Errorf("body %s, want %s", got, tt.wantBody)
+			}
+			if rec.Header().Get("Content-Type") != "application/json" || rec.Header().Get("X-Content-Type-Options") != "nosniff" {
+				t.Errorf("headers %v", rec.Header())
+			}
+		})
+	}
+}
+
+func TestWithDetailsCopies(t *testing.T) {
+	base := BadRequest("bad", "Bad")
+	detailed := base.WithDetails("x")
+	if base.Details != nil || detailed.Details != "x" || detailed.Code != "bad" {
+		t.Errorf("base %+v, detailed %+v", base, detailed)
+	}
+	if detailed.Error() != "bad: Bad" {
+		t.Errorf("Error() = %q", detailed.Error())
+	}
+}
+
+func TestRoutingErrors(t *testing.T) {
+	rec := httptest.NewRecorder()
+	NotFoundHandler(rec, httptest.NewRequest(http.MethodGet, "/nowhere", nil))
+	if rec.Code != http.StatusNotFound || rec.Body.String() != `{"error":{"code":"not_found","message":"No route matches /nowhere"}}`+"\n" {
+		t.Errorf("404: status %d, body %s", rec.Code, rec.Body)
+	}
+
+	rec = httptest.NewRecorder()
+	MethodNotAllowed(rec, httptest.NewRequest(http.MethodPatch, "/products", nil), []string{"GET", "HEAD"})
+	var env struct {
+		Error struct {
+			Code    string              `json:"code"`
+			Details map[string][]string `json:"details"`
+		} `json:"error"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &env); err != nil {
+		t.Fatal(err)
+	}
+	if rec.Code != http.StatusMethodNotAllowed || rec.Header().Get("Allow") != "GET, HEAD" ||
+		env.Error.Code != "method_not_allowed" || len(env.Error.Details["allow"]) != 2 {
+		t.Errorf("405: status %d, Allow %q, body %s", rec.Code, rec.Header().Get("Allow"), rec.Body)
+	}
+}
@@ -0,0 +1,64 @@
+package apierror
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+
+	"mypremier-backend/internal/logging"
+	"mypremier-backend/internal/store"
+)
+
+// FromError maps repository errors to API errors. This is the one place
+// that decides the HTTP status for domain errors; anything unrecognised is
+// an internal error.
+func FromError(err error) *Error {
+	var apiErr *Error
+	if errors.As(err, &apiErr) {
+		return apiErr
+	}
+
+	var storeErr *store.Error
+	if !errors.As(err, &storeErr) {
+		return Internal()
+	}
+
+	entity := storeErr.Entity
+	if entity == "" {
+		entity = "resource"
+	}
+	code := strings.ReplaceAll(entity, " ", "_")
+	label := strings.ToUpper(entity[:1]) + entity[1:]
+
+	switch storeErr.Kind {
+	case store.ErrNotFound:
+		return NotFound(code+"_not_found", label+" not found")
+	case store.ErrConflict:
+		msg := label + " conflicts with existing data"
+		if storeErr.Message != "" {
+			msg = storeErr.Message
+		}
+		return New(http.StatusConflict, code+"_conflict", msg)
+	case store.ErrInvalid:
+		msg := "is invalid"
+		if storeErr.Message != "" {
+			msg = storeErr.Message
+		}
+		if storeErr.Field != "" {
+			return Validation(FieldError{Field: storeErr.Field, Message: msg})
+		}
+		return BadRequest("invalid_"+code, label+" "+msg)
+	default:
+		return Internal()
+	}
+}
+
+// WriteError renders err with FromError, logging failures that end up as
+// internal errors
+func WriteError(w http.ResponseWriter, r *http.Request, err error) {
+	apiErr := FromError(err)
+	if apiErr.Status >= http.StatusInternalServerError {
+		logging.FromContext(r.Context()).Error("request failed", "err", err)
+	}
+	Write(w, r, apiErr)
+}
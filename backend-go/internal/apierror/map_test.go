@@ -0,0 +1,62 @@
+package apierror
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"mypremier-backend/internal/store"
+)
+
+func TestFromError(t *testing.T) {
+	apiErr := Forbidden("forbidden", "Forbidden")
+	tests := []struct {
+		name        string
+		err         error
+		wantStatus  int
+		wantCode    string
+		wantMessage string
+		wantDetails interface{}
+	}{
+		{"not found", store.NotFound("product", "p1"), http.StatusNotFound, "product_not_found", "Product not found", nil},
+		{"wrapped not found", fmt.Errorf("loading: %w", store.NotFound("support message", "m1")),
+			http.StatusNotFound, "support_message_not_found", "Support message not found", nil},
+		{"conflict", store.Conflict("user", "u1", ""), http.StatusConflict, "user_conflict", "User conflicts with existing data", nil},
+		{"conflict with a message", store.Conflict("user", "u1", "Email is already used by another account"),
+			http.StatusConflict, "user_conflict", "Email is already used by another account", nil},
+		{"invalid field", store.Invalid("user", "email", "must be an email address"),
+			http.StatusBadRequest, "validation_failed", "One or more fields are invalid",
+			ValidationDetails{Fields: []FieldError{{Field: "email", Message: "must be an email address"}}}},
+		{"invalid field without a message", store.Invalid("user", "email", ""),
+			http.StatusBadRequest, "validation_failed", "One or more fields are invalid",
+			ValidationDetails{Fields: []FieldError{{Field: "email", Message: "is invalid"}}}},
+		{"invalid document", store.Invalid("category", "", "has children"), http.StatusBadRequest, "invalid_category", "Category has children", nil},
+		{"no entity", &store.Error{Kind: store.ErrNotFound}, http.StatusNotFound, "resource_not_found", "Resource not found", nil},
+		{"unknown kind", &store.Error{Kind: errors.New("odd"), Entity: "product"}, http.StatusInternalServerError, "internal_error", "Internal server error", nil},
+		{"API error", fmt.Errorf("checking: %w", apiErr), http.StatusForbidden, "forbidden", "Forbidden", nil},
+		{"anything else", errors.New("connection reset"), http.StatusInternalServerError, "internal_error", "Internal server error", nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := FromError(tt.err)
+			if got.Status != tt.wantStatus || got.Code != tt.wantCode || got.Message != tt.wantMessage {
+				t.Errorf("FromError = %d %s %q, want %d %s %q", got.Status, got.Code, got.Message, tt.wantStatus, tt.wantCode, tt.wantMessage)
+			}
+			if !reflect.DeepEqual(got.Details, tt.wantDetails) {
+				t.Errorf("details %+v, want %+v", got.Details, tt.wantDetails)
+			}
+		})
+	}
+}
+
+func TestWriteErrorHidesCauses(t *testing.T) {
+	rec := httptest.NewRecorder()
+	WriteError(rec, httptest.NewRequest(http.MethodGet, "/x", nil), errors.New("dial tcp 10.0.0.5:443: connection refused"))
+	if rec.Code != http.StatusInternalServerError || strings.Contains(rec.Body.String(), "10.0.0.5") {
+		t.Errorf("status %d, body %s; want a 500 without the cause", rec.Code, rec.Body)
+	}
+}
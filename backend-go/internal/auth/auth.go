@@ -0,0 +1,61 @@
+// Package auth identifies the callers of the API. A Verifier turns a bearer
+// token into a Principal, which middleware stores in the request context for
+// handlers and modules to read.
+package auth
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// Custom claims carrying the role and status of a user, mirrored from the
+// users collection
+const (
+	ClaimRole   = "role"
+	ClaimActive = "active"
+)
+
+// ErrInvalidToken is returned by verifiers for tokens that are malformed,
+// wrongly signed or expired
+var ErrInvalidToken = errors.New("invalid token")
+
+// Principal is the verified identity of a caller
+type Principal struct {
+	UID   string
+	Email string
+	// Role is the role claimed by the token, if any. It may be stale;
+	// authorisation uses the role loaded by middleware.LoadUserRole.
+	Role string
+	// Active is the status claimed by the token, nil if it carries none.
+	// Like Role it may be stale.
+	Active *bool
+	// Claims holds every claim of the token, including custom ones
+	Claims map[string]interface{}
+	// IssuedAt is when the token was issued
+	IssuedAt time.Time
+	// AuthTime is when the user signed in, which refreshing a token keeps
+	AuthTime time.Time
+}
+
+// Verifier checks bearer tokens
+type Verifier interface {
+	// Verify returns the principal of a valid token. Invalid tokens give an
+	// error wrapping ErrInvalidToken; other errors mean the token could not
+	// be checked.
+	Verify(ctx context.Context, token string) (*Principal, error)
+}
+
+type contextKey struct{}
+
+// NewContext returns a copy of ctx carrying p
+func NewContext(ctx context.Context, p *Principal) context.Context {
+	return context.WithValue(ctx, contextKey{}, p)
+}
+
+// FromContext returns the principal of the request, or nil for anonymous
+// requests
+func FromContext(ctx context.Context) *Principal {
+	p, _ := ctx.Value(contextKey{}).(*Principal)
+	return p
+}
@@ -0,0 +1,54 @@
+package auth
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	firebaseauth "firebase.google.com/go/auth"
+)
+
+// IDTokenVerifier checks Firebase ID tokens. *firebaseauth.Client
+// implements it.
+type IDTokenVerifier interface {
+	VerifyIDToken(ctx context.Context, idToken string) (*firebaseauth.Token, error)
+}
+
+// FirebaseVerifier accepts Firebase ID tokens
+type FirebaseVerifier struct {
+	client IDTokenVerifier
+}
+
+// NewFirebaseVerifier verifies tokens with client, one client shared by
+// every request
+func NewFirebaseVerifier(client IDTokenVerifier) *FirebaseVerifier {
+	return &FirebaseVerifier{
+		client: client,
+	}
+}
+
+// Verify checks an ID token with Firebase Auth
+func (v *FirebaseVerifier) Verify(ctx context.Context, token string) (*Principal, error) {
+	t, err := v.client.VerifyIDToken(ctx, token)
+	if err != nil {
+		// The client does not tell bad tokens from failures to fetch its
+		// keys; both are refused
+		return nil, fmt.Errorf("%w: %v", ErrInvalidToken, err)
+	}
+
+	email, _ := t.Claims["email"].(string)
+	role, _ := t.Claims[ClaimRole].(string)
+	var active *bool
+	if a, ok := t.Claims[ClaimActive].(bool); ok {
+		active = &a
+	}
+	return &Principal{
+		UID:      t.UID,
+		Email:    email,
+		Role:     role,
+		Active:   active,
+		Claims:   t.Claims,
+		IssuedAt: time.Unix(t.IssuedAt, 0),
+		AuthTime: time.Unix(t.AuthTime, 0),
+	}, nil
+}
@@ -0,0 +1,189 @@
+package auth
+
+import (
+	"context"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/go-jose/go-jose/v4"
+	"github.com/go-jose/go-jose/v4/jwt"
+)
+
+// Algorithms of locally issued tokens
+const (
+	HS256 = "HS256"
+	RS256 = "RS256"
+)
+
+// MinSecretLength is the shortest HS256 secret accepted, in bytes
+const MinSecretLength = 32
+
+// LocalIssuer mints and verifies JWTs signed with a local key. It stands in
+// for Firebase in development and tests, where no Firebase project is at
+// hand; tokens carry the same claims as Firebase ID tokens with mirrored
+// custom claims.
+type LocalIssuer struct {
+	issuer    string
+	algorithm jose.SignatureAlgorithm
+	signer    jose.Signer
+	// verifyKey is the secret for HS256 and the public key for RS256
+	verifyKey interface{}
+}
+
+// NewLocalIssuer signs tokens as issuer with algorithm, HS256 or RS256. key
+// is the secret for HS256 and a PEM encoded RSA private key, PKCS #1 or
+// PKCS #8, for RS256.
+func NewLocalIssuer(issuer, algorithm string, key []byte) (*LocalIssuer, error) {
+	l := &LocalIssuer{issuer: issuer}
+
+	var signingKey interface{}
+	switch algorithm {
+	case HS256:
+		if len(key) < MinSecretLength {
+			return nil, fmt.Errorf("HS256 secret must be at least %d bytes, got %d", MinSecretLength, len(key))
+		}
+		l.algorithm = jose.HS256
+		signingKey, l.verifyKey = key, key
+	case RS256:
+		private, err := parseRSAPrivateKey(key)
+		if err != nil {
+			return nil, err
+		}
+		l.algorithm = jose.RS256
+		signingKey, l.verifyKey = private, &private.PublicKey
+	default:
+		return nil, fmt.Errorf("unsupported algorithm %q, want HS256 or RS256", algorithm)
+	}
+
+	signer, err := jose.NewSigner(jose.SigningKey{Algorithm: l.algorithm, Key: signingKey}, (&jose.SignerOptions{}).WithType("JWT"))
+	if err != nil {
+		return nil, fmt.Errorf("creating signer: %w", err)
+	}
+	l.signer = signer
+
+	return l, nil
+}
+
+// localClaims are the claims of a locally issued token besides the
+// registered ones and the custom claims
+type localClaims struct {
+	AuthTime int64  `json:"auth_time"`
+	Email    string `json:"email,omitempty"`
+	Role     string `json:"role,omitempty"`
+	Active   *bool  `json:"active,omitempty"`
+}
+
+// reservedClaims are set by Issue from the fields of the principal. Custom
+// claims with these names are dropped, so that a token never claims an
+// identity, role or lifetime other than the one it was issued for.
+var reservedClaims = []string{
+	"iss", "sub", "aud", "exp", "nbf", "iat", "jti",
+	"auth_time", "email", ClaimRole, ClaimActive,
+}
+
+// Issue mints a token for p that expires after ttl. The custom claims of p
+// are included, except reservedClaims; a zero AuthTime means the user signs
+// in now.
+func (l *LocalIssuer) Issue(p Principal, ttl time.Duration) (string, error) {
+	if p.UID == "" {
+		return "", errors.New("issuing token: UID is required")
+	}
+	now := time.Now()
+	if p.AuthTime.IsZero() {
+		p.AuthTime = now
+	}
+
+	registered := jwt.Claims{
+		Issuer:   l.issuer,
+		Subject:  p.UID,
+		Audience: jwt.Audience{l.issuer},
+		IssuedAt: jwt.NewNumericDate(now),
+		Expiry:   jwt.NewNumericDate(now.Add(ttl)),
+	}
+	own := localClaims{
+		AuthTime: p.AuthTime.Unix(),
+		Email:    p.Email,
+		Role:     p.Role,
+		Active:   p.Active,
+	}
+
+	builder := jwt.Signed(l.signer)
+	if custom := customClaims(p.Claims); len(custom) > 0 {
+		builder = builder.Claims(custom)
+	}
+	token, err := builder.Claims(registered).Claims(own).Serialize()
+	if err != nil {
+		return "", fmt.Errorf("issuing token: %w", err)
+	}
+	return token, nil
+}
+
+// Verify checks the signature, issuer, audience and lifetime of a token
+// minted by l
+func (l *LocalIssuer) Verify(ctx context.Context, token string) (*Principal, error) {
+	parsed, err := jwt.ParseSigned(token, []jose.SignatureAlgorithm{l.algorithm})
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidToken, err)
+	}
+
+	var (
+		registered jwt.Claims
+		own        localClaims
+		all        map[string]interface{}
+	)
+	if err := parsed.Claims(l.verifyKey, &registered, &own, &all); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidToken, err)
+	}
+	if registered.Expiry == nil || registered.IssuedAt == nil || registered.Subject == "" {
+		return nil, fmt.Errorf("%w: exp, iat and sub are required", ErrInvalidToken)
+	}
+	expected := jwt.Expected{Issuer: l.issuer, AnyAudience: jwt.Audience{l.issuer}}
+	if err := registered.ValidateWithLeeway(expected, jwt.DefaultLeeway); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidToken, err)
+	}
+
+	return &Principal{
+		UID:      registered.Subject,
+		Email:    own.Email,
+		Role:     own.Role,
+		Active:   own.Active,
+		Claims:   all,
+		IssuedAt: registered.IssuedAt.Time(),
+		AuthTime: time.Unix(own.AuthTime, 0),
+	}, nil
+}
+
+// customClaims returns claims without reservedClaims
+func customClaims(claims map[string]interface{}) map[string]interface{} {
+	custom := make(map[string]interface{}, len(claims))
+	for name, value := range claims {
+		if !slices.Contains(reservedClaims, name) {
+			custom[name] = value
+		}
+	}
+	return custom
+}
+
+func parseRSAPrivateKey(data []byte) (*rsa.PrivateKey, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, errors.New("RS256 key is not PEM encoded")
+	}
+	if key, err := x509.ParsePKCS1PrivateKey(block.Bytes); err == nil {
+		return key, nil
+	}
+	key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("parsing RS256 key: %w", err)
+	}
+	rsaKey, ok := key.(*rsa.PrivateKey)
+	if !ok {
+		return nil, errors.New("RS256 key is not an RSA private key")
+	}
+	return rsaKey, nil
+}
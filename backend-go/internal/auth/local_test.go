@@ -0,0 +1,271 @@
+package auth
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-jose/go-jose/v4"
+	"github.com/go-jose/go-jose/v4/jwt"
+)
+
+const testSecret = "0123456789abcdef0123456789abcdef"
+
+func rsaKey(t *testing.T) *rsa.PrivateKey {
+	t.Helper()
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return key
+}
+
+func pkcs1PEM(key *rsa.PrivateKey) []byte {
+	return pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(key)})
+}
+
+func pkcs8PEM(t *testing.T, key interface{}) []byte {
+	t.Helper()
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der})
+}
+
+func newIssuer(t *testing.T, issuer, algorithm string, key []byte) *LocalIssuer {
+	t.Helper()
+	l, err := NewLocalIssuer(issuer, algorithm, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return l
+}
+
+// sign signs claims with key outside of LocalIssuer, to forge tokens it
+// would never issue
+func sign(t *testing.T, alg jose.SignatureAlgorithm, key interface{}, claims ...interface{}) string {
+	t.Helper()
+	signer, err := jose.NewSigner(jose.SigningKey{Algorithm: alg, Key: key}, (&jose.SignerOptions{}).WithType("JWT"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	builder := jwt.Signed(signer)
+	for _, c := range claims {
+		builder = builder.Claims(c)
+	}
+	token, err := builder.Serialize()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return token
+}
+
+func TestLocalIssuerRoundTrip(t *testing.T) {
+	key := rsaKey(t)
+	active := true
+
+	tests := []struct {
+		name      string
+		algorithm string
+		key       []byte
+	}{
+		{"HS256", HS256, []byte(testSecret)},
+		{"RS256 PKCS1", RS256, pkcs1PEM(key)},
+		{"RS256 PKCS8", RS256, pkcs8PEM(t, key)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l := newIssuer(t, "mypremier-test", tt.algorithm, tt.key)
+			authTime := time.Now().Add(-time.Hour).Truncate(time.Second)
+
+			token, err := l.Issue(Principal{
+				UID:      "alice",
+				Email:    "alice@example.com",
+				Role:     "admin",
+				Active:   &active,
+				Claims:   map[string]interface{}{"tier": "gold"},
+				AuthTime: authTime,
+			}, time.Hour)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			p, err := l.Verify(context.Background(), token)
+			if err != nil {
+				t.Fatalf("Verify: %v", err)
+			}
+			if p.UID != "alice" || p.Email != "alice@example.com" || p.Role != "admin" {
+				t.Errorf("principal = %+v", p)
+			}
+			if p.Active == nil || !*p.Active {
+				t.Errorf("Active = %v, want true", p.Active)
+			}
+			if !p.AuthTime.Equal(authTime) {
+				t.Errorf("AuthTime = %s, want %s", p.AuthTime, authTime)
+			}
+			if d := time.Since(p.IssuedAt); d < 0 || d > time.Minute {
+				t.Errorf("IssuedAt = %s, want now", p.IssuedAt)
+			}
+			if p.Claims["tier"] != "gold" || p.Claims[ClaimRole] != "admin" || p.Claims[ClaimActive] != true {
+				t.Errorf("claims = %v", p.Claims)
+			}
+		})
+	}
+}
+
+func TestLocalIssuerReservedClaims(t *testing.T) {
+	l := newIssuer(t, "mypremier-test", HS256, []byte(testSecret))
+
+	token, err := l.Issue(Principal{
+		UID: "bob",
+		Claims: map[string]interface{}{
+			"iss":       "someone-else",
+			"sub":       "alice",
+			"aud":       "elsewhere",
+			"exp":       time.Now().Add(24 * 365 * time.Hour).Unix(),
+			"auth_time": 1,
+			"email":     "alice@example.com",
+			ClaimRole:   "admin",
+			ClaimActive: true,
+			"tier":      "gold",
+		},
+	}, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	p, err := l.Verify(context.Background(), token)
+	if err != nil {
+		t.Fatalf("Verify: %v", err)
+	}
+	if p.UID != "bob" || p.Email != "" || p.Role != "" || p.Active != nil {
+		t.Errorf("principal = %+v, want bob without email, role or status", p)
+	}
+	for _, name := range []string{"email", ClaimRole, ClaimActive} {
+		if v, ok := p.Claims[name]; ok {
+			t.Errorf("claim %s = %v, want none", name, v)
+		}
+	}
+	if p.Claims["tier"] != "gold" {
+		t.Errorf("custom claim tier = %v, want gold", p.Claims["tier"])
+	}
+	if exp, _ := p.Claims["exp"].(float64); time.Until(time.Unix(int64(exp), 0)) > time.Hour {
+		t.Errorf("exp = %v, want within the ttl", p.Claims["exp"])
+	}
+	if p.AuthTime.Unix() == 1 {
+		t.Error("auth_time was overridden")
+	}
+}
+
+func TestLocalIssuerRejects(t *testing.T) {
+	key := rsaKey(t)
+	hs := newIssuer(t, "mypremier-test", HS256, []byte(testSecret))
+	rs := newIssuer(t, "mypremier-test", RS256, pkcs1PEM(key))
+
+	now := time.Now()
+	valid := jwt.Claims{
+		Issuer:   "mypremier-test",
+		Subject:  "alice",
+		Audience: jwt.Audience{"mypremier-test"},
+		IssuedAt: jwt.NewNumericDate(now),
+		Expiry:   jwt.NewNumericDate(now.Add(time.Hour)),
+	}
+	with := func(change func(c *jwt.Claims)) jwt.Claims {
+		c := valid
+		change(&c)
+		return c
+	}
+	issue := func(l *LocalIssuer, ttl time.Duration) string {
+		token, err := l.Issue(Principal{UID: "alice", Role: "admin"}, ttl)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return token
+	}
+	b64 := base64.RawURLEncoding.EncodeToString
+	publicPEM := pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: must(x509.MarshalPKIXPublicKey(&key.PublicKey))})
+	good := issue(hs, time.Hour)
+	parts := strings.Split(good, ".")
+
+	tests := []struct {
+		name     string
+		verifier *LocalIssuer
+		token    string
+	}{
+		{"wrong secret", hs, sign(t, jose.HS256, []byte(strings.Repeat("x", 32)), valid)},
+		{"wrong RSA key", rs, issue(newIssuer(t, "mypremier-test", RS256, pkcs1PEM(rsaKey(t))), time.Hour)},
+		{"alg none", hs, b64([]byte(`{"alg":"none","typ":"JWT"}`)) + "." + parts[1] + "."},
+		{"HS256 token to RS256 verifier", rs, good},
+		{"RS256 token to HS256 verifier", hs, issue(rs, time.Hour)},
+		{"HS256 signed with the RSA public key", rs, sign(t, jose.HS256, publicPEM, valid)},
+		{"expired", hs, issue(hs, -2*jwt.DefaultLeeway)},
+		{"issued in the future", hs, sign(t, jose.HS256, []byte(testSecret), with(func(c *jwt.Claims) {
+			c.IssuedAt = jwt.NewNumericDate(now.Add(time.Hour))
+		}))},
+		{"wrong issuer", hs, issue(newIssuer(t, "someone-else", HS256, []byte(testSecret)), time.Hour)},
+		{"wrong audience", hs, sign(t, jose.HS256, []byte(testSecret), with(func(c *jwt.Claims) {
+			c.Audience = jwt.Audience{"elsewhere"}
+		}))},
+		{"no audience", hs, sign(t, jose.HS256, []byte(testSecret), with(func(c *jwt.Claims) { c.Audience = nil }))},
+		{"no expiry", hs, sign(t, jose.HS256, []byte(testSecret), with(func(c *jwt.Claims) { c.Expiry = nil }))},
+		{"no subject", hs, sign(t, jose.HS256, []byte(testSecret), with(func(c *jwt.Claims) { c.Subject = "" }))},
+		{"tampered payload", hs, parts[0] + "." + b64([]byte(`{"sub":"mallory"}`)) + "." + parts[2]},
+		{"empty", hs, ""},
+		{"not a JWT", hs, "not-a-token"},
+		{"garbage segments", hs, "a.b.c"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p, err := tt.verifier.Verify(context.Background(), tt.token)
+			if !errors.Is(err, ErrInvalidToken) {
+				t.Fatalf("Verify = %+v, %v; want ErrInvalidToken", p, err)
+			}
+		})
+	}
+}
+
+func TestNewLocalIssuerKeys(t *testing.T) {
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name      string
+		algorithm string
+		key       []byte
+	}{
+		{"short secret", HS256, []byte("too-short")},
+		{"unknown algorithm", "ES256", []byte(testSecret)},
+		{"none", "none", nil},
+		{"RS256 key not PEM", RS256, []byte(testSecret)},
+		{"RS256 key not RSA", RS256, pkcs8PEM(t, ecKey)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := NewLocalIssuer("mypremier-test", tt.algorithm, tt.key); err == nil {
+				t.Error("NewLocalIssuer accepted the key")
+			}
+		})
+	}
+}
+
+func must(b []byte, err error) []byte {
+	if err != nil {
+		panic(err)
+	}
+	return b
+}
@@ -0,0 +1,19 @@
+// Package cache keeps the results of expensive reads for a while, so that
+// repeated requests do not reach the database each time
+package cache
+
+import (
+	"context"
+	"time"
+)
+
+// Cache stores encoded values until they expire. Implementations are safe
+// for concurrent use; errors are treated as misses by Loader.
+type Cache interface {
+	// Get returns the value of key, or false when it is missing or expired
+	Get(ctx context.Context, key string) ([]byte, bool, error)
+	// Set stores value under key for ttl
+	Set(ctx context.Context, key string, value []byte, ttl time.Duration) error
+	// Delete removes keys; missing keys are not an error
+	Delete(ctx context.Context, keys ...string) error
+}
@@ -0,0 +1,254 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+)
+
+// testCache is a cache with a clock the test can move
+type testCache struct {
+	Cache
+	advance func(d time.Duration)
+}
+
+func caches(t *testing.T) map[string]testCache {
+	mr := miniredis.RunT(t)
+	rdb := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() { rdb.Close() })
+
+	memory := NewMemoryCache()
+	now := time.Unix(1700000000, 0)
+	memory.now = func() time.Time { return now }
+
+	return map[string]testCache{
+		"memory": {memory, func(d time.Duration) { now = now.Add(d) }},
+		"redis":  {NewRedisCache(rdb, "test:"), mr.FastForward},
+	}
+}
+
+func TestCache(t *testing.T) {
+	ctx := context.Background()
+	for name, c := range caches(t) {
+		t.Run(name, func(t *testing.T) {
+			if _, ok, err := c.Get(ctx, "a"); ok || err != nil {
+				t.Fatalf("Get of a missing key = %v, %v", ok, err)
+			}
+			c.Set(ctx, "a", []byte("1"), time.Minute)
+			c.Set(ctx, "b", []byte("2"), time.Hour)
+			if v, ok, err := c.Get(ctx, "a"); !ok || err != nil || string(v) != "1" {
+				t.Fatalf("Get = %q, %v, %v; want 1", v, ok, err)
+			}
+
+			c.advance(2 * time.Minute)
+			if _, ok, _ := c.Get(ctx, "a"); ok {
+				t.Error("expired value returned")
+			}
+			if err := c.Delete(ctx, "b", "missing"); err != nil {
+				t.Fatal(err)
+			}
+			if _, ok, _ := c.Get(ctx, "b"); ok {
+				t.Error("deleted value returned")
+			}
+			if err := c.Delete(ctx); err != nil {
+				t.Errorf("Delete of no keys: %v", err)
+			}
+		})
+	}
+}
+
+// failingCache fails every operation
+type failingCache struct{}
+
+func (failingCache) Get(ctx context.Context, key string) ([]byte, bool, error) {
+	return nil, false, errors.New("cache down")
+}
+
+func (failingCache) Set(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+	return errors.New("cache down")
+}
+
+func (failingCache) Delete(ctx context.Context, keys ...string) error {
+	return errors.New("cache down")
+}
+
+type item struct {
+	Name string   `json:"name"`
+	Tags []string `json:"tags"`
+}
+
+func TestLoad(t *testing.T) {
+	ctx := context.Background()
+	for name, c := range caches(t) {
+		t.Run(name, func(t *testing.T) {
+			l := NewLoader(c, "items", time.Minute)
+			var calls int
+			load := func(ctx context.Context) (*item, error) {
+				calls++
+				return &item{Name: "a", Tags: []string{"x"}}, nil
+			}
+
+			first, err := Load(ctx, l, "1", load)
+			if err != nil || first.Name != "a" {
+				t.Fatalf("Load = %+v, %v", first, err)
+			}
+			first.Tags[0] = "changed"
+			second, _ := Load(ctx, l, "1", load)
+			if calls != 1 {
+				t.Errorf("load called %d times, want 1", calls)
+			}
+			if second.Tags[0] != "x" {
+				t.Errorf("a caller's change leaked into the cache: %v", second.Tags)
+			}
+			if data, ok, _ := c.Get(ctx, "items:1"); !ok || string(data) != `{"name":"a","tags":["x"]}` {
+				t.Errorf("cached %s, %v", data, ok)
+			}
+
+			l.Invalidate(ctx, "1")
+			Load(ctx, l, "1", load)
+			if calls != 2 {
+				t.Errorf("load called %d times after Invalidate, want 2", calls)
+			}
+
+			c.advance(2 * time.Minute)
+			Load(ctx, l, "1", load)
+			if calls != 3 {
+				t.Errorf("load called %d times after expiry, want 3", calls)
+			}
+
+			// Errors reach the caller and are not cached
+			boom := errors.New("boom")
+			for i := 0; i < 2; i++ {
+				if _, err := Load(ctx, l, "2", func(ctx context.Context) (*item, error) {
+					calls++
+					return nil, boom
+				}); !errors.Is(err, boom) {
+					t.Errorf("Load = %v, want the load error", err)
+				}
+			}
+			if calls != 5 {
+				t.Errorf("load called %d times, want 5: errors are not cached", calls)
+			}
+		})
+	}
+}
+
+func TestLoadWithoutCache(t *testing.T) {
+	ctx := context.Background()
+	l := NewLoader(failingCache{}, "items", time.Minute)
+	var calls int
+	for i := 0; i < 2; i++ {
+		v, err := Load(ctx, l, "1", func(ctx context.Context) (string, error) {
+			calls++
+			return "a", nil
+		})
+		if err != nil || v != "a" {
+			t.Fatalf("Load = %q, %v; want the loaded value", v, err)
+		}
+	}
+	if calls != 2 {
+		t.Errorf("load called %d times, want 2", calls)
+	}
+	l.Invalidate(ctx, "1")
+
+	// A value that does not decode is a miss
+	c := NewMemoryCache()
+	c.Set(ctx, "items:1", []byte("not json"), time.Minute)
+	l = NewLoader(c, "items", time.Minute)
+	if v, err := Load(ctx, l, "1", func(ctx context.Context) (int, error) { return 7, nil }); err != nil || v != 7 {
+		t.Errorf("Load over a corrupt entry = %d, %v; want 7", v, err)
+	}
+}
+
+func TestLoadSharesConcurrentMisses(t *testing.T) {
+	ctx := context.Background()
+	l := NewLoader(NewMemoryCache(), "items", time.Minute)
+	release := make(chan struct{})
+	var calls atomic.Int32
+	load := func(ctx context.Context) (string, error) {
+		calls.Add(1)
+		<-release
+		return "a", nil
+	}
+
+	const callers = 10
+	var wg sync.WaitGroup
+	results := make([]string, callers)
+	for i := range callers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			results[i], _ = Load(ctx, l, "1", load)
+		}()
+	}
+	for calls.Load() == 0 {
+		time.Sleep(time.Millisecond)
+	}
+	// Let the other callers reach the shared load
+	time.Sleep(20 * time.Millisecond)
+	close(release)
+	wg.Wait()
+
+	if calls.Load() != 1 {
+		t.Errorf("load called %d times for %d concurrent misses, want 1", calls.Load(), callers)
+	}
+	for i, v := range results {
+		if v != "a" {
+			t.Errorf("caller %d got %q", i, v)
+		}
+	}
+}
+
+func TestInvalidateDuringLoad(t *testing.T) {
+	ctx := context.Background()
+	for name, c := range caches(t) {
+		for _, replica := range []string{"same replica", "other replica"} {
+			t.Run(name+"/"+replica, func(t *testing.T) {
+				l := NewLoader(c, "items", time.Minute)
+				// Replicas sharing the cache each have their own loader
+				writer := l
+				if replica == "other replica" {
+					writer = NewLoader(c, "items", time.Minute)
+				}
+				c.Delete(ctx, "items:1")
+				started := make(chan struct{})
+				release := make(chan struct{})
+
+				// The load reads the old value, then a write invalidates the
+				// key before the load finishes
+				done := make(chan string)
+				go func() {
+					v, _ := Load(ctx, l, "1", func(ctx context.Context) (string, error) {
+						close(started)
+						<-release
+						return "old", nil
+					})
+					done <- v
+				}()
+				<-started
+				writer.Invalidate(ctx, "1")
+				close(release)
+				if v := <-done; v != "old" {
+					t.Errorf("running load returned %q", v)
+				}
+
+				if _, ok, _ := c.Get(ctx, "items:1"); ok {
+					t.Error("a load older than the invalidation was cached")
+				}
+				v, _ := Load(ctx, l, "1", func(ctx context.Context) (string, error) { return "new", nil })
+				if v != "new" {
+					t.Errorf("Load after the write = %q, want new", v)
+				}
+				if v, _ := Load(ctx, writer, "1", func(ctx context.Context) (string, error) { return "other", nil }); v != "new" {
+					t.Errorf("Load on the writer = %q, want the cached new", v)
+				}
+			})
+		}
+	}
+}
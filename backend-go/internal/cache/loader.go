@@ -0,0 +1,146 @@
+package cache
+
+import (
+	"context"
+	"encoding/json"
+	"math/rand/v2"
+	"strconv"
+	"sync/atomic"
+	"time"
+
+	"mypremier-backend/internal/logging"
+	"mypremier-backend/internal/metrics"
+
+	"golang.org/x/sync/singleflight"
+)
+
+// Loader reads values through a Cache. Concurrent misses on a key share one
+// load, so an expired entry does not send a stampede to the database.
+//
+// Every invalidation stores a new version of the key in the cache. A load
+// that saw another version before and after it may have read data older
+// than the write that caused the invalidation, and is not cached. The
+// version lives in the cache so that, with a shared cache, an invalidation
+// on one replica also stops the loads running on the others.
+type Loader struct {
+	cache Cache
+	name  string
+	ttl   time.Duration
+	group singleflight.Group
+	// sequence makes the versions written by this loader unique, with
+	// instance telling loaders apart
+	instance string
+	sequence atomic.Uint64
+}
+
+// NewLoader caches values in c for ttl under keys starting with name, which
+// also labels the hit and miss metrics
+func NewLoader(c Cache, name string, ttl time.Duration) *Loader {
+	return &Loader{
+		cache:    c,
+		name:     name,
+		ttl:      ttl,
+		instance: strconv.FormatUint(rand.Uint64(), 36),
+	}
+}
+
+// Load returns the cached value of key, or calls load and caches its result.
+// Errors from load are returned and not cached; errors from the cache are
+// logged and treated as misses.
+func Load[T any](ctx context.Context, l *Loader, key string, load func(ctx context.Context) (T, error)) (T, error) {
+	key = l.name + ":" + key
+	log := logging.FromContext(ctx)
+
+	if data, ok, err := l.cache.Get(ctx, key); err != nil {
+		log.Warn("reading cache", "cache", l.name, "err", err)
+	} else if ok {
+		var v T
+		err := json.Unmarshal(data, &v)
+		if err == nil {
+			metrics.CacheRequests.WithLabelValues(l.name, "hit").Inc()
+			return v, nil
+		}
+		log.Warn("decoding cached value", "cache", l.name, "err", err)
+	}
+	metrics.CacheRequests.WithLabelValues(l.name, "miss").Inc()
+
+	// The shared load outlives the caller that started it if that caller
+	// gives up, so the others still get a result
+	loadCtx := context.WithoutCancel(ctx)
+	result, err, _ := l.group.Do(key, func() (interface{}, error) {
+		version, ok := l.version(loadCtx, key)
+		v, err := load(loadCtx)
+		if err != nil {
+			return nil, err
+		}
+		data, err := json.Marshal(v)
+		if err != nil {
+			return nil, err
+		}
+		if !ok || !l.unchanged(loadCtx, key, version) {
+			return data, nil
+		}
+		if err := l.cache.Set(loadCtx, key, data, l.ttl); err != nil {
+			log.Warn("writing cache", "cache", l.name, "err", err)
+			return data, nil
+		}
+		// An invalidation between the check and Set would otherwise be lost
+		if !l.unchanged(loadCtx, key, version) {
+			if err := l.cache.Delete(loadCtx, key); err != nil {
+				log.Warn("invalidating cache", "cache", l.name, "err", err)
+			}
+		}
+		return data, nil
+	})
+
+	var v T
+	if err != nil {
+		return v, err
+	}
+	// Each caller decodes its own copy, so none can change another's
+	if err := json.Unmarshal(result.([]byte), &v); err != nil {
+		return v, err
+	}
+	return v, nil
+}
+
+// Invalidate drops keys so that the next Load reads them again, including
+// loads that are already running
+func (l *Loader) Invalidate(ctx context.Context, keys ...string) {
+	log := logging.FromContext(ctx)
+	prefixed := make([]string, len(keys))
+	for i, key := range keys {
+		prefixed[i] = l.name + ":" + key
+		l.group.Forget(prefixed[i])
+		// A version outliving the entries is enough: a load running longer
+		// than the TTL sees the version expire and is not cached either
+		version := l.instance + "." + strconv.FormatUint(l.sequence.Add(1), 36)
+		if err := l.cache.Set(ctx, versionKey(prefixed[i]), []byte(version), l.ttl); err != nil {
+			log.Error("invalidating cache", "cache", l.name, "keys", keys, "err", err)
+		}
+	}
+	if err := l.cache.Delete(ctx, prefixed...); err != nil {
+		log.Error("invalidating cache", "cache", l.name, "keys", keys, "err", err)
+	}
+}
+
+// version returns the version of key, empty when it was never invalidated.
+// ok is false when it could not be read.
+func (l *Loader) version(ctx context.Context, key string) (version string, ok bool) {
+	data, _, err := l.cache.Get(ctx, versionKey(key))
+	if err != nil {
+		logging.FromContext(ctx).Warn("reading cache version", "cache", l.name, "err", err)
+		return "", false
+	}
+	return string(data), true
+}
+
+// unchanged reports whether key is still at version
+func (l *Loader) unchanged(ctx context.Context, key, version string) bool {
+	current, ok := l.version(ctx, key)
+	return ok && current == version
+}
+
+func versionKey(key string) string {
+	return key + "#version"
+}
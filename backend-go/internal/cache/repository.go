@@ -0,0 +1,60 @@
+package cache
+
+import "context"
+
+// Repository is the storage contract of a collection read through
+// CachedRepository, such as products or categories
+type Repository[T any] interface {
+	GetAll(ctx context.Context) ([]T, error)
+	GetByID(ctx context.Context, id string) (*T, error)
+	Create(ctx context.Context, v T) (string, error)
+	Update(ctx context.Context, id string, v T) error
+	Delete(ctx context.Context, id string) error
+}
+
+// allKey caches the result of GetAll; GetByID results are cached under
+// their ID
+const allKey = "all"
+
+// CachedRepository reads a repository through a Loader. Writes go to the
+// wrapped repository and then drop the entries they affect, even when they
+// fail, as the write may have happened anyway, e.g. after a timeout.
+type CachedRepository[T any] struct {
+	repo   Repository[T]
+	loader *Loader
+}
+
+func NewCachedRepository[T any](repo Repository[T], loader *Loader) *CachedRepository[T] {
+	return &CachedRepository[T]{
+		repo:   repo,
+		loader: loader,
+	}
+}
+
+func (r *CachedRepository[T]) GetAll(ctx context.Context) ([]T, error) {
+	return Load(ctx, r.loader, allKey, r.repo.GetAll)
+}
+
+func (r *CachedRepository[T]) GetByID(ctx context.Context, id string) (*T, error) {
+	return Load(ctx, r.loader, "id:"+id, func(ctx context.Context) (*T, error) {
+		return r.repo.GetByID(ctx, id)
+	})
+}
+
+func (r *CachedRepository[T]) Create(ctx context.Context, v T) (string, error) {
+	id, err := r.repo.Create(ctx, v)
+	r.loader.Invalidate(ctx, allKey)
+	return id, err
+}
+
+func (r *CachedRepository[T]) Update(ctx context.Context, id string, v T) error {
+	err := r.repo.Update(ctx, id, v)
+	r.loader.Invalidate(ctx, allKey, "id:"+id)
+	return err
+}
+
+func (r *CachedRepository[T]) Delete(ctx context.Context, id string) error {
+	err := r.repo.Delete(ctx, id)
+	r.loader.Invalidate(ctx, allKey, "id:"+id)
+	return err
+}
@@ -0,0 +1,108 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"mypremier-backend/internal/store"
+)
+
+// itemRepository stores items in memory and counts the reads reaching it
+type itemRepository struct {
+	docs  *store.Collection[item]
+	reads int
+}
+
+func (r *itemRepository) GetAll(ctx context.Context) ([]item, error) {
+	r.reads++
+	return r.docs.Query(nil, nil), nil
+}
+
+func (r *itemRepository) GetByID(ctx context.Context, id string) (*item, error) {
+	r.reads++
+	v, ok := r.docs.Get(id)
+	if !ok {
+		return nil, store.NotFound("item", id)
+	}
+	return &v, nil
+}
+
+func (r *itemRepository) Create(ctx context.Context, v item) (string, error) {
+	id := store.NewID()
+	r.docs.Set(id, v)
+	return id, nil
+}
+
+func (r *itemRepository) Update(ctx context.Context, id string, v item) error {
+	if !r.docs.Update(id, func(doc *item) { *doc = v }) {
+		return store.NotFound("item", id)
+	}
+	return nil
+}
+
+func (r *itemRepository) Delete(ctx context.Context, id string) error {
+	if !r.docs.Delete(id) {
+		return store.NotFound("item", id)
+	}
+	return nil
+}
+
+func TestCachedRepository(t *testing.T) {
+	ctx := context.Background()
+	for name, c := range caches(t) {
+		t.Run(name, func(t *testing.T) {
+			backing := &itemRepository{docs: store.NewCollection[item]()}
+			repo := NewCachedRepository[item](backing, NewLoader(c, "items", time.Minute))
+			id, err := repo.Create(ctx, item{Name: "Pump"})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			// reads checks that the listing and the item are called name, and
+			// that wantReads reads reached the backing repository
+			reads := func(step, name string, wantReads int) {
+				t.Helper()
+				backing.reads = 0
+				for i := 0; i < 2; i++ {
+					all, err := repo.GetAll(ctx)
+					if err != nil || len(all) != 1 || all[0].Name != name {
+						t.Fatalf("%s: GetAll = %+v, %v; want %s", step, all, err, name)
+					}
+					v, err := repo.GetByID(ctx, id)
+					if err != nil || v.Name != name {
+						t.Fatalf("%s: GetByID = %+v, %v; want %s", step, v, err, name)
+					}
+				}
+				if backing.reads != wantReads {
+					t.Errorf("%s: %d reads reached the repository, want %d", step, backing.reads, wantReads)
+				}
+			}
+
+			reads("after create", "Pump", 2)
+			reads("cached", "Pump", 0)
+
+			if err := repo.Update(ctx, id, item{Name: "Valve"}); err != nil {
+				t.Fatal(err)
+			}
+			reads("after update", "Valve", 2)
+
+			// A failed write still drops the entries, as it may have happened
+			if err := repo.Update(ctx, "missing", item{Name: "x"}); !errors.Is(err, store.ErrNotFound) {
+				t.Errorf("Update of a missing item: %v", err)
+			}
+			reads("after a failed update", "Valve", 1)
+
+			if err := repo.Delete(ctx, id); err != nil {
+				t.Fatal(err)
+			}
+			if all, _ := repo.GetAll(ctx); len(all) != 0 {
+				t.Errorf("GetAll after delete = %+v", all)
+			}
+			if _, err := repo.GetByID(ctx, id); !errors.Is(err, store.ErrNotFound) {
+				t.Errorf("GetByID after delete: %v, want not found", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,99 @@
+package clientip
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"net/netip"
+	"strings"
+)
+
+// Resolver finds the address of the client behind a request. X-Forwarded-For
+// is only believed when the connection comes from a trusted proxy, since any
+// client can send the header.
+type Resolver struct {
+	trusted []netip.Prefix
+}
+
+// NewResolver trusts the given proxies, each a CIDR like "10.0.0.0/8" or a
+// single address
+func NewResolver(trustedProxies []string) (*Resolver, error) {
+	r := &Resolver{}
+	for _, s := range trustedProxies {
+		p, err := ParsePrefix(s)
+		if err != nil {
+			return nil, err
+		}
+		r.trusted = append(r.trusted, p)
+	}
+
+	return r, nil
+}
+
+// ParsePrefix parses a CIDR or a single address
+func ParsePrefix(s string) (netip.Prefix, error) {
+	if strings.Contains(s, "/") {
+		p, err := netip.ParsePrefix(s)
+		if err != nil {
+			return netip.Prefix{}, fmt.Errorf("invalid proxy CIDR %q", s)
+		}
+		return p.Masked(), nil
+	}
+	addr, err := netip.ParseAddr(s)
+	if err != nil {
+		return netip.Prefix{}, fmt.Errorf("invalid proxy address %q", s)
+	}
+	return netip.PrefixFrom(addr.Unmap(), addr.Unmap().BitLen()), nil
+}
+
+// ClientIP returns the client address of req. It walks X-Forwarded-For from
+// the right, past every trusted proxy, and returns the first untrusted hop.
+func (r *Resolver) ClientIP(req *http.Request) string {
+	remote := parseAddr(req.RemoteAddr)
+	if !remote.IsValid() {
+		return req.RemoteAddr
+	}
+	if !r.isTrusted(remote) {
+		return remote.String()
+	}
+
+	hops := req.Header.Values("X-Forwarded-For")
+	client := remote
+	for i := len(hops) - 1; i >= 0; i-- {
+		parts := strings.Split(hops[i], ",")
+		for j := len(parts) - 1; j >= 0; j-- {
+			addr := parseAddr(strings.TrimSpace(parts[j]))
+			if !addr.IsValid() {
+				// A garbled hop was added by someone we can't vouch for
+				return client.String()
+			}
+			client = addr
+			if !r.isTrusted(addr) {
+				return client.String()
+			}
+		}
+	}
+
+	return client.String()
+}
+
+func (r *Resolver) isTrusted(addr netip.Addr) bool {
+	for _, p := range r.trusted {
+		if p.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
+
+// parseAddr accepts "ip", "ip:port" and "[ipv6]:port"
+func parseAddr(s string) netip.Addr {
+	if host, _, err := net.SplitHostPort(s); err == nil {
+		s = host
+	}
+	addr, err := netip.ParseAddr(s)
+	if err != nil {
+		return netip.Addr{}
+	}
+	return addr.Unmap()
+}
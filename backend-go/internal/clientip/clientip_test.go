@@ -0,0 +1,44 @@
+package clientip
+
+import (
+	"net/http/httptest"
+	"testing"
+)
+
+func TestClientIP(t *testing.T) {
+	r, err := NewResolver([]string{"10.0.0.0/8", "2001:db8::1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name   string
+		remote string
+		xff    []string
+		want   string
+	}{
+		{"direct client", "192.0.2.7:5123", nil, "192.0.2.7"},
+		{"untrusted peer cannot spoof", "192.0.2.7:5123", []string{"198.51.100.1"}, "192.0.2.7"},
+		{"trusted proxy", "10.1.2.3:80", []string{"198.51.100.1"}, "198.51.100.1"},
+		{"chain of trusted proxies", "10.1.2.3:80", []string{"203.0.113.9, 198.51.100.1, 10.0.0.5"}, "198.51.100.1"},
+		{"spoofed left-most entry", "10.1.2.3:80", []string{"1.1.1.1", "198.51.100.1"}, "198.51.100.1"},
+		{"all hops trusted", "10.1.2.3:80", []string{"10.0.0.9"}, "10.0.0.9"},
+		{"garbled hop", "10.1.2.3:80", []string{"nonsense"}, "10.1.2.3"},
+		{"trusted proxy without header", "10.1.2.3:80", nil, "10.1.2.3"},
+		{"ipv6 proxy", "[2001:db8::1]:443", []string{"2001:db8::42"}, "2001:db8::42"},
+		{"ipv4-mapped peer", "[::ffff:10.1.2.3]:80", []string{"198.51.100.1"}, "198.51.100.1"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", "/", nil)
+			req.RemoteAddr = tt.remote
+			for _, v := range tt.xff {
+				req.Header.Add("X-Forwarded-For", v)
+			}
+			if got := r.ClientIP(req); got != tt.want {
+				t.Errorf("ClientIP = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,27 @@
+package config
+
+import (
+	"fmt"
+	"os"
+
+	"mypremier-backend/internal/auth"
+)
+
+// NewLocalIssuer creates the issuer of local tokens described by cfg, used
+// by the server to verify them and by cmd/devtoken to mint them
+func NewLocalIssuer(cfg LocalAuthConfig) (*auth.LocalIssuer, error) {
+	key := []byte(cfg.Secret)
+	if cfg.Algorithm == auth.RS256 {
+		var err error
+		key, err = os.ReadFile(cfg.PrivateKeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read local auth key: %w", err)
+		}
+	}
+
+	issuer, err := auth.NewLocalIssuer(cfg.Issuer, cfg.Algorithm, key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create local token issuer: %w", err)
+	}
+	return issuer, nil
+}
@@ -0,0 +1,126 @@
+package config
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"mypremier-backend/internal/auth"
+)
+
+func writeRSAKey(t *testing.T) string {
+	t.Helper()
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(t.TempDir(), "local.pem")
+	data := pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(key)})
+	if err := os.WriteFile(path, data, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestNewLocalIssuer(t *testing.T) {
+	keyFile := writeRSAKey(t)
+	defaults := Default().Auth.Local
+
+	tests := []struct {
+		name    string
+		change  func(c *LocalAuthConfig)
+		wantErr string
+	}{
+		{"HS256", func(c *LocalAuthConfig) { c.Secret = strings.Repeat("s", 32) }, ""},
+		{"RS256", func(c *LocalAuthConfig) { c.Algorithm, c.PrivateKeyFile = auth.RS256, keyFile }, ""},
+		{"short secret", func(c *LocalAuthConfig) { c.Secret = "short" }, "at least 32 bytes"},
+		{"missing key file", func(c *LocalAuthConfig) {
+			c.Algorithm, c.PrivateKeyFile = auth.RS256, filepath.Join(t.TempDir(), "missing.pem")
+		}, "failed to read local auth key"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := defaults
+			tt.change(&cfg)
+
+			issuer, err := NewLocalIssuer(cfg)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("err = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			token, err := issuer.Issue(auth.Principal{UID: "alice"}, cfg.TokenTTL)
+			if err != nil {
+				t.Fatal(err)
+			}
+			p, err := issuer.Verify(context.Background(), token)
+			if err != nil || p.UID != "alice" {
+				t.Errorf("Verify = %+v, %v", p, err)
+			}
+		})
+	}
+}
+
+func TestValidateLocalAuth(t *testing.T) {
+	keyFile := writeRSAKey(t)
+
+	tests := []struct {
+		name    string
+		change  func(c *AuthConfig)
+		wantKey string
+	}{
+		{"firebase", func(c *AuthConfig) {}, ""},
+		{"HS256", func(c *AuthConfig) { c.Provider, c.Local.Secret = AuthLocal, strings.Repeat("s", 32) }, ""},
+		{"RS256", func(c *AuthConfig) {
+			c.Provider, c.Local.Algorithm, c.Local.PrivateKeyFile = AuthLocal, auth.RS256, keyFile
+		}, ""},
+		{"unknown provider", func(c *AuthConfig) { c.Provider = "ldap" }, "auth.provider"},
+		{"no secret", func(c *AuthConfig) { c.Provider = AuthLocal }, "auth.local.secret"},
+		{"no key file", func(c *AuthConfig) { c.Provider, c.Local.Algorithm = AuthLocal, auth.RS256 }, "auth.local.private_key_file"},
+		{"unreadable key file", func(c *AuthConfig) {
+			c.Provider, c.Local.Algorithm, c.Local.PrivateKeyFile = AuthLocal, auth.RS256, filepath.Join(t.TempDir(), "missing.pem")
+		}, "auth.local.private_key_file"},
+		{"unknown algorithm", func(c *AuthConfig) { c.Provider, c.Local.Algorithm = AuthLocal, "none" }, "auth.local.algorithm"},
+		{"no issuer", func(c *AuthConfig) {
+			c.Provider, c.Local.Secret, c.Local.Issuer = AuthLocal, strings.Repeat("s", 32), ""
+		}, "auth.local.issuer"},
+		{"no ttl", func(c *AuthConfig) {
+			c.Provider, c.Local.Secret, c.Local.TokenTTL = AuthLocal, strings.Repeat("s", 32), 0
+		}, "auth.local.token_ttl"},
+		{"negative ttl", func(c *AuthConfig) {
+			c.Provider, c.Local.Secret, c.Local.TokenTTL = AuthLocal, strings.Repeat("s", 32), -time.Hour
+		}, "auth.local.token_ttl"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := Default()
+			cfg.Storage.Backend = StorageMemory
+			tt.change(&cfg.Auth)
+
+			err := cfg.Validate()
+			if tt.wantKey == "" {
+				if err != nil {
+					t.Fatalf("Validate: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantKey+":") {
+				t.Fatalf("Validate = %v, want an error for %s", err, tt.wantKey)
+			}
+		})
+	}
+}
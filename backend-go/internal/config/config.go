@@ -0,0 +1,942 @@
+package config
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"os"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"mypremier-backend/internal/auth"
+	"mypremier-backend/internal/clientip"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Storage backends
+const (
+	StorageFirestore = "firestore"
+	StorageMemory    = "memory"
+)
+
+// Identity providers
+const (
+	AuthFirebase = "firebase"
+	AuthLocal    = "local"
+)
+
+// Config is the complete server configuration. Values are resolved in this
+// order: built-in defaults, the optional config file, then environment
+// variables (named in the env tags).
+type Config struct {
+	Server          ServerConfig          `yaml:"server"`
+	Health          HealthConfig          `yaml:"health"`
+	Logging         LoggingConfig         `yaml:"logging"`
+	Metrics         MetricsConfig         `yaml:"metrics"`
+	Tracing         TracingConfig         `yaml:"tracing"`
+	Storage         StorageConfig         `yaml:"storage"`
+	Firebase        FirebaseConfig        `yaml:"firebase"`
+	Auth            AuthConfig            `yaml:"auth"`
+	CORS            CORSConfig            `yaml:"cors"`
+	SecurityHeaders SecurityHeadersConfig `yaml:"security_headers"`
+	Limits          LimitsConfig          `yaml:"limits"`
+	Catalog         CatalogConfig         `yaml:"catalog"`
+	RateLimit       RateLimitConfig       `yaml:"rate_limit"`
+	Idempotency     IdempotencyConfig     `yaml:"idempotency"`
+	Redis           RedisConfig           `yaml:"redis"`
+	Antispam        AntispamConfig        `yaml:"antispam"`
+	Access          AccessConfig          `yaml:"access"`
+	Roles           []RoleConfig          `yaml:"roles"`
+	Collections     CollectionsConfig     `yaml:"collections"`
+}
+
+type ServerConfig struct {
+	Addr              string        `yaml:"addr" env:"MYPREMIER_ADDR"`
+	ReadTimeout       time.Duration `yaml:"read_timeout" env:"MYPREMIER_READ_TIMEOUT"`
+	ReadHeaderTimeout time.Duration `yaml:"read_header_timeout" env:"MYPREMIER_READ_HEADER_TIMEOUT"`
+	WriteTimeout      time.Duration `yaml:"write_timeout" env:"MYPREMIER_WRITE_TIMEOUT"`
+	IdleTimeout       time.Duration `yaml:"idle_timeout" env:"MYPREMIER_IDLE_TIMEOUT"`
+	// ShutdownTimeout bounds how long in-flight requests and workers get to finish on SIGTERM
+	ShutdownTimeout time.Duration `yaml:"shutdown_timeout" env:"MYPREMIER_SHUTDOWN_TIMEOUT"`
+	// TrustedProxies lists the proxies, as CIDRs or addresses, whose
+	// X-Forwarded-For header is believed when identifying clients
+	TrustedProxies []string `yaml:"trusted_proxies" env:"MYPREMIER_TRUSTED_PROXIES"`
+	// DrainDelay keeps serving with /readyz failing for this long after
+	// SIGTERM, so load balancers stop routing before the listener closes
+	DrainDelay time.Duration `yaml:"drain_delay" env:"MYPREMIER_DRAIN_DELAY"`
+}
+
+type HealthConfig struct {
+	// CheckTimeout bounds each readiness check
+	CheckTimeout time.Duration `yaml:"check_timeout" env:"MYPREMIER_HEALTH_CHECK_TIMEOUT"`
+}
+
+type LoggingConfig struct {
+	// Level is debug, info, warn or error
+	Level string `yaml:"level" env:"MYPREMIER_LOG_LEVEL"`
+	// Format is json or text
+	Format string `yaml:"format" env:"MYPREMIER_LOG_FORMAT"`
+}
+
+type MetricsConfig struct {
+	Enabled bool `yaml:"enabled" env:"MYPREMIER_METRICS_ENABLED"`
+	// Addr serves /metrics on a separate listener, by default on loopback
+	// only. When empty, /metrics is served by the API listener to anyone who
+	// can reach the API.
+	Addr string `yaml:"addr" env:"MYPREMIER_METRICS_ADDR"`
+}
+
+type TracingConfig struct {
+	// Exporter is none, otlp or stdout
+	Exporter string `yaml:"exporter" env:"MYPREMIER_TRACING_EXPORTER"`
+	// Endpoint is the OTLP gRPC collector address, e.g. "otel-collector:4317".
+	// When empty the OTEL_EXPORTER_OTLP_* variables apply.
+	Endpoint string `yaml:"endpoint" env:"MYPREMIER_TRACING_ENDPOINT"`
+	// Insecure disables TLS to the collector
+	Insecure bool `yaml:"insecure" env:"MYPREMIER_TRACING_INSECURE"`
+	// SampleRatio is the fraction of new traces to record, from 0 to 1
+	SampleRatio float64 `yaml:"sample_ratio" env:"MYPREMIER_TRACING_SAMPLE_RATIO"`
+	ServiceName string  `yaml:"service_name" env:"MYPREMIER_TRACING_SERVICE_NAME"`
+}
+
+type StorageConfig struct {
+	Backend string `yaml:"backend" env:"MYPREMIER_STORAGE"`
+}
+
+type FirebaseConfig struct {
+	// CredentialsFile is the path to a service account key
+	CredentialsFile string `yaml:"credentials_file" env:"MYPREMIER_FIREBASE_CREDENTIALS_FILE"`
+	// CredentialsJSON is an inline service account key, used instead of CredentialsFile
+	CredentialsJSON string `yaml:"credentials_json" env:"MYPREMIER_FIREBASE_CREDENTIALS_JSON" secret:"true"`
+	// UseADC uses Application Default Credentials instead of a service account key
+	UseADC       bool   `yaml:"use_adc" env:"MYPREMIER_FIREBASE_USE_ADC"`
+	ProjectID    string `yaml:"project_id" env:"MYPREMIER_FIREBASE_PROJECT_ID"`
+	EmulatorHost string `yaml:"emulator_host" env:"MYPREMIER_FIRESTORE_EMULATOR_HOST"`
+}
+
+// AuthConfig selects how bearer tokens are verified
+type AuthConfig struct {
+	// Provider verifies bearer tokens: firebase for Firebase ID tokens, or
+	// local for tokens minted by cmd/devtoken. Never use local in production.
+	Provider string          `yaml:"provider" env:"MYPREMIER_AUTH_PROVIDER"`
+	Local    LocalAuthConfig `yaml:"local"`
+}
+
+// LocalAuthConfig is the signing key of locally issued tokens
+type LocalAuthConfig struct {
+	// Algorithm is HS256 or RS256
+	Algorithm string `yaml:"algorithm" env:"MYPREMIER_AUTH_LOCAL_ALGORITHM"`
+	// Secret is the HS256 key, at least 32 bytes
+	Secret string `yaml:"secret" env:"MYPREMIER_AUTH_LOCAL_SECRET" secret:"true"`
+	// PrivateKeyFile is the path to the PEM RSA private key for RS256
+	PrivateKeyFile string `yaml:"private_key_file" env:"MYPREMIER_AUTH_LOCAL_PRIVATE_KEY_FILE"`
+	// Issuer is the iss claim of minted tokens, required when verifying
+	Issuer string `yaml:"issuer" env:"MYPREMIER_AUTH_LOCAL_ISSUER"`
+	// TokenTTL is the lifetime of minted tokens
+	TokenTTL time.Duration `yaml:"token_ttl" env:"MYPREMIER_AUTH_LOCAL_TOKEN_TTL"`
+}
+
+// CORSConfig is the cross-origin policy of routes outside every group, plus
+// the policies of route groups
+type CORSConfig struct {
+	// AllowedOrigins lists the origins allowed to call the API, or "*" for any
+	AllowedOrigins   []string      `yaml:"allowed_origins" env:"MYPREMIER_CORS_ALLOWED_ORIGINS"`
+	AllowCredentials bool          `yaml:"allow_credentials" env:"MYPREMIER_CORS_ALLOW_CREDENTIALS"`
+	MaxAge           time.Duration `yaml:"max_age" env:"MYPREMIER_CORS_MAX_AGE"`
+	// Groups override the policy above for the paths under their prefixes.
+	// The longest matching prefix wins.
+	Groups map[string]CORSGroup `yaml:"groups"`
+}
+
+// CORSGroup is the cross-origin policy of a group of routes
+type CORSGroup struct {
+	PathPrefixes     []string      `yaml:"path_prefixes"`
+	AllowedOrigins   []string      `yaml:"allowed_origins"`
+	AllowCredentials bool          `yaml:"allow_credentials"`
+	MaxAge           time.Duration `yaml:"max_age"`
+}
+
+// SecurityHeadersConfig controls the headers sent with every response
+type SecurityHeadersConfig struct {
+	// HSTSMaxAge is the Strict-Transport-Security max-age; 0 disables HSTS
+	HSTSMaxAge time.Duration `yaml:"hsts_max_age" env:"MYPREMIER_HSTS_MAX_AGE"`
+	// HSTSIncludeSubdomains extends HSTS to every subdomain
+	HSTSIncludeSubdomains bool   `yaml:"hsts_include_subdomains" env:"MYPREMIER_HSTS_INCLUDE_SUBDOMAINS"`
+	ReferrerPolicy        string `yaml:"referrer_policy" env:"MYPREMIER_REFERRER_POLICY"`
+}
+
+type LimitsConfig struct {
+	// MaxBodyBytes caps the size of request bodies
+	MaxBodyBytes int64 `yaml:"max_body_bytes" env:"MYPREMIER_MAX_BODY_BYTES"`
+}
+
+type CatalogConfig struct {
+	// CacheMaxAge is how long clients and CDNs may reuse public catalog
+	// responses before revalidating them with their ETag
+	CacheMaxAge time.Duration `yaml:"cache_max_age" env:"MYPREMIER_CATALOG_CACHE_MAX_AGE"`
+	// ReadCache keeps product and category reads in front of storage
+	ReadCache ReadCacheConfig `yaml:"read_cache"`
+}
+
+type ReadCacheConfig struct {
+	Enabled bool `yaml:"enabled" env:"MYPREMIER_CATALOG_READ_CACHE_ENABLED"`
+	// Store is memory (per replica) or redis (shared). With memory, admin
+	// writes reach the other replicas only once their entries expire. With
+	// redis, a write also keeps reads running on other replicas from
+	// caching what they read before it.
+	Store string `yaml:"store" env:"MYPREMIER_CATALOG_READ_CACHE_STORE"`
+	// TTL bounds how long an entry is served without reading storage
+	TTL time.Duration `yaml:"ttl" env:"MYPREMIER_CATALOG_READ_CACHE_TTL"`
+}
+
+type RateLimitConfig struct {
+	Enabled bool `yaml:"enabled" env:"MYPREMIER_RATE_LIMIT_ENABLED"`
+	// Store is memory (per replica) or redis (shared)
+	Store string `yaml:"store" env:"MYPREMIER_RATE_LIMIT_STORE"`
+	// Default applies to every rate-limited route without an override
+	Default RateLimit `yaml:"default"`
+	// Routes overrides Default per route pattern, e.g. "POST /support"
+	Routes map[string]RateLimit `yaml:"routes"`
+}
+
+// For returns the limit of the route with the given pattern
+func (c RateLimitConfig) For(pattern string) RateLimit {
+	if l, ok := c.Routes[pattern]; ok {
+		return l
+	}
+	return c.Default
+}
+
+// RateLimit allows Burst requests at once, refilled at Requests per Per.
+// The env tags only apply to rate_limit.default.
+type RateLimit struct {
+	Requests int           `yaml:"requests" env:"MYPREMIER_RATE_LIMIT_REQUESTS"`
+	Per      time.Duration `yaml:"per" env:"MYPREMIER_RATE_LIMIT_PER"`
+	// Burst defaults to Requests
+	Burst int `yaml:"burst" env:"MYPREMIER_RATE_LIMIT_BURST"`
+}
+
+type IdempotencyConfig struct {
+	// Enabled honours the Idempotency-Key header on POST routes
+	Enabled bool `yaml:"enabled" env:"MYPREMIER_IDEMPOTENCY_ENABLED"`
+	// Store is memory (per replica) or redis (shared)
+	Store string `yaml:"store" env:"MYPREMIER_IDEMPOTENCY_STORE"`
+	// TTL is how long a key and its response are kept
+	TTL time.Duration `yaml:"ttl" env:"MYPREMIER_IDEMPOTENCY_TTL"`
+	// Lease is how long a running request holds its key. It should outlast
+	// server.write_timeout; a key left by a crashed replica frees up after it.
+	Lease time.Duration `yaml:"lease" env:"MYPREMIER_IDEMPOTENCY_LEASE"`
+}
+
+type RedisConfig struct {
+	// Addr is host:port of a Redis-compatible server
+	Addr     string `yaml:"addr" env:"MYPREMIER_REDIS_ADDR"`
+	Username string `yaml:"username" env:"MYPREMIER_REDIS_USERNAME"`
+	Password string `yaml:"password" env:"MYPREMIER_REDIS_PASSWORD" secret:"true"`
+	DB       int    `yaml:"db" env:"MYPREMIER_REDIS_DB"`
+	TLS      bool   `yaml:"tls" env:"MYPREMIER_REDIS_TLS"`
+}
+
+type AntispamConfig struct {
+	// Enabled screens public submissions and quarantines likely spam
+	Enabled bool `yaml:"enabled" env:"MYPREMIER_ANTISPAM_ENABLED"`
+	// Secret signs form tokens and challenges. It must be shared by all
+	// replicas; when empty a random one is used per process.
+	Secret string `yaml:"secret" env:"MYPREMIER_ANTISPAM_SECRET" secret:"true"`
+	// Store remembers solved challenges so each is accepted once: memory
+	// (per replica, so a challenge can be replayed once on each) or redis
+	// (shared)
+	Store string `yaml:"store" env:"MYPREMIER_ANTISPAM_STORE"`
+	// HoneypotField is a data field hidden from people; bots fill it
+	HoneypotField string `yaml:"honeypot_field" env:"MYPREMIER_ANTISPAM_HONEYPOT_FIELD"`
+	// MinSubmitTime is the least time between fetching a form token and
+	// submitting the form
+	MinSubmitTime time.Duration `yaml:"min_submit_time" env:"MYPREMIER_ANTISPAM_MIN_SUBMIT_TIME"`
+	FormTokenTTL  time.Duration `yaml:"form_token_ttl" env:"MYPREMIER_ANTISPAM_FORM_TOKEN_TTL"`
+	// PowDifficulty is the number of leading zero bits a proof-of-work
+	// must have
+	PowDifficulty int           `yaml:"pow_difficulty" env:"MYPREMIER_ANTISPAM_POW_DIFFICULTY"`
+	PowTTL        time.Duration `yaml:"pow_ttl" env:"MYPREMIER_ANTISPAM_POW_TTL"`
+	// MaxLinks is the number of links a submission may contain unpenalised
+	MaxLinks       int      `yaml:"max_links" env:"MYPREMIER_ANTISPAM_MAX_LINKS"`
+	BlockedDomains []string `yaml:"blocked_domains" env:"MYPREMIER_ANTISPAM_BLOCKED_DOMAINS"`
+	// Threshold is the spam score at which a submission is quarantined
+	Threshold float64 `yaml:"threshold" env:"MYPREMIER_ANTISPAM_THRESHOLD"`
+}
+
+// AccessConfig controls how the role, status and sessions of users are
+// checked. Role and status are mirrored into Firebase custom claims, which
+// authorise most requests without reading the users collection.
+type AccessConfig struct {
+	// ChangeStore records role and status changes, after which older tokens
+	// are checked against storage. With storage, nothing is recorded and
+	// every request reads the users collection. With redis, shared by every
+	// replica, fresh tokens are authorised from their claims alone. memory
+	// is per replica: a change made on one replica would go unnoticed by
+	// the others for up to an hour, so it is only allowed with the memory
+	// storage backend. Empty picks memory for local tokens on the memory
+	// backend, whose users collection starts empty and would refuse every
+	// token, and storage otherwise; see Config.AccessChangeStore.
+	ChangeStore string `yaml:"change_store" env:"MYPREMIER_ACCESS_CHANGE_STORE"`
+	// ReconcileInterval is how often claims are compared with the users
+	// collection and corrected; 0 disables reconciliation
+	ReconcileInterval time.Duration `yaml:"reconcile_interval" env:"MYPREMIER_ACCESS_RECONCILE_INTERVAL"`
+	// CheckRevoked refuses tokens on admin routes that were issued before
+	// their user's sessions were revoked
+	CheckRevoked bool `yaml:"check_revoked" env:"MYPREMIER_ACCESS_CHECK_REVOKED"`
+	// RevocationCacheTTL is how long the revocation time of a user is kept
+	// in the change store, or per replica with storage, before Firebase
+	// Auth is asked again
+	RevocationCacheTTL time.Duration `yaml:"revocation_cache_ttl" env:"MYPREMIER_ACCESS_REVOCATION_CACHE_TTL"`
+	// AdminMaxTokenAge refuses admin requests from users who signed in
+	// longer ago, whatever the age of the token; 0 disables the limit
+	AdminMaxTokenAge time.Duration `yaml:"admin_max_token_age" env:"MYPREMIER_ACCESS_ADMIN_MAX_TOKEN_AGE"`
+}
+
+// AccessChangeStore returns access.change_store, or the store picked for
+// it when it is empty
+func (c *Config) AccessChangeStore() string {
+	switch {
+	case c.Access.ChangeStore != "":
+		return c.Access.ChangeStore
+	case c.Auth.Provider == AuthLocal && c.Storage.Backend == StorageMemory:
+		// Tokens from cmd/devtoken are trusted from their claims
+		return "memory"
+	default:
+		return "storage"
+	}
+}
+
+// rolePattern matches role names
+var rolePattern = regexp.MustCompile(`^[a-z][a-z0-9_-]*$`)
+
+// RoleConfig defines a role users can be assigned. Roles are listed in
+// full: a roles key in the config file replaces the built-in list.
+type RoleConfig struct {
+	// Name is stored on users, e.g. sales
+	Name        string `yaml:"name"`
+	DisplayName string `yaml:"display_name"`
+	Description string `yaml:"description"`
+	// Permissions the role grants, e.g. product:write. Unknown permissions
+	// are rejected at startup.
+	Permissions []string `yaml:"permissions"`
+}
+
+// CollectionsConfig holds the Firestore collection name used by each module
+type CollectionsConfig struct {
+	Products        string `yaml:"products" env:"MYPREMIER_COLLECTION_PRODUCTS"`
+	Categories      string `yaml:"categories" env:"MYPREMIER_COLLECTION_CATEGORIES"`
+	Requests        string `yaml:"requests" env:"MYPREMIER_COLLECTION_REQUESTS"`
+	Supports        string `yaml:"supports" env:"MYPREMIER_COLLECTION_SUPPORTS"`
+	SupportMessages string `yaml:"support_messages" env:"MYPREMIER_COLLECTION_SUPPORT_MESSAGES"`
+	Users           string `yaml:"users" env:"MYPREMIER_COLLECTION_USERS"`
+	AuditLogs       string `yaml:"audit_logs" env:"MYPREMIER_COLLECTION_AUDIT_LOGS"`
+	Quarantine      string `yaml:"quarantine" env:"MYPREMIER_COLLECTION_QUARANTINE"`
+}
+
+// Default returns the configuration used when nothing is overridden
+func Default() *Config {
+	return &Config{
+		Server: ServerConfig{
+			Addr:              ":8080",
+			ReadTimeout:       15 * time.Second,
+			ReadHeaderTimeout: 5 * time.Second,
+			WriteTimeout:      30 * time.Second,
+			IdleTimeout:       120 * time.Second,
+			ShutdownTimeout:   20 * time.Second,
+		},
+		Health: HealthConfig{
+			CheckTimeout: 2 * time.Second,
+		},
+		Logging: LoggingConfig{
+			Level:  "info",
+			Format: "json",
+		},
+		Metrics: MetricsConfig{
+			Enabled: true,
+			Addr:    "127.0.0.1:9090",
+		},
+		Tracing: TracingConfig{
+			Exporter:    "none",
+			SampleRatio: 1,
+			ServiceName: "mypremier-backend",
+		},
+		Storage: StorageConfig{
+			Backend: StorageFirestore,
+		},
+		Firebase: FirebaseConfig{
+			CredentialsFile: "serviceAccountKey.json",
+		},
+		Auth: AuthConfig{
+			Provider: AuthFirebase,
+			Local: LocalAuthConfig{
+				Algorithm: "HS256",
+				Issuer:    "mypremier-dev",
+				TokenTTL:  time.Hour,
+			},
+		},
+		CORS: CORSConfig{
+			AllowedOrigins: []string{"*"},
+			MaxAge:         10 * time.Minute,
+			Groups: map[string]CORSGroup{
+				// The admin web sends credentials; list its origins here
+				"admin": {
+					PathPrefixes:     []string{"/admin/"},
+					AllowedOrigins:   []string{"http://localhost:3000"},
+					AllowCredentials: true,
+					MaxAge:           10 * time.Minute,
+				},
+			},
+		},
+		SecurityHeaders: SecurityHeadersConfig{
+			HSTSMaxAge:     365 * 24 * time.Hour,
+			ReferrerPolicy: "no-referrer",
+		},
+		Limits: LimitsConfig{
+			MaxBodyBytes: 1 << 20,
+		},
+		Catalog: CatalogConfig{
+			CacheMaxAge: time.Minute,
+			ReadCache: ReadCacheConfig{
+				Enabled: true,
+				Store:   "memory",
+				TTL:     time.Minute,
+			},
+		},
+		RateLimit: RateLimitConfig{
+			Enabled: true,
+			Store:   "memory",
+			Default: RateLimit{Requests: 5, Per: time.Minute, Burst: 5},
+		},
+		Idempotency: IdempotencyConfig{
+			Enabled: true,
+			Store:   "memory",
+			TTL:     24 * time.Hour,
+			Lease:   time.Minute,
+		},
+		Antispam: AntispamConfig{
+			Enabled:       true,
+			Store:         "memory",
+			HoneypotField: "website",
+			MinSubmitTime: 3 * time.Second,
+			FormTokenTTL:  2 * time.Hour,
+			PowDifficulty: 16,
+			PowTTL:        10 * time.Minute,
+			MaxLinks:      2,
+			Threshold:     5,
+		},
+		Access: AccessConfig{
+			ReconcileInterval:  time.Hour,
+			CheckRevoked:       true,
+			RevocationCacheTTL: 30 * time.Second,
+		},
+		Roles: []RoleConfig{
+			{
+				Name:        "admin",
+				DisplayName: "Administrator",
+				Description: "Full access, including users and the audit trail",
+				Permissions: []string{
+					"product:read", "product:write", "category:read", "category:write",
+					"request:read", "support:read", "support:write", "quarantine:read", "quarantine:write",
+					"user:read", "user:manage", "stats:read", "audit:read",
+				},
+			},
+			{
+				Name:        "sales",
+				DisplayName: "Sales",
+				Description: "Runs the catalog and follows up requests and support tickets",
+				Permissions: []string{
+					"product:read", "product:write", "category:read", "category:write",
+					"request:read", "support:read", "support:write", "quarantine:read", "quarantine:write",
+					"stats:read",
+				},
+			},
+			{
+				Name:        "engineer",
+				DisplayName: "Engineer",
+				Description: "Answers technical support tickets",
+				Permissions: []string{
+					"product:read", "category:read", "support:read", "support:write",
+				},
+			},
+			{
+				Name:        "client",
+				DisplayName: "Client",
+				Description: "Customer using the public catalog and their support chat",
+			},
+		},
+		Collections: CollectionsConfig{
+			Products:        "products",
+			Categories:      "categories",
+			Requests:        "requests",
+			Supports:        "supports",
+			SupportMessages: "support_messages",
+			Users:           "users",
+			AuditLogs:       "audit_logs",
+			Quarantine:      "quarantine",
+		},
+	}
+}
+
+// Load builds the configuration from defaults, the optional file at path
+// (YAML or JSON) and environment variables. Callers run Validate before
+// using the result.
+func Load(path string) (*Config, error) {
+	cfg := Default()
+
+	if path != "" {
+		if err := cfg.loadFile(path); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := applyEnv(reflect.ValueOf(cfg).Elem(), os.LookupEnv); err != nil {
+		return nil, err
+	}
+
+	return cfg, nil
+}
+
+// loadFile reads a YAML or JSON config file. JSON is valid YAML, so both go
+// through the same decoder. Unknown keys are rejected to catch typos.
+func (c *Config) loadFile(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to read config file: %w", err)
+	}
+
+	dec := yaml.NewDecoder(bytes.NewReader(data))
+	dec.KnownFields(true)
+	if err := dec.Decode(c); err != nil && !errors.Is(err, io.EOF) {
+		return fmt.Errorf("failed to parse config file %s: %w", path, err)
+	}
+
+	return nil
+}
+
+// Validate checks the configuration and reports every problem at once
+func (c *Config) Validate() error {
+	var errs []error
+	add := func(key, format string, args ...interface{}) {
+		errs = append(errs, fmt.Errorf("%s: %s", key, fmt.Sprintf(format, args...)))
+	}
+
+	if _, _, err := net.SplitHostPort(c.Server.Addr); err != nil {
+		add("server.addr", "must be host:port, got %q", c.Server.Addr)
+	}
+	for key, d := range map[string]time.Duration{
+		"server.read_timeout":        c.Server.ReadTimeout,
+		"server.read_header_timeout": c.Server.ReadHeaderTimeout,
+		"server.write_timeout":       c.Server.WriteTimeout,
+		"server.idle_timeout":        c.Server.IdleTimeout,
+		"server.shutdown_timeout":    c.Server.ShutdownTimeout,
+		"health.check_timeout":       c.Health.CheckTimeout,
+	} {
+		if d <= 0 {
+			add(key, "must be a positive duration like 10s, got %s", d)
+		}
+	}
+
+	if c.Server.DrainDelay < 0 {
+		add("server.drain_delay", "must not be negative, got %s", c.Server.DrainDelay)
+	}
+
+	switch strings.ToLower(c.Logging.Level) {
+	case "debug", "info", "warn", "error":
+	default:
+		add("logging.level", "must be debug, info, warn or error, got %q", c.Logging.Level)
+	}
+	if f := strings.ToLower(c.Logging.Format); f != "json" && f != "text" {
+		add("logging.format", "must be json or text, got %q", c.Logging.Format)
+	}
+
+	if c.Metrics.Enabled && c.Metrics.Addr != "" {
+		if _, _, err := net.SplitHostPort(c.Metrics.Addr); err != nil {
+			add("metrics.addr", "must be host:port, got %q", c.Metrics.Addr)
+		} else if c.Metrics.Addr == c.Server.Addr {
+			add("metrics.addr", "must differ from server.addr (leave it empty to serve /metrics on the API listener)")
+		}
+	}
+
+	switch c.Tracing.Exporter {
+	case "none", "otlp", "stdout":
+	default:
+		add("tracing.exporter", "must be none, otlp or stdout, got %q", c.Tracing.Exporter)
+	}
+	if c.Tracing.SampleRatio < 0 || c.Tracing.SampleRatio > 1 {
+		add("tracing.sample_ratio", "must be between 0 and 1, got %g", c.Tracing.SampleRatio)
+	}
+	if c.Tracing.ServiceName == "" {
+		add("tracing.service_name", "must not be empty")
+	}
+
+	switch c.Storage.Backend {
+	case StorageFirestore:
+		fb := c.Firebase
+		switch {
+		case fb.EmulatorHost != "":
+			if fb.ProjectID == "" {
+				add("firebase.project_id", "is required when using the Firestore emulator")
+			}
+		case fb.CredentialsJSON != "", fb.UseADC:
+		case fb.CredentialsFile != "":
+			if _, err := os.Stat(fb.CredentialsFile); err != nil {
+				add("firebase.credentials_file", "cannot read %q: %v (set use_adc or credentials_json to use other credentials)", fb.CredentialsFile, err)
+			}
+		default:
+			add("firebase", "one of credentials_file, credentials_json, use_adc or emulator_host is required")
+		}
+	case StorageMemory:
+	default:
+		add("storage.backend", "must be %q or %q, got %q", StorageFirestore, StorageMemory, c.Storage.Backend)
+	}
+
+	switch c.Auth.Provider {
+	case AuthFirebase:
+	case AuthLocal:
+		l := c.Auth.Local
+		switch l.Algorithm {
+		case auth.HS256:
+			if len(l.Secret) < auth.MinSecretLength {
+				add("auth.local.secret", "must be at least %d bytes for HS256, got %d", auth.MinSecretLength, len(l.Secret))
+			}
+		case auth.RS256:
+			if l.PrivateKeyFile == "" {
+				add("auth.local.private_key_file", "is required for RS256")
+			} else if _, err := os.Stat(l.PrivateKeyFile); err != nil {
+				add("auth.local.private_key_file", "cannot read %q: %v", l.PrivateKeyFile, err)
+			}
+		default:
+			add("auth.local.algorithm", "must be %s or %s, got %q", auth.HS256, auth.RS256, l.Algorithm)
+		}
+		if l.Issuer == "" {
+			add("auth.local.issuer", "must not be empty")
+		}
+		if l.TokenTTL <= 0 {
+			add("auth.local.token_ttl", "must be a positive duration like 1h, got %s", l.TokenTTL)
+		}
+	default:
+		add("auth.provider", "must be %q or %q, got %q", AuthFirebase, AuthLocal, c.Auth.Provider)
+	}
+
+	validateOrigins := func(key string, origins []string, credentials bool, maxAge time.Duration) {
+		if len(origins) == 0 {
+			add(key+".allowed_origins", "must not be empty (use \"*\" to allow any origin)")
+		}
+		for _, origin := range origins {
+			if origin == "*" {
+				if credentials {
+					add(key+".allowed_origins", "must list origins when allow_credentials is set; browsers refuse \"*\" with credentials")
+				}
+				continue
+			}
+			u, err := url.Parse(origin)
+			if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" || (u.Path != "" && u.Path != "/") {
+				add(key+".allowed_origins", "%q is not an origin like https://admin.example.com", origin)
+			}
+		}
+		if maxAge < 0 {
+			add(key+".max_age", "must not be negative, got %s", maxAge)
+		}
+	}
+	validateOrigins("cors", c.CORS.AllowedOrigins, c.CORS.AllowCredentials, c.CORS.MaxAge)
+	for name, g := range c.CORS.Groups {
+		key := "cors.groups." + name
+		if len(g.PathPrefixes) == 0 {
+			add(key+".path_prefixes", "must not be empty")
+		}
+		for _, prefix := range g.PathPrefixes {
+			if !strings.HasPrefix(prefix, "/") {
+				add(key+".path_prefixes", "%q must start with /", prefix)
+			}
+		}
+		validateOrigins(key, g.AllowedOrigins, g.AllowCredentials, g.MaxAge)
+	}
+
+	if c.SecurityHeaders.HSTSMaxAge < 0 {
+		add("security_headers.hsts_max_age", "must not be negative, got %s", c.SecurityHeaders.HSTSMaxAge)
+	}
+
+	if c.Limits.MaxBodyBytes <= 0 {
+		add("limits.max_body_bytes", "must be positive, got %d", c.Limits.MaxBodyBytes)
+	}
+
+	for _, p := range c.Server.TrustedProxies {
+		if _, err := clientip.ParsePrefix(p); err != nil {
+			add("server.trusted_proxies", "%v", err)
+		}
+	}
+
+	if c.Catalog.CacheMaxAge < 0 {
+		add("catalog.cache_max_age", "must not be negative, got %s", c.Catalog.CacheMaxAge)
+	}
+	if c.Catalog.ReadCache.Enabled {
+		switch c.Catalog.ReadCache.Store {
+		case "memory":
+		case "redis":
+			if c.Redis.Addr == "" {
+				add("redis.addr", "is required when catalog.read_cache.store is redis")
+			}
+		default:
+			add("catalog.read_cache.store", "must be memory or redis, got %q", c.Catalog.ReadCache.Store)
+		}
+		if c.Catalog.ReadCache.TTL <= 0 {
+			add("catalog.read_cache.ttl", "must be a positive duration like 1m, got %s", c.Catalog.ReadCache.TTL)
+		}
+	}
+
+	if c.RateLimit.Enabled {
+		switch c.RateLimit.Store {
+		case "memory":
+		case "redis":
+			if c.Redis.Addr == "" {
+				add("redis.addr", "is required when rate_limit.store is redis")
+			}
+		default:
+			add("rate_limit.store", "must be memory or redis, got %q", c.RateLimit.Store)
+		}
+		validateRateLimit := func(key string, l RateLimit) {
+			if l.Requests <= 0 {
+				add(key+".requests", "must be positive, got %d", l.Requests)
+			}
+			if l.Per <= 0 {
+				add(key+".per", "must be a positive duration like 1m, got %s", l.Per)
+			}
+			if l.Burst < 0 {
+				add(key+".burst", "must not be negative, got %d", l.Burst)
+			}
+		}
+		validateRateLimit("rate_limit.default", c.RateLimit.Default)
+		for pattern, l := range c.RateLimit.Routes {
+			validateRateLimit(fmt.Sprintf("rate_limit.routes[%q]", pattern), l)
+		}
+	}
+	if c.Idempotency.Enabled {
+		switch c.Idempotency.Store {
+		case "memory":
+		case "redis":
+			if c.Redis.Addr == "" {
+				add("redis.addr", "is required when idempotency.store is redis")
+			}
+		default:
+			add("idempotency.store", "must be memory or redis, got %q", c.Idempotency.Store)
+		}
+		if c.Idempotency.TTL <= 0 {
+			add("idempotency.ttl", "must be a positive duration like 24h, got %s", c.Idempotency.TTL)
+		}
+		if c.Idempotency.Lease <= 0 || c.Idempotency.Lease > c.Idempotency.TTL {
+			add("idempotency.lease", "must be a positive duration no longer than idempotency.ttl, got %s", c.Idempotency.Lease)
+		}
+	}
+	switch c.AccessChangeStore() {
+	case "storage":
+	case "memory":
+		if c.Storage.Backend != StorageMemory {
+			add("access.change_store", "memory is per replica and only allowed with storage.backend %s; use storage or redis", StorageMemory)
+		}
+	case "redis":
+		if c.Redis.Addr == "" {
+			add("redis.addr", "is required when access.change_store is redis")
+		}
+	default:
+		add("access.change_store", "must be storage, memory or redis, got %q", c.Access.ChangeStore)
+	}
+	if c.Access.ReconcileInterval < 0 {
+		add("access.reconcile_interval", "must not be negative, got %s", c.Access.ReconcileInterval)
+	}
+	if c.Access.CheckRevoked && c.Access.RevocationCacheTTL <= 0 {
+		add("access.revocation_cache_ttl", "must be a positive duration like 30s, got %s", c.Access.RevocationCacheTTL)
+	}
+	if c.Access.AdminMaxTokenAge < 0 {
+		add("access.admin_max_token_age", "must not be negative, got %s", c.Access.AdminMaxTokenAge)
+	}
+	if c.Redis.Addr != "" {
+		if _, _, err := net.SplitHostPort(c.Redis.Addr); err != nil {
+			add("redis.addr", "must be host:port, got %q", c.Redis.Addr)
+		}
+	}
+
+	if c.Antispam.Enabled {
+		a := c.Antispam
+		switch a.Store {
+		case "memory":
+		case "redis":
+			if c.Redis.Addr == "" {
+				add("redis.addr", "is required when antispam.store is redis")
+			}
+		default:
+			add("antispam.store", "must be memory or redis, got %q", a.Store)
+		}
+		if a.HoneypotField == "" {
+			add("antispam.honeypot_field", "must not be empty")
+		}
+		if a.MinSubmitTime < 0 {
+			add("antispam.min_submit_time", "must not be negative, got %s", a.MinSubmitTime)
+		}
+		if a.FormTokenTTL <= a.MinSubmitTime {
+			add("antispam.form_token_ttl", "must be longer than min_submit_time, got %s", a.FormTokenTTL)
+		}
+		if a.PowDifficulty < 0 || a.PowDifficulty > 32 {
+			add("antispam.pow_difficulty", "must be between 0 and 32, got %d", a.PowDifficulty)
+		}
+		if a.PowTTL <= 0 {
+			add("antispam.pow_ttl", "must be a positive duration like 10m, got %s", a.PowTTL)
+		}
+		if a.MaxLinks < 0 {
+			add("antispam.max_links", "must not be negative, got %d", a.MaxLinks)
+		}
+		if a.Threshold <= 0 {
+			add("antispam.threshold", "must be positive, got %g", a.Threshold)
+		}
+	}
+
+	if len(c.Roles) == 0 {
+		add("roles", "must define at least one role")
+	}
+	roleNames := make(map[string]bool)
+	for i, role := range c.Roles {
+		key := fmt.Sprintf("roles[%d]", i)
+		switch {
+		case !rolePattern.MatchString(role.Name):
+			add(key+".name", "must be lowercase letters, digits, - or _, got %q", role.Name)
+		case roleNames[role.Name]:
+			add(key+".name", "%q is defined more than once", role.Name)
+		}
+		roleNames[role.Name] = true
+		if role.DisplayName == "" {
+			add(key+".display_name", "must not be empty")
+		}
+		for _, perm := range role.Permissions {
+			if entity, action, ok := strings.Cut(perm, ":"); !ok || entity == "" || action == "" {
+				add(key+".permissions", "%q must look like entity:action", perm)
+			}
+		}
+	}
+
+	seen := make(map[string]string)
+	cols := reflect.ValueOf(c.Collections)
+	for i := 0; i < cols.NumField(); i++ {
+		key := "collections." + yamlName(cols.Type().Field(i))
+		name := cols.Field(i).String()
+		if name == "" {
+			add(key, "must not be empty")
+			continue
+		}
+		if strings.Contains(name, "/") {
+			add(key, "%q must not contain '/'", name)
+		}
+		if other, ok := seen[name]; ok {
+			add(key, "%q is already used by %s", name, other)
+		}
+		seen[name] = key
+	}
+
+	if len(errs) > 0 {
+		return fmt.Errorf("invalid configuration:\n%w", errors.Join(errs...))
+	}
+
+	return nil
+}
+
+// Masked returns the configuration as YAML with secret values replaced
+func (c *Config) Masked() (string, error) {
+	masked := *c
+	maskSecrets(reflect.ValueOf(&masked).Elem())
+
+	out, err := yaml.Marshal(&masked)
+	if err != nil {
+		return "", fmt.Errorf("failed to render config: %w", err)
+	}
+
+	return string(out), nil
+}
+
+func maskSecrets(v reflect.Value) {
+	for i := 0; i < v.NumField(); i++ {
+		field := v.Field(i)
+		switch {
+		case field.Kind() == reflect.Struct:
+			maskSecrets(field)
+		case v.Type().Field(i).Tag.Get("secret") == "true" && field.Kind() == reflect.String && field.String() != "":
+			field.SetString("********")
+		}
+	}
+}
+
+// applyEnv overrides fields from the environment variables named in their env tags
+func applyEnv(v reflect.Value, lookup func(string) (string, bool)) error {
+	durationType := reflect.TypeOf(time.Duration(0))
+
+	for i := 0; i < v.NumField(); i++ {
+		field := v.Field(i)
+		sf := v.Type().Field(i)
+
+		if field.Kind() == reflect.Struct {
+			if err := applyEnv(field, lookup); err != nil {
+				return err
+			}
+			continue
+		}
+
+		name := sf.Tag.Get("env")
+		if name == "" {
+			continue
+		}
+		raw, ok := lookup(name)
+		if !ok {
+			continue
+		}
+
+		var err error
+		switch {
+		case field.Type() == durationType:
+			var d time.Duration
+			d, err = time.ParseDuration(raw)
+			field.SetInt(int64(d))
+		case field.Kind() == reflect.String:
+			field.SetString(raw)
+		case field.Kind() == reflect.Bool:
+			var b bool
+			b, err = strconv.ParseBool(raw)
+			field.SetBool(b)
+		case field.Kind() == reflect.Int, field.Kind() == reflect.Int64:
+			var n int64
+			n, err = strconv.ParseInt(raw, 10, 64)
+			field.SetInt(n)
+		case field.Kind() == reflect.Float64:
+			var f float64
+			f, err = strconv.ParseFloat(raw, 64)
+			field.SetFloat(f)
+		case field.Kind() == reflect.Slice && field.Type().Elem().Kind() == reflect.String:
+			var items []string
+			for _, item := range strings.Split(raw, ",") {
+				if item = strings.TrimSpace(item); item != "" {
+					items = append(items, item)
+				}
+			}
+			field.Set(reflect.ValueOf(items))
+		default:
+			err = fmt.Errorf("unsupported type %s", field.Type())
+		}
+		if err != nil {
+			return fmt.Errorf("invalid value for %s: %w", name, err)
+		}
+	}
+
+	return nil
+}
+
+func yamlName(f reflect.StructField) string {
+	name, _, _ := strings.Cut(f.Tag.Get("yaml"), ",")
+	if name == "" {
+		return strings.ToLower(f.Name)
+	}
+	return name
+}
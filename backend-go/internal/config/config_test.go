@@ -0,0 +1,65 @@
+package config
+
+import (
+	"net"
+	"strings"
+	"testing"
+)
+
+func TestMetricsDefaultToLoopback(t *testing.T) {
+	m := Default().Metrics
+	host, _, err := net.SplitHostPort(m.Addr)
+	if !m.Enabled || err != nil || !net.ParseIP(host).IsLoopback() {
+		t.Errorf("default metrics %+v: want a separate listener on loopback", m)
+	}
+}
+
+func TestValidateMetrics(t *testing.T) {
+	tests := []struct {
+		name    string
+		change  func(c *Config)
+		wantErr bool
+	}{
+		{"default", func(c *Config) {}, false},
+		{"all interfaces", func(c *Config) { c.Metrics.Addr = ":9090" }, false},
+		{"API listener", func(c *Config) { c.Metrics.Addr = "" }, false},
+		{"no port", func(c *Config) { c.Metrics.Addr = "127.0.0.1" }, true},
+		{"same as the API", func(c *Config) { c.Metrics.Addr = c.Server.Addr }, true},
+		{"disabled", func(c *Config) { c.Metrics.Enabled, c.Metrics.Addr = false, "nonsense" }, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := Default()
+			cfg.Storage.Backend = StorageMemory
+			tt.change(cfg)
+
+			err := cfg.Validate()
+			if tt.wantErr != (err != nil && strings.Contains(err.Error(), "metrics.addr:")) {
+				t.Errorf("Validate = %v, want a metrics.addr error: %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestAccessChangeStore(t *testing.T) {
+	tests := []struct {
+		name     string
+		provider string
+		backend  string
+		set      string
+		want     string
+	}{
+		{"firebase on firestore", AuthFirebase, StorageFirestore, "", "storage"},
+		{"firebase on memory", AuthFirebase, StorageMemory, "", "storage"},
+		{"local on firestore", AuthLocal, StorageFirestore, "", "storage"},
+		{"local on memory", AuthLocal, StorageMemory, "", "memory"},
+		{"set", AuthLocal, StorageMemory, "storage", "storage"},
+	}
+	for _, tt := range tests {
+		cfg := Default()
+		cfg.Auth.Provider, cfg.Storage.Backend, cfg.Access.ChangeStore = tt.provider, tt.backend, tt.set
+		if got := cfg.AccessChangeStore(); got != tt.want {
+			t.Errorf("%s: AccessChangeStore = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
@@ -2,22 +2,44 @@ package config
 
 import (
 	"context"
-	"log"
+	"fmt"
+	"log/slog"
+	"os"
 
 	firebase "firebase.google.com/go"
 	"google.golang.org/api/option"
 )
 
-var FirebaseApp *firebase.App
+// NewFirebaseApp initializes the Firebase app described by cfg. The app
+// only holds configuration; clients are created from it once, at startup.
+func NewFirebaseApp(cfg FirebaseConfig) (*firebase.App, error) {
+	// The Firestore client picks the emulator up from the environment
+	if cfg.EmulatorHost != "" {
+		if err := os.Setenv("FIRESTORE_EMULATOR_HOST", cfg.EmulatorHost); err != nil {
+			return nil, fmt.Errorf("failed to configure firestore emulator: %w", err)
+		}
+	}
+
+	var opts []option.ClientOption
+	switch {
+	case cfg.CredentialsJSON != "":
+		opts = append(opts, option.WithCredentialsJSON([]byte(cfg.CredentialsJSON)))
+	case cfg.UseADC, cfg.EmulatorHost != "":
+		// Application Default Credentials, or none for the emulator
+	default:
+		opts = append(opts, option.WithCredentialsFile(cfg.CredentialsFile))
+	}
 
-func InitFirebase() {
-	opt := option.WithCredentialsFile("serviceAccountKey.json")
+	var fbConfig *firebase.Config
+	if cfg.ProjectID != "" {
+		fbConfig = &firebase.Config{ProjectID: cfg.ProjectID}
+	}
 
-	app, err := firebase.NewApp(context.Background(), nil, opt)
+	app, err := firebase.NewApp(context.Background(), fbConfig, opts...)
 	if err != nil {
-		log.Fatalf("failed to initialize firebase: %v", err)
+		return nil, fmt.Errorf("failed to initialize firebase: %w", err)
 	}
 
-	FirebaseApp = app
-	log.Println("🔥 Firebase initialized")
+	slog.Info("firebase initialized", "project_id", cfg.ProjectID, "emulator", cfg.EmulatorHost != "")
+	return app, nil
 }
@@ -0,0 +1,97 @@
+// Package decode reads JSON request bodies strictly and validates them
+package decode
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"reflect"
+	"strings"
+
+	"mypremier-backend/internal/apierror"
+	"mypremier-backend/internal/logging"
+	"mypremier-backend/internal/validate"
+)
+
+// JSON decodes the body of r into dst and validates it with
+// validate.Struct. The body must be a single JSON value sent as
+// application/json, with no fields dst does not declare. Its size is capped
+// by middleware.MaxBodySize; a body over the limit is reported as 413. The
+// returned error is ready to be written with apierror.Write and lists every
+// invalid field.
+func JSON(r *http.Request, dst interface{}) *apierror.Error {
+	if ct := r.Header.Get("Content-Type"); ct != "" || r.ContentLength != 0 {
+		mediaType, _, err := mime.ParseMediaType(ct)
+		if err != nil || mediaType != "application/json" {
+			return apierror.New(http.StatusUnsupportedMediaType, "unsupported_media_type", "Content-Type must be application/json")
+		}
+	}
+
+	dec := json.NewDecoder(r.Body)
+	dec.DisallowUnknownFields()
+
+	if err := dec.Decode(dst); err != nil {
+		logging.FromContext(r.Context()).Warn("invalid request body", "err", err)
+		return fromDecodeError(err)
+	}
+	if _, err := dec.Token(); !errors.Is(err, io.EOF) {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			return fromDecodeError(err)
+		}
+		return apierror.BadRequest("invalid_request_body", "Request body must contain a single JSON value")
+	}
+
+	if fields := validate.Struct(dst); len(fields) > 0 {
+		return apierror.Validation(fields...)
+	}
+	return nil
+}
+
+// fromDecodeError turns a decoding failure into the most specific API error
+func fromDecodeError(err error) *apierror.Error {
+	var (
+		tooLarge  *http.MaxBytesError
+		typeErr   *json.UnmarshalTypeError
+		syntaxErr *json.SyntaxError
+	)
+	switch {
+	case errors.As(err, &tooLarge):
+		return apierror.New(http.StatusRequestEntityTooLarge, "request_too_large",
+			fmt.Sprintf("Request body must not exceed %d bytes", tooLarge.Limit))
+	case errors.Is(err, io.EOF):
+		return apierror.BadRequest("invalid_request_body", "Request body must not be empty")
+	case errors.As(err, &typeErr) && typeErr.Field != "":
+		return apierror.Validation(apierror.FieldError{Field: typeErr.Field, Message: "must be " + jsonType(typeErr.Type.Kind())})
+	case errors.As(err, &syntaxErr), errors.Is(err, io.ErrUnexpectedEOF):
+		return apierror.BadRequest("invalid_request_body", "Request body is not valid JSON")
+	}
+
+	// encoding/json has no error type for unknown fields
+	if name, ok := strings.CutPrefix(err.Error(), "json: unknown field "); ok {
+		return apierror.Validation(apierror.FieldError{Field: strings.Trim(name, `"`), Message: "is not allowed"})
+	}
+	return apierror.InvalidBody()
+}
+
+// jsonType names a Go kind the way a client sees it
+func jsonType(kind reflect.Kind) string {
+	switch kind {
+	case reflect.String:
+		return "a string"
+	case reflect.Bool:
+		return "a boolean"
+	case reflect.Slice, reflect.Array:
+		return "an array"
+	case reflect.Map, reflect.Struct:
+		return "an object"
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		return "a number"
+	}
+	return "of another type"
+}
@@ -0,0 +1,122 @@
+package decode_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"mypremier-backend/internal/apierror"
+	"mypremier-backend/internal/decode"
+	"mypremier-backend/internal/middleware"
+)
+
+type address struct {
+	City string `json:"city" validate:"required"`
+}
+
+type input struct {
+	Name    string   `json:"name" validate:"required,max=10"`
+	Count   int      `json:"count"`
+	Active  bool     `json:"active"`
+	Tags    []string `json:"tags"`
+	Address *address `json:"address"`
+}
+
+func TestJSON(t *testing.T) {
+	tests := []struct {
+		name        string
+		contentType string
+		body        string
+		wantStatus  int
+		wantCode    string
+		wantFields  map[string]string
+	}{
+		{"valid", "application/json", `{"name":"Pump","count":2}`, 0, "", nil},
+		{"charset parameter", "application/json; charset=utf-8", `{"name":"Pump"}`, 0, "", nil},
+		{"trailing whitespace", "application/json", "{\"name\":\"Pump\"}\n\t ", 0, "", nil},
+		{"no content type", "", `{"name":"Pump"}`, http.StatusUnsupportedMediaType, "unsupported_media_type", nil},
+		{"form content type", "application/x-www-form-urlencoded", `name=Pump`, http.StatusUnsupportedMediaType, "unsupported_media_type", nil},
+		{"text content type", "text/plain", `{"name":"Pump"}`, http.StatusUnsupportedMediaType, "unsupported_media_type", nil},
+		{"malformed content type", "application/json;;", `{"name":"Pump"}`, http.StatusUnsupportedMediaType, "unsupported_media_type", nil},
+		{"empty body", "application/json", ``, http.StatusBadRequest, "invalid_request_body", nil},
+		{"invalid JSON", "application/json", `{"name":`, http.StatusBadRequest, "invalid_request_body", nil},
+		{"syntax error", "application/json", `{"name" "Pump"}`, http.StatusBadRequest, "invalid_request_body", nil},
+		{"trailing value", "application/json", `{"name":"Pump"}{"name":"Valve"}`, http.StatusBadRequest, "invalid_request_body", nil},
+		{"trailing garbage", "application/json", `{"name":"Pump"} x`, http.StatusBadRequest, "invalid_request_body", nil},
+		{"array", "application/json", `[]`, http.StatusBadRequest, "invalid_request_body", nil},
+		{"unknown field", "application/json", `{"name":"Pump","admin":true}`, http.StatusBadRequest, "validation_failed",
+			map[string]string{"admin": "is not allowed"}},
+		{"unknown nested field", "application/json", `{"name":"Pump","address":{"city":"Jakarta","zip":"1"}}`, http.StatusBadRequest, "validation_failed",
+			map[string]string{"zip": "is not allowed"}},
+		{"string for a number", "application/json", `{"name":"Pump","count":"2"}`, http.StatusBadRequest, "validation_failed",
+			map[string]string{"count": "must be a number"}},
+		{"number for a boolean", "application/json", `{"name":"Pump","active":1}`, http.StatusBadRequest, "validation_failed",
+			map[string]string{"active": "must be a boolean"}},
+		{"object for an array", "application/json", `{"name":"Pump","tags":{}}`, http.StatusBadRequest, "validation_failed",
+			map[string]string{"tags": "must be an array"}},
+		{"number for a string", "application/json", `{"name":1}`, http.StatusBadRequest, "validation_failed",
+			map[string]string{"name": "must be a string"}},
+		{"every invalid field", "application/json", `{"name":"","address":{"city":" "}}`, http.StatusBadRequest, "validation_failed",
+			map[string]string{"name": "is required", "address.city": "is required"}},
+		{"too long", "application/json", `{"name":"` + strings.Repeat("x", 11) + `"}`, http.StatusBadRequest, "validation_failed",
+			map[string]string{"name": "must have at most 10 characters"}},
+		{"oversize", "application/json", `{"name":"` + strings.Repeat("x", 100) + `"}`, http.StatusRequestEntityTooLarge, "request_too_large", nil},
+		{"oversize after a value", "application/json", `{"name":"Pump"}` + strings.Repeat(" ", 100), http.StatusRequestEntityTooLarge, "request_too_large", nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got *apierror.Error
+			var dst input
+			h := middleware.MaxBodySize(64)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				got = decode.JSON(r, &dst)
+			}))
+			req := httptest.NewRequest(http.MethodPost, "/admin/products", strings.NewReader(tt.body))
+			if tt.contentType != "" {
+				req.Header.Set("Content-Type", tt.contentType)
+			}
+			h.ServeHTTP(httptest.NewRecorder(), req)
+
+			if tt.wantStatus == 0 {
+				if got != nil {
+					t.Fatalf("JSON = %+v, want nil", got)
+				}
+				if dst.Name != "Pump" {
+					t.Errorf("decoded %+v", dst)
+				}
+				return
+			}
+			if got == nil {
+				t.Fatalf("JSON accepted the body, want %d %s", tt.wantStatus, tt.wantCode)
+			}
+			if got.Status != tt.wantStatus || got.Code != tt.wantCode {
+				t.Errorf("JSON = %d %s (%s), want %d %s", got.Status, got.Code, got.Message, tt.wantStatus, tt.wantCode)
+			}
+			if tt.wantFields != nil {
+				fields := map[string]string{}
+				details, _ := got.Details.(apierror.ValidationDetails)
+				for _, f := range details.Fields {
+					fields[f.Field] = f.Message
+				}
+				if len(fields) != len(tt.wantFields) {
+					t.Errorf("fields %v, want %v", fields, tt.wantFields)
+				}
+				for k, v := range tt.wantFields {
+					if fields[k] != v {
+						t.Errorf("field %s: %q, want %q", k, fields[k], v)
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestJSONWithoutBody(t *testing.T) {
+	// A request without a body or Content-Type is an empty body, not an
+	// unsupported one
+	req := httptest.NewRequest(http.MethodPost, "/admin/products", http.NoBody)
+	var dst input
+	if err := decode.JSON(req, &dst); err == nil || err.Code != "invalid_request_body" {
+		t.Errorf("JSON = %+v, want invalid_request_body", err)
+	}
+}
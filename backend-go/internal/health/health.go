@@ -0,0 +1,156 @@
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"mypremier-backend/internal/logging"
+)
+
+// CheckFunc reports whether a dependency is usable. It must give up once
+// ctx is done.
+type CheckFunc func(ctx context.Context) error
+
+type check struct {
+	name    string
+	timeout time.Duration
+	fn      CheckFunc
+}
+
+// Checker serves the liveness and readiness probes. Readiness runs every
+// registered check in parallel, each under its own timeout, and fails while
+// the process is shutting down so load balancers stop sending traffic
+// before the listener closes.
+type Checker struct {
+	shuttingDown func() bool
+	checks       []check
+}
+
+// New creates a checker. shuttingDown reports whether a graceful shutdown
+// has started; it may be nil.
+func New(shuttingDown func() bool) *Checker {
+	if shuttingDown == nil {
+		shuttingDown = func() bool { return false }
+	}
+	return &Checker{shuttingDown: shuttingDown}
+}
+
+// Add registers a readiness check
+func (c *Checker) Add(name string, timeout time.Duration, fn CheckFunc) {
+	c.checks = append(c.checks, check{name: name, timeout: timeout, fn: fn})
+}
+
+// Result is the outcome of one check
+type Result struct {
+	Status     string  `json:"status"`
+	DurationMS float64 `json:"duration_ms"`
+	Error      string  `json:"error,omitempty"`
+}
+
+// Report is the body of a probe response
+type Report struct {
+	Status string            `json:"status"`
+	Checks map[string]Result `json:"checks,omitempty"`
+}
+
+const (
+	StatusOK           = "ok"
+	StatusFailing      = "failing"
+	StatusShuttingDown = "shutting_down"
+)
+
+// Run executes all checks and reports whether every one passed
+func (c *Checker) Run(ctx context.Context) (Report, bool) {
+	report := Report{Status: StatusOK, Checks: make(map[string]Result, len(c.checks))}
+
+	var (
+		mu sync.Mutex
+		wg sync.WaitGroup
+	)
+	for _, chk := range c.checks {
+		wg.Add(1)
+		go func(chk check) {
+			defer wg.Done()
+			res := chk.run(ctx)
+			mu.Lock()
+			report.Checks[chk.name] = res
+			mu.Unlock()
+		}(chk)
+	}
+	wg.Wait()
+
+	for _, res := range report.Checks {
+		if res.Status != StatusOK {
+			report.Status = StatusFailing
+		}
+	}
+	if c.shuttingDown() {
+		report.Status = StatusShuttingDown
+	}
+
+	return report, report.Status == StatusOK
+}
+
+func (chk check) run(ctx context.Context) Result {
+	ctx, cancel := context.WithTimeout(ctx, chk.timeout)
+	defer cancel()
+
+	start := time.Now()
+	errc := make(chan error, 1)
+	go func() {
+		defer func() {
+			if p := recover(); p != nil {
+				errc <- fmt.Errorf("check panicked: %v", p)
+			}
+		}()
+		errc <- chk.fn(ctx)
+	}()
+
+	// Don't rely on the check honouring ctx
+	var err error
+	select {
+	case err = <-errc:
+	case <-ctx.Done():
+		err = fmt.Errorf("timed out after %s", chk.timeout)
+	}
+
+	res := Result{
+		Status:     StatusOK,
+		DurationMS: float64(time.Since(start).Microseconds()) / 1000,
+	}
+	if err != nil {
+		res.Status = StatusFailing
+		res.Error = err.Error()
+	}
+	return res
+}
+
+// Livez answers as long as the process can serve HTTP. It checks no
+// dependencies, so a broken dependency never gets the process restarted.
+func (c *Checker) Livez(w http.ResponseWriter, r *http.Request) {
+	write(w, r, http.StatusOK, Report{Status: StatusOK})
+}
+
+// Readyz runs the readiness checks and answers 503 if any fails or the
+// process is shutting down
+func (c *Checker) Readyz(w http.ResponseWriter, r *http.Request) {
+	report, ok := c.Run(r.Context())
+	status := http.StatusOK
+	if !ok {
+		status = http.StatusServiceUnavailable
+	}
+	write(w, r, status, report)
+}
+
+func write(w http.ResponseWriter, r *http.Request, status int, report Report) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(report); err != nil {
+		logging.FromContext(r.Context()).Error("encoding response", "err", err)
+	}
+}
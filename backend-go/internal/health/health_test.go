@@ -0,0 +1,154 @@
+package health_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"mypremier-backend/internal/health"
+	"mypremier-backend/internal/lifecycle"
+)
+
+func readyz(t *testing.T, c *health.Checker) (int, health.Report) {
+	t.Helper()
+	rec := httptest.NewRecorder()
+	c.Readyz(rec, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+	var report health.Report
+	if err := json.Unmarshal(rec.Body.Bytes(), &report); err != nil {
+		t.Fatalf("decoding %s: %v", rec.Body, err)
+	}
+	if rec.Header().Get("Content-Type") != "application/json" || rec.Header().Get("Cache-Control") != "no-store" {
+		t.Errorf("headers %v", rec.Header())
+	}
+	return rec.Code, report
+}
+
+func TestReadyz(t *testing.T) {
+	// hang ignores its context, like a client stuck on a dead connection
+	hung := make(chan struct{})
+	t.Cleanup(func() { close(hung) })
+	hang := func(ctx context.Context) error {
+		<-hung
+		return nil
+	}
+	ok := func(ctx context.Context) error { return nil }
+
+	tests := []struct {
+		name       string
+		checks     map[string]health.CheckFunc
+		wantCode   int
+		wantChecks map[string]string
+	}{
+		{"no checks", nil, http.StatusOK, nil},
+		{"passing", map[string]health.CheckFunc{"firestore": ok, "redis": ok},
+			http.StatusOK, map[string]string{"firestore": "", "redis": ""}},
+		{"failing", map[string]health.CheckFunc{"firestore": ok, "redis": func(ctx context.Context) error {
+			return errors.New("connection refused")
+		}}, http.StatusServiceUnavailable, map[string]string{"firestore": "", "redis": "connection refused"}},
+		{"timed out", map[string]health.CheckFunc{"firestore": hang, "redis": ok},
+			http.StatusServiceUnavailable, map[string]string{"firestore": "timed out after 20ms", "redis": ""}},
+		{"panicked", map[string]health.CheckFunc{"firestore": func(ctx context.Context) error { panic("nil client") }},
+			http.StatusServiceUnavailable, map[string]string{"firestore": "check panicked: nil client"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := health.New(nil)
+			for name, fn := range tt.checks {
+				c.Add(name, 20*time.Millisecond, fn)
+			}
+
+			start := time.Now()
+			code, report := readyz(t, c)
+			if elapsed := time.Since(start); elapsed > time.Second {
+				t.Errorf("probe took %s", elapsed)
+			}
+			if code != tt.wantCode {
+				t.Errorf("status %d, want %d", code, tt.wantCode)
+			}
+			wantStatus := health.StatusOK
+			if tt.wantCode != http.StatusOK {
+				wantStatus = health.StatusFailing
+			}
+			if report.Status != wantStatus || len(report.Checks) != len(tt.wantChecks) {
+				t.Fatalf("report %+v, want %s with %d checks", report, wantStatus, len(tt.wantChecks))
+			}
+			for name, wantErr := range tt.wantChecks {
+				res := report.Checks[name]
+				if res.Error != wantErr || (res.Status == health.StatusOK) != (wantErr == "") {
+					t.Errorf("check %s = %+v, want error %q", name, res, wantErr)
+				}
+			}
+		})
+	}
+}
+
+func TestReadyzWhileShuttingDown(t *testing.T) {
+	var shuttingDown atomic.Bool
+	c := health.New(shuttingDown.Load)
+	c.Add("firestore", time.Second, func(ctx context.Context) error { return nil })
+
+	if code, _ := readyz(t, c); code != http.StatusOK {
+		t.Fatalf("before shutdown: status %d", code)
+	}
+	shuttingDown.Store(true)
+	code, report := readyz(t, c)
+	if code != http.StatusServiceUnavailable || report.Status != health.StatusShuttingDown {
+		t.Errorf("during shutdown: status %d, report %+v; want 503 shutting_down", code, report)
+	}
+	// Dependencies are still reported
+	if report.Checks["firestore"].Status != health.StatusOK {
+		t.Errorf("checks %+v", report.Checks)
+	}
+
+	// Liveness ignores both checks and shutdown
+	rec := httptest.NewRecorder()
+	c.Livez(rec, httptest.NewRequest(http.MethodGet, "/livez", nil))
+	if rec.Code != http.StatusOK || strings.TrimSpace(rec.Body.String()) != `{"status":"ok"}` {
+		t.Errorf("livez: status %d, body %s", rec.Code, rec.Body)
+	}
+}
+
+func TestReadyzReportsStoppedWorkers(t *testing.T) {
+	app := lifecycle.New(time.Second)
+	c := health.New(app.ShuttingDown)
+	c.Add("workers", time.Second, app.CheckWorkers)
+
+	stopped := make(chan struct{})
+	app.Go("claims reconciler", func(ctx context.Context) error {
+		// Returning without an error does not stop the process
+		close(stopped)
+		return nil
+	})
+	app.Go("exporter", func(ctx context.Context) error {
+		<-ctx.Done()
+		return nil
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() { done <- app.Run(ctx) }()
+	<-stopped
+
+	// The worker records its result just after returning
+	var code int
+	var report health.Report
+	for deadline := time.Now().Add(time.Second); time.Now().Before(deadline); time.Sleep(time.Millisecond) {
+		if code, report = readyz(t, c); code != http.StatusOK {
+			break
+		}
+	}
+	if code != http.StatusServiceUnavailable || report.Checks["workers"].Error != "worker claims reconciler: returned early" {
+		t.Errorf("status %d, report %+v; want the stopped worker reported", code, report)
+	}
+
+	cancel()
+	if err := <-done; err != nil {
+		t.Errorf("Run = %v", err)
+	}
+}
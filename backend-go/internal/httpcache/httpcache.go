@@ -0,0 +1,121 @@
+// Package httpcache serves JSON with validators, so clients and CDNs can
+// revalidate cached copies instead of downloading them again
+package httpcache
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"mypremier-backend/internal/apierror"
+	"mypremier-backend/internal/logging"
+)
+
+// Policy is the caching policy of a group of public responses
+type Policy struct {
+	// MaxAge is how long caches may reuse a response without revalidating;
+	// 0 makes them revalidate every time
+	MaxAge time.Duration
+}
+
+// CacheControl returns the Cache-Control value of the policy
+func (p Policy) CacheControl() string {
+	if p.MaxAge <= 0 {
+		return "public, no-cache"
+	}
+	return "public, max-age=" + strconv.Itoa(int(p.MaxAge/time.Second))
+}
+
+// Validators describe the version of a response besides its content
+type Validators struct {
+	// LastModified is when the content last changed; zero leaves out
+	// Last-Modified
+	LastModified time.Time
+	// IgnoreModifiedSince answers If-Modified-Since with the full response.
+	// Lists set it: removing an item does not move their LastModified, so
+	// only the ETag tells that they changed.
+	IgnoreModifiedSince bool
+}
+
+// WriteJSON writes v as JSON with a strong ETag computed from the encoded
+// content, Cache-Control from p and Last-Modified from val. A request whose
+// If-None-Match, or failing that If-Modified-Since, shows it already has
+// this version gets 304 Not Modified without a body.
+func (p Policy) WriteJSON(w http.ResponseWriter, r *http.Request, v interface{}, val Validators) {
+	body, err := json.Marshal(v)
+	if err != nil {
+		logging.FromContext(r.Context()).Error("encoding response", "err", err)
+		apierror.Write(w, r, apierror.Internal())
+		return
+	}
+	body = append(body, '\n')
+
+	etag := ETag(body)
+	h := w.Header()
+	h.Set("ETag", etag)
+	h.Set("Cache-Control", p.CacheControl())
+	if !val.LastModified.IsZero() {
+		h.Set("Last-Modified", val.LastModified.UTC().Format(http.TimeFormat))
+	}
+
+	if notModified(r, etag, val) {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+
+	h.Set("Content-Type", "application/json")
+	h.Set("Content-Length", strconv.Itoa(len(body)))
+	w.Write(body)
+}
+
+// ETag returns a strong entity tag for body
+func ETag(body []byte) string {
+	sum := sha256.Sum256(body)
+	return `"` + base64.RawURLEncoding.EncodeToString(sum[:16]) + `"`
+}
+
+// notModified evaluates the preconditions of RFC 9110 section 13.2.2 for a
+// GET or HEAD
+func notModified(r *http.Request, etag string, val Validators) bool {
+	if inm := r.Header.Get("If-None-Match"); inm != "" {
+		return matchesAny(inm, etag)
+	}
+
+	ims := r.Header.Get("If-Modified-Since")
+	if ims == "" || val.LastModified.IsZero() || val.IgnoreModifiedSince {
+		return false
+	}
+	since, err := http.ParseTime(ims)
+	if err != nil {
+		return false
+	}
+	// HTTP dates have a precision of one second
+	return !val.LastModified.Truncate(time.Second).After(since)
+}
+
+// matchesAny reports whether an If-None-Match list names etag. The
+// comparison is weak, as the header requires, so W/ prefixes are ignored.
+func matchesAny(header, etag string) bool {
+	for _, candidate := range strings.Split(header, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if candidate == "*" || strings.TrimPrefix(candidate, "W/") == etag {
+			return true
+		}
+	}
+	return false
+}
+
+// Latest returns the latest of times, ignoring zero ones
+func Latest(times ...time.Time) time.Time {
+	var latest time.Time
+	for _, t := range times {
+		if t.After(latest) {
+			latest = t
+		}
+	}
+	return latest
+}
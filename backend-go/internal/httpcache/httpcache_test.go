@@ -0,0 +1,130 @@
+package httpcache
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCacheControl(t *testing.T) {
+	tests := []struct {
+		maxAge time.Duration
+		want   string
+	}{
+		{0, "public, no-cache"},
+		{-time.Second, "public, no-cache"},
+		{90 * time.Second, "public, max-age=90"},
+		{1500 * time.Millisecond, "public, max-age=1"},
+	}
+	for _, tt := range tests {
+		if got := (Policy{MaxAge: tt.maxAge}).CacheControl(); got != tt.want {
+			t.Errorf("CacheControl(%s) = %q, want %q", tt.maxAge, got, tt.want)
+		}
+	}
+}
+
+func TestWriteJSON(t *testing.T) {
+	modified := time.Date(2026, 3, 1, 12, 0, 0, 500_000_000, time.FixedZone("WIB", 7*3600))
+	value := map[string]string{"name": "Pump"}
+	etag := ETag([]byte(`{"name":"Pump"}` + "\n"))
+	other := ETag([]byte("other"))
+	httpDate := func(t time.Time) string { return t.UTC().Format(http.TimeFormat) }
+
+	tests := []struct {
+		name     string
+		header   map[string]string
+		val      Validators
+		wantCode int
+	}{
+		{"no preconditions", nil, Validators{LastModified: modified}, http.StatusOK},
+		{"etag match", map[string]string{"If-None-Match": etag}, Validators{}, http.StatusNotModified},
+		{"etag mismatch", map[string]string{"If-None-Match": other}, Validators{}, http.StatusOK},
+		{"weak etag", map[string]string{"If-None-Match": "W/" + etag}, Validators{}, http.StatusNotModified},
+		{"list", map[string]string{"If-None-Match": other + ", " + etag}, Validators{}, http.StatusNotModified},
+		{"list without spaces", map[string]string{"If-None-Match": other + "," + etag}, Validators{}, http.StatusNotModified},
+		{"list mismatch", map[string]string{"If-None-Match": other + ", W/" + other}, Validators{}, http.StatusOK},
+		{"star", map[string]string{"If-None-Match": "*"}, Validators{}, http.StatusNotModified},
+		{"unquoted etag", map[string]string{"If-None-Match": strings.Trim(etag, `"`)}, Validators{}, http.StatusOK},
+		{"modified since", map[string]string{"If-Modified-Since": httpDate(modified.Add(-time.Hour))},
+			Validators{LastModified: modified}, http.StatusOK},
+		{"not modified since", map[string]string{"If-Modified-Since": httpDate(modified)},
+			Validators{LastModified: modified}, http.StatusNotModified},
+		{"not modified since, later date", map[string]string{"If-Modified-Since": httpDate(modified.Add(time.Hour))},
+			Validators{LastModified: modified}, http.StatusNotModified},
+		{"modified since ignored", map[string]string{"If-Modified-Since": httpDate(modified)},
+			Validators{LastModified: modified, IgnoreModifiedSince: true}, http.StatusOK},
+		{"modified since without last modified", map[string]string{"If-Modified-Since": httpDate(modified)},
+			Validators{}, http.StatusOK},
+		{"invalid date", map[string]string{"If-Modified-Since": "yesterday"}, Validators{LastModified: modified}, http.StatusOK},
+		{"etag mismatch wins over date", map[string]string{"If-None-Match": other, "If-Modified-Since": httpDate(modified)},
+			Validators{LastModified: modified}, http.StatusOK},
+		{"etag match wins over date", map[string]string{"If-None-Match": etag, "If-Modified-Since": httpDate(modified.Add(-time.Hour))},
+			Validators{LastModified: modified}, http.StatusNotModified},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/products/1", nil)
+			for k, v := range tt.header {
+				req.Header.Set(k, v)
+			}
+			rec := httptest.NewRecorder()
+			Policy{MaxAge: time.Minute}.WriteJSON(rec, req, value, tt.val)
+
+			if rec.Code != tt.wantCode {
+				t.Fatalf("status %d, want %d", rec.Code, tt.wantCode)
+			}
+			h := rec.Header()
+			if h.Get("ETag") != etag || h.Get("Cache-Control") != "public, max-age=60" {
+				t.Errorf("ETag %q, Cache-Control %q", h.Get("ETag"), h.Get("Cache-Control"))
+			}
+			wantModified := ""
+			if !tt.val.LastModified.IsZero() {
+				wantModified = "Sun, 01 Mar 2026 05:00:00 GMT"
+			}
+			if got := h.Get("Last-Modified"); got != wantModified {
+				t.Errorf("Last-Modified = %q, want %q", got, wantModified)
+			}
+
+			if tt.wantCode == http.StatusNotModified {
+				if rec.Body.Len() != 0 || h.Get("Content-Type") != "" || h.Get("Content-Length") != "" {
+					t.Errorf("304 with body %q and headers %v", rec.Body, h)
+				}
+				return
+			}
+			if rec.Body.String() != `{"name":"Pump"}`+"\n" || h.Get("Content-Type") != "application/json" || h.Get("Content-Length") != "16" {
+				t.Errorf("body %q, headers %v", rec.Body, h)
+			}
+		})
+	}
+}
+
+func TestWriteJSONEncodingError(t *testing.T) {
+	rec := httptest.NewRecorder()
+	Policy{}.WriteJSON(rec, httptest.NewRequest(http.MethodGet, "/products", nil), make(chan int), Validators{})
+	if rec.Code != http.StatusInternalServerError || rec.Header().Get("ETag") != "" {
+		t.Errorf("status %d, ETag %q; want a 500 without validators", rec.Code, rec.Header().Get("ETag"))
+	}
+}
+
+func TestETag(t *testing.T) {
+	a, b := ETag([]byte("a")), ETag([]byte("b"))
+	if a == b || a != ETag([]byte("a")) {
+		t.Errorf("ETag(a) = %s, ETag(b) = %s", a, b)
+	}
+	if !strings.HasPrefix(a, `"`) || !strings.HasSuffix(a, `"`) || strings.HasPrefix(a, "W/") {
+		t.Errorf("ETag %s is not a quoted strong tag", a)
+	}
+}
+
+func TestLatest(t *testing.T) {
+	a := time.Unix(100, 0)
+	b := time.Unix(200, 0)
+	if got := Latest(a, time.Time{}, b); !got.Equal(b) {
+		t.Errorf("Latest = %s, want %s", got, b)
+	}
+	if got := Latest(); !got.IsZero() {
+		t.Errorf("Latest() = %s, want zero", got)
+	}
+}
@@ -0,0 +1,41 @@
+// Package idempotency replays the stored response of a POST when a client
+// retries it with the same Idempotency-Key header, instead of running it
+// twice
+package idempotency
+
+import (
+	"context"
+	"net/http"
+	"time"
+)
+
+// Header carries the client's key for a request
+const Header = "Idempotency-Key"
+
+// MaxKeyLength bounds client keys; a UUID is 36 characters
+const MaxKeyLength = 255
+
+// Record is the state of one key. It is created when a request starts and
+// filled in with the response once the request completes.
+type Record struct {
+	// Fingerprint identifies the request payload, to catch a key reused for
+	// a different request
+	Fingerprint string `json:"fingerprint"`
+	// Done is false while the first request is still running
+	Done   bool        `json:"done"`
+	Status int         `json:"status,omitempty"`
+	Header http.Header `json:"header,omitempty"`
+	Body   []byte      `json:"body,omitempty"`
+}
+
+// Store keeps records until they expire
+type Store interface {
+	// Begin stores rec under key unless the key is taken. It returns the
+	// existing record when there is one, or nil when rec was stored.
+	Begin(ctx context.Context, key string, rec Record, ttl time.Duration) (*Record, error)
+	// Complete replaces the record of a key begun earlier
+	Complete(ctx context.Context, key string, rec Record, ttl time.Duration) error
+	// Release forgets a key so the request can be retried, e.g. after it
+	// failed on the server
+	Release(ctx context.Context, key string) error
+}
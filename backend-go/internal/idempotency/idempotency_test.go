@@ -0,0 +1,427 @@
+package idempotency
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+)
+
+// testStore is a store with a clock the test can move
+type testStore struct {
+	Store
+	advance func(d time.Duration)
+}
+
+func stores(t *testing.T) map[string]testStore {
+	mr := miniredis.RunT(t)
+	rdb := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() { rdb.Close() })
+
+	memory := NewMemoryStore()
+	now := time.Unix(1700000000, 0)
+	memory.now = func() time.Time { return now }
+
+	return map[string]testStore{
+		"memory": {memory, func(d time.Duration) { now = now.Add(d) }},
+		"redis":  {NewRedisStore(rdb, "test:"), mr.FastForward},
+	}
+}
+
+func TestStore(t *testing.T) {
+	ctx := context.Background()
+	ttl := time.Minute
+
+	for name, store := range stores(t) {
+		t.Run(name, func(t *testing.T) {
+			if existing, err := store.Begin(ctx, "k", Record{Fingerprint: "a"}, ttl); existing != nil || err != nil {
+				t.Fatalf("first Begin = %+v, %v; want nil", existing, err)
+			}
+			existing, err := store.Begin(ctx, "k", Record{Fingerprint: "b"}, ttl)
+			if err != nil || existing == nil || existing.Fingerprint != "a" || existing.Done {
+				t.Fatalf("second Begin = %+v, %v; want the running record", existing, err)
+			}
+
+			done := Record{Fingerprint: "a", Done: true, Status: http.StatusCreated,
+				Header: http.Header{"Location": {"/x/1"}}, Body: []byte(`{"id":"1"}`)}
+			if err := store.Complete(ctx, "k", done, ttl); err != nil {
+				t.Fatal(err)
+			}
+			existing, err = store.Begin(ctx, "k", Record{Fingerprint: "a"}, ttl)
+			if err != nil || existing == nil || !existing.Done || existing.Status != http.StatusCreated ||
+				string(existing.Body) != `{"id":"1"}` || existing.Header.Get("Location") != "/x/1" {
+				t.Fatalf("Begin after Complete = %+v, %v; want the stored response", existing, err)
+			}
+
+			if err := store.Release(ctx, "k"); err != nil {
+				t.Fatal(err)
+			}
+			if existing, err := store.Begin(ctx, "k", Record{Fingerprint: "c"}, ttl); existing != nil || err != nil {
+				t.Fatalf("Begin after Release = %+v, %v; want nil", existing, err)
+			}
+
+			store.advance(ttl + time.Second)
+			if existing, err := store.Begin(ctx, "k", Record{Fingerprint: "d"}, ttl); existing != nil || err != nil {
+				t.Fatalf("Begin after expiry = %+v, %v; want nil", existing, err)
+			}
+
+			// Releasing a key that is not held is not an error
+			if err := store.Release(ctx, "missing"); err != nil {
+				t.Errorf("Release of a missing key: %v", err)
+			}
+		})
+	}
+}
+
+// failingStore fails every operation
+type failingStore struct{}
+
+func (failingStore) Begin(ctx context.Context, key string, rec Record, ttl time.Duration) (*Record, error) {
+	return nil, errors.New("store down")
+}
+
+func (failingStore) Complete(ctx context.Context, key string, rec Record, ttl time.Duration) error {
+	return errors.New("store down")
+}
+
+func (failingStore) Release(ctx context.Context, key string) error {
+	return errors.New("store down")
+}
+
+// endpoint is a handler counting its calls, answering 201 unless status is
+// set
+type endpoint struct {
+	calls  atomic.Int32
+	status atomic.Int32
+	// wait, if set, blocks the handler until it is closed
+	wait chan struct{}
+}
+
+func (e *endpoint) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	n := e.calls.Add(1)
+	if e.wait != nil {
+		<-e.wait
+	}
+	status := int(e.status.Load())
+	if status == 0 {
+		status = http.StatusCreated
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Location", "/items/1")
+	w.Header().Set("X-Request-Id", "call-"+strconv.Itoa(int(n)))
+	w.WriteHeader(status)
+	w.Write([]byte(`{"id":"1"}`))
+}
+
+func send(h http.Handler, key, actor, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodPost, "/items", strings.NewReader(body))
+	if key != "" {
+		req.Header.Set(Header, key)
+	}
+	req.Header.Set("X-Actor", actor)
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	return rec
+}
+
+const lease = time.Minute
+
+func newKeeper(store Store) *Keeper {
+	return New(store, time.Hour, lease, func(r *http.Request) string { return r.Header.Get("X-Actor") })
+}
+
+func TestMiddlewareReplay(t *testing.T) {
+	for name, store := range stores(t) {
+		t.Run(name, func(t *testing.T) {
+			e := &endpoint{}
+			h := newKeeper(store).Middleware("POST /items")(e)
+
+			first := send(h, "key-1", "alice", `{"name":"a"}`)
+			if first.Code != http.StatusCreated || first.Header().Get("Idempotent-Replayed") != "" {
+				t.Fatalf("first: status %d, headers %v", first.Code, first.Header())
+			}
+
+			retry := send(h, "key-1", "alice", `{"name":"a"}`)
+			if e.calls.Load() != 1 {
+				t.Errorf("handler ran %d times, want 1", e.calls.Load())
+			}
+			if retry.Code != http.StatusCreated || retry.Body.String() != `{"id":"1"}` {
+				t.Errorf("retry: status %d, body %s", retry.Code, retry.Body)
+			}
+			if got := retry.Header().Get("Idempotent-Replayed"); got != "true" {
+				t.Errorf("Idempotent-Replayed = %q, want true", got)
+			}
+			if retry.Header().Get("Location") != "/items/1" || retry.Header().Get("Content-Type") != "application/json" {
+				t.Errorf("retry headers %v, want Location and Content-Type", retry.Header())
+			}
+			if got := retry.Header().Get("X-Request-Id"); got != "" {
+				t.Errorf("X-Request-Id of the first request was replayed: %q", got)
+			}
+
+			reused := send(h, "key-1", "alice", `{"name":"b"}`)
+			if reused.Code != http.StatusUnprocessableEntity || !strings.Contains(reused.Body.String(), "idempotency_key_reused") {
+				t.Errorf("different payload: status %d, body %s; want 422", reused.Code, reused.Body)
+			}
+
+			// Without a key every request runs
+			send(h, "", "alice", `{"name":"a"}`)
+			send(h, "", "alice", `{"name":"a"}`)
+			if e.calls.Load() != 3 {
+				t.Errorf("handler ran %d times, want 3", e.calls.Load())
+			}
+		})
+	}
+}
+
+func TestMiddlewareInProgress(t *testing.T) {
+	for name, store := range stores(t) {
+		t.Run(name, func(t *testing.T) {
+			e := &endpoint{wait: make(chan struct{})}
+			h := newKeeper(store).Middleware("POST /items")(e)
+
+			var wg sync.WaitGroup
+			var first *httptest.ResponseRecorder
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				first = send(h, "key-1", "alice", `{}`)
+			}()
+			for e.calls.Load() == 0 {
+				time.Sleep(time.Millisecond)
+			}
+
+			concurrent := send(h, "key-1", "alice", `{}`)
+			if concurrent.Code != http.StatusConflict || !strings.Contains(concurrent.Body.String(), "idempotency_key_in_progress") {
+				t.Errorf("concurrent: status %d, body %s; want 409", concurrent.Code, concurrent.Body)
+			}
+			if got := concurrent.Header().Get("Retry-After"); got != "1" {
+				t.Errorf("Retry-After = %q, want 1", got)
+			}
+
+			close(e.wait)
+			wg.Wait()
+			if first.Code != http.StatusCreated {
+				t.Errorf("first: status %d", first.Code)
+			}
+			if retry := send(h, "key-1", "alice", `{}`); retry.Header().Get("Idempotent-Replayed") != "true" {
+				t.Errorf("after completion: status %d, want a replay", retry.Code)
+			}
+		})
+	}
+}
+
+func TestMiddlewareReleasesAfterServerError(t *testing.T) {
+	for name, store := range stores(t) {
+		t.Run(name, func(t *testing.T) {
+			e := &endpoint{}
+			h := newKeeper(store).Middleware("POST /items")(e)
+
+			e.status.Store(http.StatusServiceUnavailable)
+			if rec := send(h, "key-1", "alice", `{}`); rec.Code != http.StatusServiceUnavailable {
+				t.Fatalf("first: status %d", rec.Code)
+			}
+
+			e.status.Store(0)
+			retry := send(h, "key-1", "alice", `{}`)
+			if retry.Code != http.StatusCreated || retry.Header().Get("Idempotent-Replayed") != "" {
+				t.Errorf("retry: status %d, headers %v; want the request to run again", retry.Code, retry.Header())
+			}
+			if e.calls.Load() != 2 {
+				t.Errorf("handler ran %d times, want 2", e.calls.Load())
+			}
+
+			// Client errors are stored like successes
+			e.status.Store(http.StatusBadRequest)
+			send(h, "key-2", "alice", `{}`)
+			if rec := send(h, "key-2", "alice", `{}`); rec.Code != http.StatusBadRequest || rec.Header().Get("Idempotent-Replayed") != "true" {
+				t.Errorf("4xx retry: status %d, want a replayed 400", rec.Code)
+			}
+		})
+	}
+}
+
+func TestMiddlewareReleasesAfterPanic(t *testing.T) {
+	store := NewMemoryStore()
+	panicked := true
+	h := newKeeper(store).Middleware("POST /items")(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if panicked {
+			panicked = false
+			panic("boom")
+		}
+		w.WriteHeader(http.StatusCreated)
+	}))
+
+	func() {
+		defer func() { recover() }()
+		send(h, "key-1", "alice", `{}`)
+	}()
+	if rec := send(h, "key-1", "alice", `{}`); rec.Code != http.StatusCreated || rec.Header().Get("Idempotent-Replayed") != "" {
+		t.Errorf("retry after a panic: status %d, want the request to run again", rec.Code)
+	}
+}
+
+func TestMiddlewareScope(t *testing.T) {
+	for name, store := range stores(t) {
+		t.Run(name, func(t *testing.T) {
+			keeper := newKeeper(store)
+			e := &endpoint{}
+			items := keeper.Middleware("POST /items")(e)
+			orders := keeper.Middleware("POST /orders")(e)
+
+			send(items, "key-1", "alice", `{}`)
+			tests := []struct {
+				name       string
+				h          http.Handler
+				actor      string
+				wantReplay bool
+			}{
+				{"same route and actor", items, "alice", true},
+				{"other actor", items, "bob", false},
+				{"anonymous", items, "", false},
+				{"other route", orders, "alice", false},
+			}
+			for _, tt := range tests {
+				rec := send(tt.h, "key-1", tt.actor, `{}`)
+				if replayed := rec.Header().Get("Idempotent-Replayed") == "true"; replayed != tt.wantReplay {
+					t.Errorf("%s: replayed = %v, want %v", tt.name, replayed, tt.wantReplay)
+				}
+			}
+		})
+	}
+}
+
+func TestMiddlewareSettlesAfterClientLeaves(t *testing.T) {
+	for name, store := range stores(t) {
+		t.Run(name, func(t *testing.T) {
+			e := &endpoint{}
+			// The client disconnects while the handler runs
+			var disconnect context.CancelFunc
+			h := newKeeper(store).Middleware("POST /items")(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if disconnect != nil {
+					disconnect()
+				}
+				e.ServeHTTP(w, r)
+			}))
+			leave := func(key string) {
+				ctx, cancel := context.WithCancel(context.Background())
+				defer cancel()
+				disconnect = cancel
+				req := httptest.NewRequest(http.MethodPost, "/items", strings.NewReader(`{}`)).WithContext(ctx)
+				req.Header.Set(Header, key)
+				req.Header.Set("X-Actor", "alice")
+				h.ServeHTTP(httptest.NewRecorder(), req)
+				disconnect = nil
+			}
+
+			leave("key-1")
+			if retry := send(h, "key-1", "alice", `{}`); retry.Code != http.StatusCreated || retry.Header().Get("Idempotent-Replayed") != "true" {
+				t.Errorf("retry of a completed request: status %d, body %s; want a replay", retry.Code, retry.Body)
+			}
+
+			e.status.Store(http.StatusServiceUnavailable)
+			leave("key-2")
+			e.status.Store(0)
+			if retry := send(h, "key-2", "alice", `{}`); retry.Code != http.StatusCreated || retry.Header().Get("Idempotent-Replayed") != "" {
+				t.Errorf("retry of a failed request: status %d, body %s; want it to run", retry.Code, retry.Body)
+			}
+		})
+	}
+}
+
+func TestMiddlewareLease(t *testing.T) {
+	ctx := context.Background()
+	for name, store := range stores(t) {
+		t.Run(name, func(t *testing.T) {
+			e := &endpoint{}
+			h := newKeeper(store).Middleware("POST /items")(e)
+
+			// A replica began the request and died
+			store.Begin(ctx, storeKey("POST /items", "alice", "key-1"), Record{Fingerprint: hashHex([]byte(`{}`))}, lease)
+			if rec := send(h, "key-1", "alice", `{}`); rec.Code != http.StatusConflict {
+				t.Fatalf("during the lease: status %d, want 409", rec.Code)
+			}
+			store.advance(lease + time.Second)
+			if rec := send(h, "key-1", "alice", `{}`); rec.Code != http.StatusCreated || rec.Header().Get("Idempotent-Replayed") != "" {
+				t.Errorf("after the lease: status %d, want the request to run", rec.Code)
+			}
+
+			// Completed responses are kept for the TTL, not the lease
+			store.advance(2 * lease)
+			if rec := send(h, "key-1", "alice", `{}`); rec.Header().Get("Idempotent-Replayed") != "true" {
+				t.Errorf("after completion: status %d, want a replay", rec.Code)
+			}
+		})
+	}
+}
+
+func TestActor(t *testing.T) {
+	actor := Actor(
+		func(r *http.Request) string { return r.Header.Get("X-Actor") },
+		func(r *http.Request) string { return r.RemoteAddr },
+	)
+	tests := []struct {
+		name       string
+		uid        string
+		remoteAddr string
+		want       string
+	}{
+		{"signed in", "alice", "192.0.2.1", "uid:alice"},
+		{"anonymous", "", "192.0.2.1", "ip:192.0.2.1"},
+		{"anonymous elsewhere", "", "192.0.2.2", "ip:192.0.2.2"},
+		{"UID that looks like an address", "192.0.2.1", "192.0.2.9", "uid:192.0.2.1"},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest(http.MethodPost, "/request-info", nil)
+		req.Header.Set("X-Actor", tt.uid)
+		req.RemoteAddr = tt.remoteAddr
+		if got := actor(req); got != tt.want {
+			t.Errorf("%s: Actor = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+
+	// Anonymous callers at different addresses do not share keys
+	e := &endpoint{}
+	h := New(NewMemoryStore(), time.Hour, lease, actor).Middleware("POST /request-info")(e)
+	for _, addr := range []string{"192.0.2.1", "192.0.2.2"} {
+		req := httptest.NewRequest(http.MethodPost, "/request-info", strings.NewReader(`{}`))
+		req.Header.Set(Header, "key-1")
+		req.RemoteAddr = addr
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		if rec.Header().Get("Idempotent-Replayed") != "" {
+			t.Errorf("%s got another caller's response", addr)
+		}
+	}
+	if e.calls.Load() != 2 {
+		t.Errorf("handler ran %d times, want 2", e.calls.Load())
+	}
+}
+
+func TestMiddlewareInvalidKeyAndStoreFailure(t *testing.T) {
+	e := &endpoint{}
+	h := newKeeper(NewMemoryStore()).Middleware("POST /items")(e)
+	rec := send(h, strings.Repeat("k", MaxKeyLength+1), "alice", `{}`)
+	if rec.Code != http.StatusBadRequest || !strings.Contains(rec.Body.String(), "invalid_idempotency_key") {
+		t.Errorf("long key: status %d, body %s; want 400", rec.Code, rec.Body)
+	}
+	if e.calls.Load() != 0 {
+		t.Error("handler ran for an invalid key")
+	}
+
+	// Without a store requests run unprotected rather than fail
+	h = newKeeper(failingStore{}).Middleware("POST /items")(e)
+	send(h, "key-1", "alice", `{}`)
+	send(h, "key-1", "alice", `{}`)
+	if e.calls.Load() != 2 {
+		t.Errorf("handler ran %d times with a failing store, want 2", e.calls.Load())
+	}
+}
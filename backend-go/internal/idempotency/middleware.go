@@ -0,0 +1,203 @@
+package idempotency
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"net/http"
+	"time"
+
+	"mypremier-backend/internal/apierror"
+	"mypremier-backend/internal/logging"
+	"mypremier-backend/internal/metrics"
+)
+
+// replayHeaders are the response headers stored with a record. Others, such
+// as the request ID and rate limit headers, describe the retry itself.
+var replayHeaders = []string{"Content-Type", "Location"}
+
+// Keeper makes requests carrying an Idempotency-Key idempotent
+type Keeper struct {
+	store Store
+	ttl   time.Duration
+	lease time.Duration
+	actor func(*http.Request) string
+}
+
+// New keeps completed records in store for ttl. A request still running
+// holds its key for lease, so a key left behind by a crashed replica frees
+// up soon. actor identifies the caller so that callers cannot replay each
+// other's responses, see Actor.
+func New(store Store, ttl, lease time.Duration, actor func(*http.Request) string) *Keeper {
+	return &Keeper{
+		store: store,
+		ttl:   ttl,
+		lease: lease,
+		actor: actor,
+	}
+}
+
+// Actor identifies signed-in callers by uid and anonymous ones by clientIP,
+// so that anonymous callers do not share one namespace
+func Actor(uid, clientIP func(*http.Request) string) func(*http.Request) string {
+	return func(r *http.Request) string {
+		if id := uid(r); id != "" {
+			return "uid:" + id
+		}
+		return "ip:" + clientIP(r)
+	}
+}
+
+// Middleware applies keys to route, the route pattern. Requests without the
+// header run as usual. The first request with a key runs and its response is
+// stored unless it failed on the server; retries with the same key and
+// payload get the stored response with Idempotent-Replayed: true. A retry
+// with another payload gets 422, and one arriving while the first is still
+// running gets 409. If the store fails the request runs without protection.
+func (k *Keeper) Middleware(route string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			clientKey := r.Header.Get(Header)
+			if clientKey == "" {
+				next.ServeHTTP(w, r)
+				return
+			}
+			if len(clientKey) > MaxKeyLength {
+				apierror.Write(w, r, apierror.BadRequest("invalid_idempotency_key", "Idempotency-Key must not exceed 255 characters"))
+				return
+			}
+
+			body, err := io.ReadAll(r.Body)
+			if err != nil {
+				// Let the handler report the body error, e.g. too large
+				r.Body = io.NopCloser(io.MultiReader(bytes.NewReader(body), errReader{err}))
+				next.ServeHTTP(w, r)
+				return
+			}
+			r.Body = io.NopCloser(bytes.NewReader(body))
+
+			ctx := r.Context()
+			key := storeKey(route, k.actor(r), clientKey)
+			fingerprint := hashHex(body)
+
+			existing, err := k.store.Begin(ctx, key, Record{Fingerprint: fingerprint}, k.lease)
+			if err != nil {
+				logging.FromContext(ctx).Warn("idempotency store unavailable, running request", "route", route, "err", err)
+				next.ServeHTTP(w, r)
+				return
+			}
+			if existing != nil {
+				k.replay(w, r, route, existing, fingerprint)
+				return
+			}
+
+			// The record is settled even if the client went away, or its
+			// retries would find the key in progress until the lease ends
+			storeCtx := context.WithoutCancel(ctx)
+			rec := &recorder{ResponseWriter: w}
+			completed := false
+			defer func() {
+				// A panic or server error leaves the key free for a retry
+				if !completed {
+					if err := k.store.Release(storeCtx, key); err != nil {
+						logging.FromContext(ctx).Warn("releasing idempotency key", "route", route, "err", err)
+					}
+				}
+			}()
+
+			next.ServeHTTP(rec, r)
+
+			status := rec.statusCode()
+			if status >= http.StatusInternalServerError {
+				return
+			}
+
+			stored := Record{Fingerprint: fingerprint, Done: true, Status: status, Header: http.Header{}, Body: rec.body.Bytes()}
+			for _, name := range replayHeaders {
+				if v := w.Header().Values(name); len(v) > 0 {
+					stored.Header[name] = v
+				}
+			}
+			if err := k.store.Complete(storeCtx, key, stored, k.ttl); err != nil {
+				logging.FromContext(ctx).Warn("storing idempotent response", "route", route, "err", err)
+				return
+			}
+			completed = true
+		})
+	}
+}
+
+func (k *Keeper) replay(w http.ResponseWriter, r *http.Request, route string, rec *Record, fingerprint string) {
+	switch {
+	case rec.Fingerprint != fingerprint:
+		metrics.IdempotentRequests.WithLabelValues(route, "mismatch").Inc()
+		apierror.Write(w, r, apierror.New(http.StatusUnprocessableEntity, "idempotency_key_reused",
+			"Idempotency-Key was already used for a different request"))
+	case !rec.Done:
+		metrics.IdempotentRequests.WithLabelValues(route, "in_progress").Inc()
+		w.Header().Set("Retry-After", "1")
+		apierror.Write(w, r, apierror.New(http.StatusConflict, "idempotency_key_in_progress",
+			"A request with this Idempotency-Key is still being processed"))
+	default:
+		metrics.IdempotentRequests.WithLabelValues(route, "replayed").Inc()
+		logging.FromContext(r.Context()).Info("replaying idempotent response", "route", route, "status", rec.Status)
+		for name, values := range rec.Header {
+			w.Header()[name] = values
+		}
+		w.Header().Set("Idempotent-Replayed", "true")
+		w.WriteHeader(rec.Status)
+		w.Write(rec.Body)
+	}
+}
+
+// storeKey hashes the parts so that client keys of any content make safe
+// store keys
+func storeKey(route, actor, clientKey string) string {
+	return hashHex([]byte(route + "\x00" + actor + "\x00" + clientKey))
+}
+
+func hashHex(b []byte) string {
+	sum := sha256.Sum256(b)
+	return hex.EncodeToString(sum[:])
+}
+
+// recorder captures the response while writing it through
+type recorder struct {
+	http.ResponseWriter
+	status int
+	body   bytes.Buffer
+}
+
+func (rec *recorder) WriteHeader(status int) {
+	if rec.status == 0 {
+		rec.status = status
+	}
+	rec.ResponseWriter.WriteHeader(status)
+}
+
+func (rec *recorder) Write(b []byte) (int, error) {
+	if rec.status == 0 {
+		rec.status = http.StatusOK
+	}
+	rec.body.Write(b)
+	return rec.ResponseWriter.Write(b)
+}
+
+func (rec *recorder) statusCode() int {
+	if rec.status == 0 {
+		return http.StatusOK
+	}
+	return rec.status
+}
+
+// Unwrap lets http.ResponseController reach the underlying writer
+func (rec *recorder) Unwrap() http.ResponseWriter {
+	return rec.ResponseWriter
+}
+
+// errReader fails every read with err
+type errReader struct{ err error }
+
+func (e errReader) Read([]byte) (int, error) { return 0, e.err }
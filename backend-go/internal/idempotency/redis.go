@@ -0,0 +1,67 @@
+package idempotency
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// RedisStore keeps records in Redis as JSON strings, so keys hold across
+// replicas
+type RedisStore struct {
+	client redis.Cmdable
+	prefix string
+}
+
+// NewRedisStore stores records under keys starting with prefix
+func NewRedisStore(client redis.Cmdable, prefix string) *RedisStore {
+	return &RedisStore{
+		client: client,
+		prefix: prefix,
+	}
+}
+
+func (s *RedisStore) Begin(ctx context.Context, key string, rec Record, ttl time.Duration) (*Record, error) {
+	data, err := json.Marshal(rec)
+	if err != nil {
+		return nil, fmt.Errorf("encoding idempotency record: %w", err)
+	}
+
+	// SET NX GET stores the record only if the key is free and returns the
+	// existing value otherwise, in one round trip
+	existing, err := s.client.SetArgs(ctx, s.prefix+key, data, redis.SetArgs{Mode: "NX", Get: true, TTL: ttl}).Bytes()
+	if errors.Is(err, redis.Nil) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("begin idempotency key: %w", err)
+	}
+
+	var stored Record
+	if err := json.Unmarshal(existing, &stored); err != nil {
+		return nil, fmt.Errorf("decoding idempotency record: %w", err)
+	}
+	return &stored, nil
+}
+
+func (s *RedisStore) Complete(ctx context.Context, key string, rec Record, ttl time.Duration) error {
+	data, err := json.Marshal(rec)
+	if err != nil {
+		return fmt.Errorf("encoding idempotency record: %w", err)
+	}
+	if err := s.client.Set(ctx, s.prefix+key, data, ttl).Err(); err != nil {
+		return fmt.Errorf("complete idempotency key: %w", err)
+	}
+	return nil
+}
+
+func (s *RedisStore) Release(ctx context.Context, key string) error {
+	if err := s.client.Del(ctx, s.prefix+key).Err(); err != nil {
+		return fmt.Errorf("release idempotency key: %w", err)
+	}
+	return nil
+}
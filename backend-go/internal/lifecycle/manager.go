@@ -0,0 +1,215 @@
+package lifecycle
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"os"
+	"os/signal"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"time"
+)
+
+type namedServer struct {
+	name   string
+	server *http.Server
+}
+
+type worker struct {
+	name string
+	run  func(ctx context.Context) error
+}
+
+type closer struct {
+	name  string
+	close func() error
+}
+
+// Manager runs the HTTP servers and background workers of the process and
+// shuts them down in order when a termination signal arrives:
+//
+//  1. stop accepting connections and drain in-flight requests
+//  2. cancel background workers and wait for them to return
+//  3. run the registered closers (Firestore clients, exporters) in reverse order
+//
+// Every step shares one deadline set by the shutdown timeout. Before step 1
+// the manager reports ShuttingDown and waits for the drain delay, giving load
+// balancers time to see the readiness probe fail.
+type Manager struct {
+	shutdownTimeout time.Duration
+	drainDelay      time.Duration
+	shuttingDown    atomic.Bool
+
+	servers []namedServer
+	workers []worker
+	closers []closer
+
+	mu      sync.Mutex
+	stopped map[string]error
+}
+
+// New creates a manager that allows shutdownTimeout for a graceful shutdown
+func New(shutdownTimeout time.Duration) *Manager {
+	return &Manager{
+		shutdownTimeout: shutdownTimeout,
+		stopped:         make(map[string]error),
+	}
+}
+
+// SetDrainDelay sets how long to keep serving after a shutdown signal
+// before the listeners close. It does not count against the shutdown timeout.
+func (m *Manager) SetDrainDelay(d time.Duration) {
+	m.drainDelay = d
+}
+
+// ShuttingDown reports whether a shutdown has started
+func (m *Manager) ShuttingDown() bool {
+	return m.shuttingDown.Load()
+}
+
+// CheckWorkers returns an error naming the background workers that have
+// stopped before shutdown
+func (m *Manager) CheckWorkers(ctx context.Context) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var errs []error
+	for _, w := range m.workers {
+		if err, ok := m.stopped[w.name]; ok && !m.ShuttingDown() {
+			if err == nil {
+				err = errors.New("returned early")
+			}
+			errs = append(errs, fmt.Errorf("worker %s: %w", w.name, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// AddServer registers an HTTP server to start in Run
+func (m *Manager) AddServer(name string, server *http.Server) {
+	m.servers = append(m.servers, namedServer{name: name, server: server})
+}
+
+// Go registers a background worker to start in Run. The worker must return
+// once ctx is cancelled. A worker that fails before shutdown triggers a
+// shutdown of the whole process.
+func (m *Manager) Go(name string, run func(ctx context.Context) error) {
+	m.workers = append(m.workers, worker{name: name, run: run})
+}
+
+// OnClose registers a function to release a resource after the servers and
+// workers have stopped. Closers run in reverse registration order.
+func (m *Manager) OnClose(name string, close func() error) {
+	m.closers = append(m.closers, closer{name: name, close: close})
+}
+
+// Run starts everything and blocks until ctx is cancelled, SIGINT or SIGTERM
+// arrives, or a server or worker fails. It then shuts down and returns the
+// first failure together with any shutdown errors.
+func (m *Manager) Run(ctx context.Context) error {
+	ctx, stop := signal.NotifyContext(ctx, os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	failed := make(chan error, len(m.servers)+len(m.workers))
+
+	for _, s := range m.servers {
+		go func(s namedServer) {
+			slog.Info("server listening", "server", s.name, "addr", s.server.Addr)
+			if err := s.server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+				failed <- fmt.Errorf("%s: %w", s.name, err)
+			}
+		}(s)
+	}
+
+	workerCtx, cancelWorkers := context.WithCancel(context.Background())
+	defer cancelWorkers()
+
+	var wg sync.WaitGroup
+	for _, w := range m.workers {
+		wg.Add(1)
+		go func(w worker) {
+			defer wg.Done()
+			err := w.run(workerCtx)
+			m.mu.Lock()
+			m.stopped[w.name] = err
+			m.mu.Unlock()
+			if err != nil && workerCtx.Err() == nil {
+				failed <- fmt.Errorf("worker %s: %w", w.name, err)
+			}
+		}(w)
+	}
+
+	var runErr error
+	select {
+	case <-ctx.Done():
+		slog.Info("shutdown signal received")
+	case runErr = <-failed:
+		slog.Error("shutting down after failure", "err", runErr)
+	}
+	// Restore default signal handling so a second signal kills the process
+	stop()
+	m.shuttingDown.Store(true)
+
+	if runErr == nil && m.drainDelay > 0 {
+		slog.Info("draining before shutdown", "delay", m.drainDelay.String())
+		time.Sleep(m.drainDelay)
+	}
+
+	deadline, cancel := context.WithTimeout(context.Background(), m.shutdownTimeout)
+	defer cancel()
+
+	errs := []error{runErr}
+	errs = append(errs, m.shutdownServers(deadline)...)
+
+	cancelWorkers()
+	done := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-deadline.Done():
+		errs = append(errs, fmt.Errorf("background workers did not stop: %w", deadline.Err()))
+	}
+
+	for i := len(m.closers) - 1; i >= 0; i-- {
+		c := m.closers[i]
+		if err := c.close(); err != nil {
+			errs = append(errs, fmt.Errorf("closing %s: %w", c.name, err))
+		}
+	}
+
+	slog.Info("shutdown complete")
+	return errors.Join(errs...)
+}
+
+// shutdownServers drains all servers in parallel. Servers still busy at the
+// deadline are closed forcefully.
+func (m *Manager) shutdownServers(deadline context.Context) []error {
+	var (
+		mu   sync.Mutex
+		errs []error
+		wg   sync.WaitGroup
+	)
+
+	for _, s := range m.servers {
+		wg.Add(1)
+		go func(s namedServer) {
+			defer wg.Done()
+			if err := s.server.Shutdown(deadline); err != nil {
+				s.server.Close()
+				mu.Lock()
+				errs = append(errs, fmt.Errorf("%s: shutdown: %w", s.name, err))
+				mu.Unlock()
+			}
+		}(s)
+	}
+	wg.Wait()
+
+	return errs
+}
@@ -0,0 +1,246 @@
+package lifecycle_test
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"mypremier-backend/internal/lifecycle"
+)
+
+// server returns a server on a free local port and its URL
+func server(t *testing.T, h http.Handler) (*http.Server, string) {
+	t.Helper()
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := l.Addr().String()
+	l.Close()
+	return &http.Server{Addr: addr, Handler: h}, "http://" + addr
+}
+
+// waitUp polls url until the server answers
+func waitUp(t *testing.T, url string) {
+	t.Helper()
+	for deadline := time.Now().Add(5 * time.Second); time.Now().Before(deadline); time.Sleep(5 * time.Millisecond) {
+		if resp, err := http.Get(url); err == nil {
+			resp.Body.Close()
+			return
+		}
+	}
+	t.Fatalf("%s did not come up", url)
+}
+
+// events records the order of shutdown steps
+type events struct {
+	mu   sync.Mutex
+	list []string
+}
+
+func (e *events) add(s string) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.list = append(e.list, s)
+}
+
+func (e *events) get() []string {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return slices.Clone(e.list)
+}
+
+func TestRunShutsDownInOrder(t *testing.T) {
+	var ev events
+	srv, url := server(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+	m := lifecycle.New(5 * time.Second)
+	m.AddServer("API server", srv)
+	m.Go("worker", func(ctx context.Context) error {
+		<-ctx.Done()
+		// The listener is closed before workers are cancelled
+		if resp, err := http.Get(url); err == nil {
+			resp.Body.Close()
+			ev.add("worker stopped while serving")
+		} else {
+			ev.add("worker stopped")
+		}
+		return nil
+	})
+	m.OnClose("first", func() error { ev.add("close first"); return nil })
+	m.OnClose("second", func() error { ev.add("close second"); return errors.New("flush failed") })
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() { done <- m.Run(ctx) }()
+	waitUp(t, url)
+	if m.ShuttingDown() {
+		t.Error("ShuttingDown before the signal")
+	}
+
+	cancel()
+	err := <-done
+	if err == nil || !strings.Contains(err.Error(), "closing second: flush failed") {
+		t.Errorf("Run = %v, want the closer error", err)
+	}
+	want := []string{"worker stopped", "close second", "close first"}
+	if got := ev.get(); !slices.Equal(got, want) {
+		t.Errorf("shutdown steps %v, want %v", got, want)
+	}
+	if !m.ShuttingDown() {
+		t.Error("ShuttingDown is false after shutdown")
+	}
+}
+
+func TestRunDrainDelay(t *testing.T) {
+	const delay = 200 * time.Millisecond
+	srv, url := server(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	m := lifecycle.New(5 * time.Second)
+	m.SetDrainDelay(delay)
+	m.AddServer("API server", srv)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() { done <- m.Run(ctx) }()
+	waitUp(t, url)
+
+	start := time.Now()
+	cancel()
+	for !m.ShuttingDown() {
+		time.Sleep(time.Millisecond)
+	}
+	// Requests are still served while load balancers catch up
+	resp, err := http.Get(url)
+	if err != nil {
+		t.Fatalf("request during the drain delay: %v", err)
+	}
+	resp.Body.Close()
+
+	if err := <-done; err != nil {
+		t.Errorf("Run = %v", err)
+	}
+	if elapsed := time.Since(start); elapsed < delay {
+		t.Errorf("shut down after %s, before the drain delay of %s", elapsed, delay)
+	}
+}
+
+func TestRunShutdownTimeout(t *testing.T) {
+	release := make(chan struct{})
+	defer close(release)
+	started := make(chan struct{})
+	srv, url := server(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/slow" {
+			close(started)
+			<-release
+		}
+	}))
+
+	m := lifecycle.New(100 * time.Millisecond)
+	m.AddServer("API server", srv)
+	m.Go("stubborn worker", func(ctx context.Context) error {
+		<-release
+		return nil
+	})
+	closed := false
+	m.OnClose("client", func() error { closed = true; return nil })
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() { done <- m.Run(ctx) }()
+	waitUp(t, url)
+	go http.Get(url + "/slow")
+	<-started
+
+	start := time.Now()
+	cancel()
+	err := <-done
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Errorf("shutdown took %s with a timeout of 100ms", elapsed)
+	}
+	for _, want := range []string{"API server: shutdown", "background workers did not stop"} {
+		if err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("Run = %v, want %q", err, want)
+		}
+	}
+	if !closed {
+		t.Error("closers did not run after the timeout")
+	}
+}
+
+func TestRunWorkerFails(t *testing.T) {
+	m := lifecycle.New(5 * time.Second)
+	// The drain delay is skipped when there is nothing to drain for
+	m.SetDrainDelay(time.Hour)
+	m.Go("reconciler", func(ctx context.Context) error {
+		return errors.New("lost connection")
+	})
+	stopped := make(chan struct{})
+	m.Go("exporter", func(ctx context.Context) error {
+		<-ctx.Done()
+		close(stopped)
+		return nil
+	})
+
+	done := make(chan error)
+	go func() { done <- m.Run(context.Background()) }()
+	select {
+	case err := <-done:
+		if err == nil || err.Error() != "worker reconciler: lost connection" {
+			t.Errorf("Run = %v, want the worker error", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("a failing worker did not stop the process")
+	}
+	select {
+	case <-stopped:
+	default:
+		t.Error("the other workers were not stopped")
+	}
+	// Once shutting down, stopped workers are expected
+	if err := m.CheckWorkers(context.Background()); err != nil {
+		t.Errorf("CheckWorkers after shutdown = %v", err)
+	}
+}
+
+func TestCheckWorkers(t *testing.T) {
+	m := lifecycle.New(5 * time.Second)
+	returned := make(chan struct{})
+	m.Go("reconciler", func(ctx context.Context) error {
+		// Returning nil early does not stop the process
+		close(returned)
+		return nil
+	})
+	m.Go("exporter", func(ctx context.Context) error {
+		<-ctx.Done()
+		return nil
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() { done <- m.Run(ctx) }()
+	<-returned
+
+	var err error
+	for deadline := time.Now().Add(5 * time.Second); time.Now().Before(deadline); time.Sleep(time.Millisecond) {
+		if err = m.CheckWorkers(ctx); err != nil {
+			break
+		}
+	}
+	if err == nil || err.Error() != "worker reconciler: returned early" {
+		t.Errorf("CheckWorkers = %v, want the reconciler reported", err)
+	}
+	if m.ShuttingDown() {
+		t.Error("a worker returning nil shut the process down")
+	}
+
+	cancel()
+	if err := <-done; err != nil {
+		t.Errorf("Run = %v", err)
+	}
+}
@@ -0,0 +1,75 @@
+package logging
+
+import (
+	"context"
+	"sync"
+)
+
+type infoKey struct{}
+
+// RequestInfo collects facts about a request as it passes through the
+// router and auth middleware, so the access log can report them once the
+// response is written
+type RequestInfo struct {
+	mu    sync.Mutex
+	route string
+	uid   string
+	role  string
+}
+
+// WithRequestInfo attaches an empty RequestInfo to ctx
+func WithRequestInfo(ctx context.Context) (context.Context, *RequestInfo) {
+	info := &RequestInfo{}
+	return context.WithValue(ctx, infoKey{}, info), info
+}
+
+// Info returns the RequestInfo of the request. It returns nil outside a
+// request; the setters are safe to call on nil.
+func Info(ctx context.Context) *RequestInfo {
+	info, _ := ctx.Value(infoKey{}).(*RequestInfo)
+	return info
+}
+
+// SetRoute records the matched route pattern, e.g. "GET /products/{id}"
+func (i *RequestInfo) SetRoute(route string) {
+	if i == nil {
+		return
+	}
+	i.mu.Lock()
+	i.route = route
+	i.mu.Unlock()
+}
+
+// SetActor records the authenticated user
+func (i *RequestInfo) SetActor(uid string) {
+	if i == nil {
+		return
+	}
+	i.mu.Lock()
+	i.uid = uid
+	i.mu.Unlock()
+}
+
+// SetRole records the role of the authenticated user
+func (i *RequestInfo) SetRole(role string) {
+	if i == nil {
+		return
+	}
+	i.mu.Lock()
+	i.role = role
+	i.mu.Unlock()
+}
+
+// Route returns the matched route pattern
+func (i *RequestInfo) Route() string {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	return i.route
+}
+
+// Actor returns the authenticated user UID and role
+func (i *RequestInfo) Actor() (uid, role string) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	return i.uid, i.role
+}
@@ -0,0 +1,173 @@
+package metrics
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// Registry holds every metric of the process. It is separate from the
+// client library's default registry so only our metrics are exported.
+var Registry = prometheus.NewRegistry()
+
+var factory = promauto.With(Registry)
+
+func init() {
+	Registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+	)
+}
+
+var (
+	httpRequests = factory.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "mypremier",
+		Subsystem: "http",
+		Name:      "requests_total",
+		Help:      "HTTP requests by method, route pattern and status class.",
+	}, []string{"method", "route", "status"})
+
+	httpDuration = factory.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: "mypremier",
+		Subsystem: "http",
+		Name:      "request_duration_seconds",
+		Help:      "HTTP request latency by method and route pattern.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"method", "route"})
+
+	firestoreOps = factory.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "mypremier",
+		Subsystem: "firestore",
+		Name:      "operations_total",
+		Help:      "Firestore operations by collection, method and outcome.",
+	}, []string{"collection", "method", "outcome"})
+
+	firestoreDuration = factory.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: "mypremier",
+		Subsystem: "firestore",
+		Name:      "operation_duration_seconds",
+		Help:      "Firestore operation latency by collection and method.",
+		Buckets:   []float64{.005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5},
+	}, []string{"collection", "method"})
+)
+
+// Business event counters
+var (
+	RequestsCreated = factory.NewCounter(prometheus.CounterOpts{
+		Namespace: "mypremier",
+		Name:      "info_requests_created_total",
+		Help:      "Product information requests submitted.",
+	})
+
+	SupportTicketsOpened = factory.NewCounter(prometheus.CounterOpts{
+		Namespace: "mypremier",
+		Name:      "support_tickets_opened_total",
+		Help:      "Support tickets opened.",
+	})
+
+	AuditWriteFailures = factory.NewCounter(prometheus.CounterOpts{
+		Namespace: "mypremier",
+		Name:      "audit_log_write_failures_total",
+		Help:      "Audit log entries that could not be written.",
+	})
+
+	IdempotentRequests = factory.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "mypremier",
+		Name:      "idempotent_requests_total",
+		Help:      "Retried requests answered from an Idempotency-Key record, by route and outcome (replayed, mismatch, in_progress).",
+	}, []string{"route", "outcome"})
+
+	SubmissionsQuarantined = factory.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "mypremier",
+		Name:      "submissions_quarantined_total",
+		Help:      "Public submissions held in quarantine as likely spam, by form.",
+	}, []string{"form"})
+
+	SpamScore = factory.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: "mypremier",
+		Name:      "submission_spam_score",
+		Help:      "Spam score of public submissions, by form.",
+		Buckets:   []float64{0, 1, 2, 3, 5, 8, 13, 21},
+	}, []string{"form"})
+
+	CacheRequests = factory.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "mypremier",
+		Name:      "cache_requests_total",
+		Help:      "Reads through the read-through cache, by cache and result (hit, miss).",
+	}, []string{"cache", "result"})
+
+	AccessDenied = factory.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "mypremier",
+		Name:      "access_denied_total",
+		Help:      "Requests rejected because the caller's role lacks a permission, by permission.",
+	}, []string{"permission"})
+
+	AccessLookups = factory.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "mypremier",
+		Name:      "access_lookups_total",
+		Help:      "Role and status lookups of signed-in callers, by source (claims, storage).",
+	}, []string{"source"})
+
+	ClaimsReconciled = factory.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "mypremier",
+		Name:      "claims_reconciled_total",
+		Help:      "Users whose custom claims differed from their document, by result (corrected, failed).",
+	}, []string{"result"})
+
+	RateLimited = factory.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "mypremier",
+		Name:      "rate_limited_total",
+		Help:      "Requests rejected by the rate limiter, by route.",
+	}, []string{"route"})
+)
+
+// Handler serves the registry in the Prometheus exposition format
+func Handler() http.Handler {
+	return promhttp.HandlerFor(Registry, promhttp.HandlerOpts{Registry: Registry})
+}
+
+// ObserveHTTP records a finished request. route is the matched pattern, or
+// empty when no route matched.
+func ObserveHTTP(method, route string, status int, elapsed time.Duration) {
+	if route == "" {
+		// Keep unmatched paths out of the label set
+		route = "unmatched"
+	}
+	method = methodLabel(method)
+	httpRequests.WithLabelValues(method, route, statusClass(status)).Inc()
+	httpDuration.WithLabelValues(method, route).Observe(elapsed.Seconds())
+}
+
+// methodLabel keeps client-chosen methods out of the label set: net/http
+// accepts any token as a method, so each would make new series
+func methodLabel(method string) string {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodPost, http.MethodPut, http.MethodPatch,
+		http.MethodDelete, http.MethodConnect, http.MethodOptions, http.MethodTrace:
+		return method
+	}
+	return "OTHER"
+}
+
+// ObserveFirestore records a finished Firestore operation
+func ObserveFirestore(collection, method string, err error, elapsed time.Duration) {
+	outcome := "ok"
+	if err != nil {
+		outcome = "error"
+	}
+	firestoreOps.WithLabelValues(collection, method, outcome).Inc()
+	firestoreDuration.WithLabelValues(collection, method).Observe(elapsed.Seconds())
+}
+
+// statusClass turns 404 into "4xx"
+func statusClass(status int) string {
+	if status < 100 || status > 599 {
+		return "other"
+	}
+	return strconv.Itoa(status/100) + "xx"
+}
@@ -2,53 +2,67 @@ package middleware
 
 import (
 	"context"
+	"errors"
 	"net/http"
 	"strings"
 
-	"mypremier-backend/internal/config"
+	"mypremier-backend/internal/apierror"
+	"mypremier-backend/internal/auth"
+	"mypremier-backend/internal/logging"
 )
 
 type contextKey string
 
-const userUIDKey contextKey = "userUID"
-
-func AuthRequired(next http.Handler) http.Handler {
-	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-
-		authHeader := r.Header.Get("Authorization")
-		if authHeader == "" {
-			http.Error(w, "Authorization header required", http.StatusUnauthorized)
-			return
-		}
-
-		parts := strings.Split(authHeader, " ")
-		if len(parts) != 2 || parts[0] != "Bearer" {
-			http.Error(w, "Invalid authorization format", http.StatusUnauthorized)
-			return
-		}
-
-		tokenString := parts[1]
-
-		authClient, err := config.FirebaseApp.Auth(r.Context())
-		if err != nil {
-			http.Error(w, "Firebase auth init failed", http.StatusInternalServerError)
-			return
-		}
-
-		token, err := authClient.VerifyIDToken(context.Background(), tokenString)
-		if err != nil {
-			http.Error(w, "Invalid or expired token", http.StatusUnauthorized)
-			return
-		}
-
-		// simpan UID ke context
-		ctx := context.WithValue(r.Context(), userUIDKey, token.UID)
-		next.ServeHTTP(w, r.WithContext(ctx))
-	})
+// AuthRequired verifies the bearer token of each request with verifier and
+// stores the principal in the context, where auth.FromContext finds it. A
+// nil verifier, when no identity provider is configured, rejects every
+// request.
+func AuthRequired(verifier auth.Verifier) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+
+			authHeader := r.Header.Get("Authorization")
+			if authHeader == "" {
+				apierror.Write(w, r, apierror.Unauthorized("missing_token", "Authorization header required"))
+				return
+			}
+
+			parts := strings.Split(authHeader, " ")
+			if len(parts) != 2 || parts[0] != "Bearer" {
+				apierror.Write(w, r, apierror.Unauthorized("invalid_authorization_header", "Invalid authorization format"))
+				return
+			}
+
+			tokenString := parts[1]
+
+			if verifier == nil {
+				apierror.Write(w, r, apierror.New(http.StatusServiceUnavailable, "auth_unavailable", "Authentication is not configured"))
+				return
+			}
+
+			principal, err := verifier.Verify(r.Context(), tokenString)
+			if errors.Is(err, auth.ErrInvalidToken) {
+				apierror.Write(w, r, apierror.Unauthorized("invalid_token", "Invalid or expired token"))
+				return
+			}
+			if err != nil {
+				logging.FromContext(r.Context()).Error("verifying token", "err", err)
+				apierror.Write(w, r, apierror.New(http.StatusServiceUnavailable, "auth_unavailable", "Could not verify the token"))
+				return
+			}
+
+			// simpan principal ke context
+			logging.Info(r.Context()).SetActor(principal.UID)
+			ctx := auth.NewContext(r.Context(), principal)
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
 }
 
 // helper ambil uid di handler
 func GetUserUID(ctx context.Context) string {
-	uid, _ := ctx.Value(userUIDKey).(string)
-	return uid
+	if p := auth.FromContext(ctx); p != nil {
+		return p.UID
+	}
+	return ""
 }
@@ -0,0 +1,165 @@
+package middleware_test
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"mypremier-backend/internal/auth"
+	"mypremier-backend/internal/middleware"
+	"mypremier-backend/internal/modules/user"
+
+	"cloud.google.com/go/firestore/apiv1/firestorepb"
+	firebase "firebase.google.com/go"
+	"google.golang.org/api/option"
+	"google.golang.org/grpc"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// stubVerifier accepts every token without a network round trip, so the
+// benchmarks measure the middleware and client handling only
+type stubVerifier struct{}
+
+func (stubVerifier) Verify(ctx context.Context, token string) (*auth.Principal, error) {
+	return &auth.Principal{UID: "bench-user"}, nil
+}
+
+// perRequestVerifier creates an Auth client for every token, as the
+// middleware did before clients were shared
+type perRequestVerifier struct {
+	app *firebase.App
+}
+
+func (v perRequestVerifier) Verify(ctx context.Context, token string) (*auth.Principal, error) {
+	if _, err := v.app.Auth(ctx); err != nil {
+		return nil, err
+	}
+	return stubVerifier{}.Verify(ctx, token)
+}
+
+// fakeFirestore answers every document read with an active admin, standing
+// in for Firestore on a local port
+type fakeFirestore struct {
+	firestorepb.UnimplementedFirestoreServer
+}
+
+func (fakeFirestore) BatchGetDocuments(req *firestorepb.BatchGetDocumentsRequest, stream firestorepb.Firestore_BatchGetDocumentsServer) error {
+	now := timestamppb.Now()
+	for _, name := range req.Documents {
+		err := stream.Send(&firestorepb.BatchGetDocumentsResponse{
+			Result: &firestorepb.BatchGetDocumentsResponse_Found{Found: &firestorepb.Document{
+				Name: name,
+				Fields: map[string]*firestorepb.Value{
+					"role":      {ValueType: &firestorepb.Value_StringValue{StringValue: "admin"}},
+					"is_active": {ValueType: &firestorepb.Value_BooleanValue{BooleanValue: true}},
+				},
+				CreateTime: now,
+				UpdateTime: now,
+			}},
+			ReadTime: now,
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// startFirestore serves fakeFirestore and points Firestore clients at it
+// through the emulator variable
+func startFirestore(b *testing.B) {
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		b.Fatal(err)
+	}
+	server := grpc.NewServer()
+	firestorepb.RegisterFirestoreServer(server, fakeFirestore{})
+	go server.Serve(lis)
+	b.Cleanup(server.Stop)
+	b.Setenv("FIRESTORE_EMULATOR_HOST", lis.Addr().String())
+}
+
+func newApp(b *testing.B) *firebase.App {
+	app, err := firebase.NewApp(context.Background(), &firebase.Config{ProjectID: "bench"}, option.WithoutAuthentication())
+	if err != nil {
+		b.Fatal(err)
+	}
+	return app
+}
+
+// serve sends authenticated requests to handler until the benchmark ends
+func serve(b *testing.B, handler http.Handler) {
+	b.ReportAllocs()
+	for b.Loop() {
+		req := httptest.NewRequest(http.MethodGet, "/protected", nil)
+		req.Header.Set("Authorization", "Bearer token")
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		if rec.Code != http.StatusOK {
+			b.Fatalf("status %d: %s", rec.Code, rec.Body)
+		}
+	}
+}
+
+// BenchmarkAuthRequired compares a shared Auth client with one created per
+// request. Run with -benchmem to see the allocations saved.
+func BenchmarkAuthRequired(b *testing.B) {
+	app := newApp(b)
+	ok := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})
+
+	for _, bc := range []struct {
+		name     string
+		verifier auth.Verifier
+	}{
+		{"shared_client", stubVerifier{}},
+		{"client_per_request", perRequestVerifier{app: app}},
+	} {
+		b.Run(bc.name, func(b *testing.B) {
+			serve(b, middleware.AuthRequired(bc.verifier)(ok))
+		})
+	}
+}
+
+// BenchmarkLoadUserRole runs the middleware of admin routes, reading the
+// role from Firestore through the shared repository, and through a
+// repository and Firestore client created per request as LoadUserRole did
+// before clients were shared.
+func BenchmarkLoadUserRole(b *testing.B) {
+	startFirestore(b)
+	app := newApp(b)
+
+	client, err := app.Firestore(context.Background())
+	if err != nil {
+		b.Fatal(err)
+	}
+	b.Cleanup(func() { client.Close() })
+	shared := user.NewClaimsSync(nil, user.NewFirestoreRepository(client, "users"), nil)
+
+	perRequest := func(ctx context.Context, p *auth.Principal) (middleware.Access, error) {
+		client, err := app.Firestore(ctx)
+		if err != nil {
+			return middleware.Access{}, err
+		}
+		// The old middleware never closed its clients; closing keeps the
+		// benchmark from running out of connections
+		defer client.Close()
+		return user.NewClaimsSync(nil, user.NewFirestoreRepository(client, "users"), nil).Access(ctx, p)
+	}
+
+	ok := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})
+	known := func(role string) bool { return role == "admin" }
+
+	for _, bc := range []struct {
+		name   string
+		lookup middleware.AccessLookup
+	}{
+		{"shared_client", shared.Access},
+		{"client_per_request", perRequest},
+	} {
+		b.Run(bc.name, func(b *testing.B) {
+			serve(b, middleware.AuthRequired(stubVerifier{})(middleware.LoadUserRole(bc.lookup, known)(ok)))
+		})
+	}
+}
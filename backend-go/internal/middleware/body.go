@@ -0,0 +1,18 @@
+package middleware
+
+import (
+	"net/http"
+)
+
+// MaxBodySize rejects request bodies larger than limit bytes. Handlers see
+// the failure as a decode error once the limit is exceeded.
+func MaxBodySize(limit int64) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.Body != nil {
+				r.Body = http.MaxBytesReader(w, r.Body, limit)
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
@@ -2,23 +2,141 @@ package middleware
 
 import (
 	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"mypremier-backend/internal/apierror"
+)
+
+// Methods and headers cross-origin callers may use, and the response headers
+// they may read
+const (
+	corsAllowMethods  = "GET, HEAD, POST, PUT, PATCH, DELETE"
+	corsAllowHeaders  = "Authorization, Content-Type, Idempotency-Key, X-Request-ID"
+	corsExposeHeaders = "X-Request-ID, Retry-After, X-RateLimit-Limit, X-RateLimit-Remaining, Idempotent-Replayed"
 )
 
-func CORS(next http.Handler) http.Handler {
-	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		// Set CORS headers
-		w.Header().Set("Access-Control-Allow-Origin", "*")
-		w.Header().Set("Access-Control-Allow-Methods", "GET, POST, PATCH, PUT, DELETE, OPTIONS")
-		w.Header().Set("Access-Control-Allow-Headers", "Authorization, Content-Type")
-
-		// Handle preflight OPTIONS requests
-		if r.Method == "OPTIONS" {
-			w.WriteHeader(http.StatusOK)
-			return
+// CORSPolicy says which origins may call a group of routes
+type CORSPolicy struct {
+	// AllowedOrigins lists exact origins, or "*" for any
+	AllowedOrigins []string
+	// AllowCredentials lets browsers send cookies and authorization headers.
+	// The caller's origin is echoed back instead of "*".
+	AllowCredentials bool
+	// MaxAge is how long browsers may cache a preflight answer
+	MaxAge time.Duration
+}
+
+// CORSGroup applies a policy to the paths under its prefixes
+type CORSGroup struct {
+	PathPrefixes []string
+	Policy       CORSPolicy
+}
+
+type corsPolicy struct {
+	allowAll    bool
+	origins     map[string]bool
+	credentials bool
+	maxAge      string
+}
+
+func compileCORS(p CORSPolicy) *corsPolicy {
+	c := &corsPolicy{
+		origins:     make(map[string]bool, len(p.AllowedOrigins)),
+		credentials: p.AllowCredentials,
+	}
+	for _, origin := range p.AllowedOrigins {
+		if origin == "*" {
+			c.allowAll = true
 		}
+		c.origins[strings.TrimSuffix(origin, "/")] = true
+	}
+	if p.MaxAge > 0 {
+		c.maxAge = strconv.Itoa(int(p.MaxAge / time.Second))
+	}
+	return c
+}
 
-		// Continue to next handler
-		next.ServeHTTP(w, r)
-	})
+func (c *corsPolicy) allows(origin string) bool {
+	return c.allowAll || c.origins[origin]
 }
 
+// CORS answers preflight requests and sets the CORS headers of responses
+// using the policy of the group the path belongs to, or defaultPolicy. The
+// longest matching prefix wins. Preflights from origins a policy does not
+// allow are refused with 403; OPTIONS requests that are not preflights go to
+// the router like any other method.
+func CORS(defaultPolicy CORSPolicy, groups []CORSGroup) func(http.Handler) http.Handler {
+	fallback := compileCORS(defaultPolicy)
+	type prefixPolicy struct {
+		prefix string
+		policy *corsPolicy
+	}
+	var prefixes []prefixPolicy
+	for _, g := range groups {
+		policy := compileCORS(g.Policy)
+		for _, prefix := range g.PathPrefixes {
+			prefixes = append(prefixes, prefixPolicy{prefix, policy})
+		}
+	}
+	policyFor := func(path string) *corsPolicy {
+		best, bestLen := fallback, -1
+		for _, p := range prefixes {
+			if strings.HasPrefix(path, p.prefix) && len(p.prefix) > bestLen {
+				best, bestLen = p.policy, len(p.prefix)
+			}
+		}
+		return best
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			policy := policyFor(r.URL.Path)
+			origin := r.Header.Get("Origin")
+			preflight := r.Method == http.MethodOptions && origin != "" && r.Header.Get("Access-Control-Request-Method") != ""
+
+			h := w.Header()
+			// Answers depend on the origin unless every origin gets "*"
+			wildcard := policy.allowAll && !policy.credentials
+			if !wildcard {
+				h.Add("Vary", "Origin")
+			}
+			if preflight {
+				h.Add("Vary", "Access-Control-Request-Method")
+				h.Add("Vary", "Access-Control-Request-Headers")
+			}
+
+			if origin == "" || !policy.allows(origin) {
+				if preflight {
+					apierror.Write(w, r, apierror.Forbidden("cors_origin_not_allowed", "Origin "+origin+" may not call "+r.URL.Path))
+					return
+				}
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			if wildcard {
+				h.Set("Access-Control-Allow-Origin", "*")
+			} else {
+				h.Set("Access-Control-Allow-Origin", origin)
+			}
+			if policy.credentials {
+				h.Set("Access-Control-Allow-Credentials", "true")
+			}
+
+			if !preflight {
+				h.Set("Access-Control-Expose-Headers", corsExposeHeaders)
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			h.Set("Access-Control-Allow-Methods", corsAllowMethods)
+			h.Set("Access-Control-Allow-Headers", corsAllowHeaders)
+			if policy.maxAge != "" {
+				h.Set("Access-Control-Max-Age", policy.maxAge)
+			}
+			w.WriteHeader(http.StatusNoContent)
+		})
+	}
+}
@@ -0,0 +1,188 @@
+package middleware_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"mypremier-backend/internal/apierror"
+	"mypremier-backend/internal/middleware"
+)
+
+func corsHandler() http.Handler {
+	public := middleware.CORSPolicy{AllowedOrigins: []string{"*"}, MaxAge: 10 * time.Minute}
+	groups := []middleware.CORSGroup{
+		{PathPrefixes: []string{"/admin/"}, Policy: middleware.CORSPolicy{
+			AllowedOrigins: []string{"https://admin.example.com/"}, AllowCredentials: true,
+		}},
+		// Nested in /admin/ and longer, so it wins there
+		{PathPrefixes: []string{"/admin/public/"}, Policy: middleware.CORSPolicy{
+			AllowedOrigins: []string{"https://partner.example.com"},
+		}},
+		{PathPrefixes: []string{"/support"}, Policy: middleware.CORSPolicy{
+			AllowedOrigins: []string{"https://app.example.com", "https://admin.example.com"},
+		}},
+	}
+	return middleware.CORS(public, groups)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{}`))
+	}))
+}
+
+func TestCORS(t *testing.T) {
+	tests := []struct {
+		name       string
+		method     string
+		path       string
+		origin     string
+		preflight  bool
+		wantCode   int
+		wantOrigin string
+		wantCreds  bool
+		wantVary   bool
+	}{
+		{"public, any origin", http.MethodGet, "/products", "https://shop.example.net", false, http.StatusOK, "*", false, false},
+		{"public preflight", http.MethodOptions, "/products", "https://shop.example.net", true, http.StatusNoContent, "*", false, false},
+		{"no origin", http.MethodGet, "/support", "", false, http.StatusOK, "", false, true},
+		{"allowed", http.MethodGet, "/support", "https://app.example.com", false, http.StatusOK, "https://app.example.com", false, true},
+		{"allowed preflight", http.MethodOptions, "/support", "https://app.example.com", true, http.StatusNoContent, "https://app.example.com", false, true},
+		{"disallowed", http.MethodGet, "/support", "https://evil.example.com", false, http.StatusOK, "", false, true},
+		{"disallowed preflight", http.MethodOptions, "/support", "https://evil.example.com", true, http.StatusForbidden, "", false, true},
+		{"OPTIONS without a preflight", http.MethodOptions, "/support", "https://evil.example.com", false, http.StatusOK, "", false, true},
+		{"credentials echo the origin", http.MethodGet, "/admin/products", "https://admin.example.com", false, http.StatusOK, "https://admin.example.com", true, true},
+		{"credentials preflight", http.MethodOptions, "/admin/products", "https://admin.example.com", true, http.StatusNoContent, "https://admin.example.com", true, true},
+		{"longest prefix wins", http.MethodGet, "/admin/public/x", "https://partner.example.com", false, http.StatusOK, "https://partner.example.com", false, true},
+		{"shorter prefix does not apply", http.MethodOptions, "/admin/public/x", "https://admin.example.com", true, http.StatusForbidden, "", false, true},
+		{"group origin elsewhere", http.MethodOptions, "/admin/products", "https://partner.example.com", true, http.StatusForbidden, "", false, true},
+	}
+	h := corsHandler()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, tt.path, nil)
+			if tt.origin != "" {
+				req.Header.Set("Origin", tt.origin)
+			}
+			if tt.preflight {
+				req.Header.Set("Access-Control-Request-Method", http.MethodPost)
+			}
+			rec := httptest.NewRecorder()
+			h.ServeHTTP(rec, req)
+			got := rec.Header()
+
+			if rec.Code != tt.wantCode {
+				t.Errorf("status %d, want %d: %s", rec.Code, tt.wantCode, rec.Body)
+			}
+			if got.Get("Access-Control-Allow-Origin") != tt.wantOrigin {
+				t.Errorf("Access-Control-Allow-Origin %q, want %q", got.Get("Access-Control-Allow-Origin"), tt.wantOrigin)
+			}
+			if (got.Get("Access-Control-Allow-Credentials") == "true") != tt.wantCreds {
+				t.Errorf("Access-Control-Allow-Credentials %q, want %v", got.Get("Access-Control-Allow-Credentials"), tt.wantCreds)
+			}
+			// Caches must not reuse an answer for another origin; only
+			// "*" without credentials is the same for everyone
+			if slices.Contains(got.Values("Vary"), "Origin") != tt.wantVary {
+				t.Errorf("Vary %v, want Origin: %v", got.Values("Vary"), tt.wantVary)
+			}
+			if tt.wantCode == http.StatusForbidden && !strings.Contains(rec.Body.String(), `"code":"cors_origin_not_allowed"`) {
+				t.Errorf("body %s", rec.Body)
+			}
+			if tt.preflight && tt.wantCode == http.StatusNoContent {
+				if got.Get("Access-Control-Allow-Methods") == "" || !strings.Contains(got.Get("Access-Control-Allow-Headers"), "Idempotency-Key") {
+					t.Errorf("preflight headers %v", got)
+				}
+				if !slices.Contains(got.Values("Vary"), "Access-Control-Request-Method") {
+					t.Errorf("preflight Vary %v", got.Values("Vary"))
+				}
+			}
+			if !tt.preflight && tt.wantOrigin != "" && !strings.Contains(got.Get("Access-Control-Expose-Headers"), "X-Request-ID") {
+				t.Errorf("Access-Control-Expose-Headers %q", got.Get("Access-Control-Expose-Headers"))
+			}
+		})
+	}
+}
+
+func TestCORSMaxAge(t *testing.T) {
+	req := httptest.NewRequest(http.MethodOptions, "/products", nil)
+	req.Header.Set("Origin", "https://shop.example.net")
+	req.Header.Set("Access-Control-Request-Method", http.MethodGet)
+	rec := httptest.NewRecorder()
+	corsHandler().ServeHTTP(rec, req)
+	if got := rec.Header().Get("Access-Control-Max-Age"); got != "600" {
+		t.Errorf("Access-Control-Max-Age %q, want 600", got)
+	}
+}
+
+func TestSecurityHeaders(t *testing.T) {
+	tests := []struct {
+		name    string
+		handler http.HandlerFunc
+		wantCSP string
+	}{
+		{"JSON", func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", "application/json; charset=utf-8")
+			w.Write([]byte(`{}`))
+		}, "default-src 'none'"},
+		{"error envelope", func(w http.ResponseWriter, r *http.Request) {
+			apierror.Write(w, r, apierror.NotFound("product_not_found", "Product not found"))
+		}, "default-src 'none'"},
+		{"internal error", func(w http.ResponseWriter, r *http.Request) {
+			apierror.Write(w, r, apierror.Internal())
+		}, "default-src 'none'"},
+		{"HTML with its own policy", func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", "text/html")
+			w.Header().Set("Content-Security-Policy", "script-src 'self'")
+			w.WriteHeader(http.StatusOK)
+		}, "script-src 'self'"},
+		{"plain text", func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", "text/plain")
+			w.Write([]byte("ok"))
+		}, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := middleware.SecurityHeaders(middleware.SecurityHeadersOptions{
+				HSTSMaxAge:            365 * 24 * time.Hour,
+				HSTSIncludeSubdomains: true,
+				ReferrerPolicy:        "no-referrer",
+			})(tt.handler)
+			rec := httptest.NewRecorder()
+			h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/products", nil))
+			got := rec.Header()
+
+			if csp := got.Get("Content-Security-Policy"); !strings.HasPrefix(csp, tt.wantCSP) || (tt.wantCSP == "") != (csp == "") {
+				t.Errorf("Content-Security-Policy %q, want %q", csp, tt.wantCSP)
+			}
+			want := map[string]string{
+				"Strict-Transport-Security": "max-age=31536000; includeSubDomains",
+				"X-Content-Type-Options":    "nosniff",
+				"X-Frame-Options":           "DENY",
+				"Referrer-Policy":           "no-referrer",
+			}
+			for name, value := range want {
+				if got.Get(name) != value {
+					t.Errorf("%s %q, want %q", name, got.Get(name), value)
+				}
+			}
+		})
+	}
+}
+
+func TestSecurityHeadersOnCORSRefusals(t *testing.T) {
+	// The global chain puts security headers outside CORS, so its refusals
+	// carry them too
+	h := middleware.SecurityHeaders(middleware.SecurityHeadersOptions{})(corsHandler())
+	req := httptest.NewRequest(http.MethodOptions, "/support", nil)
+	req.Header.Set("Origin", "https://evil.example.com")
+	req.Header.Set("Access-Control-Request-Method", http.MethodPost)
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	if rec.Code != http.StatusForbidden || !strings.HasPrefix(rec.Header().Get("Content-Security-Policy"), "default-src 'none'") {
+		t.Errorf("status %d, headers %v", rec.Code, rec.Header())
+	}
+	if rec.Header().Get("Strict-Transport-Security") != "" {
+		t.Errorf("HSTS set with a max-age of 0: %q", rec.Header().Get("Strict-Transport-Security"))
+	}
+}
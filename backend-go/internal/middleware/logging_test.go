@@ -0,0 +1,158 @@
+package middleware_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"mypremier-backend/internal/apierror"
+	"mypremier-backend/internal/logging"
+	"mypremier-backend/internal/middleware"
+	"mypremier-backend/internal/requestid"
+)
+
+// logLines decodes the JSON log lines in buf
+func logLines(t *testing.T, buf *bytes.Buffer) []map[string]interface{} {
+	t.Helper()
+	var lines []map[string]interface{}
+	for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+		if line == "" {
+			continue
+		}
+		var m map[string]interface{}
+		if err := json.Unmarshal([]byte(line), &m); err != nil {
+			t.Fatalf("log line %q: %v", line, err)
+		}
+		lines = append(lines, m)
+	}
+	return lines
+}
+
+func TestRequestID(t *testing.T) {
+	tests := []struct {
+		name     string
+		incoming string
+		wantKept bool
+	}{
+		{"none", "", false},
+		{"valid", "req-42_a.b:c", true},
+		{"longest allowed", strings.Repeat("a", 128), true},
+		{"oversized", strings.Repeat("a", 129), false},
+		{"spaces", "req 42", false},
+		{"log injection", "req\n{\"level\":\"ERROR\"}", false},
+		{"non-ASCII", "réq", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			var seen string
+			h := middleware.RequestID(slog.New(slog.NewJSONHandler(&buf, nil)))(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				seen = requestid.FromContext(r.Context())
+				logging.FromContext(r.Context()).Info("handled")
+			}))
+			req := httptest.NewRequest(http.MethodGet, "/products", nil)
+			if tt.incoming != "" {
+				req.Header.Set(requestid.Header, tt.incoming)
+			}
+			rec := httptest.NewRecorder()
+			h.ServeHTTP(rec, req)
+
+			id := rec.Header().Get(requestid.Header)
+			if tt.wantKept && id != tt.incoming {
+				t.Errorf("request ID %q, want the incoming %q", id, tt.incoming)
+			}
+			if !tt.wantKept && (id == tt.incoming || len(id) != 32) {
+				t.Errorf("request ID %q, want a new one", id)
+			}
+			if seen != id {
+				t.Errorf("context carries %q, response %q", seen, id)
+			}
+			if lines := logLines(t, &buf); len(lines) != 1 || lines[0]["request_id"] != id {
+				t.Errorf("log lines %v, want one carrying %s", lines, id)
+			}
+		})
+	}
+}
+
+func TestAccessLog(t *testing.T) {
+	var buf bytes.Buffer
+	h := middleware.RequestID(slog.New(slog.NewJSONHandler(&buf, nil)))(middleware.AccessLog(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			info := logging.Info(r.Context())
+			info.SetRoute("PUT /admin/products/{id}")
+			info.SetActor("alice")
+			info.SetRole("sales")
+			if r.URL.Path == "/admin/products/missing" {
+				apierror.Write(w, r, apierror.NotFound("product_not_found", "Product not found"))
+				return
+			}
+			apierror.Write(w, r, apierror.Internal())
+		})))
+
+	tests := []struct {
+		path      string
+		wantCode  int
+		wantLevel string
+	}{
+		{"/admin/products/missing", http.StatusNotFound, "INFO"},
+		{"/admin/products/broken", http.StatusInternalServerError, "ERROR"},
+	}
+	for _, tt := range tests {
+		buf.Reset()
+		req := httptest.NewRequest(http.MethodPut, tt.path, nil)
+		req.Header.Set(requestid.Header, "req-1")
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+
+		var env struct {
+			Error struct {
+				RequestID string `json:"request_id"`
+			} `json:"error"`
+		}
+		if err := json.Unmarshal(rec.Body.Bytes(), &env); err != nil || env.Error.RequestID != "req-1" {
+			t.Errorf("%s: body %s, want request_id req-1", tt.path, rec.Body)
+		}
+
+		lines := logLines(t, &buf)
+		if len(lines) != 1 {
+			t.Fatalf("%s: %d log lines, want one access line: %v", tt.path, len(lines), lines)
+		}
+		line := lines[0]
+		want := map[string]interface{}{
+			"msg":        "request",
+			"level":      tt.wantLevel,
+			"method":     "PUT",
+			"route":      "PUT /admin/products/{id}",
+			"path":       tt.path,
+			"status":     float64(tt.wantCode),
+			"actor_uid":  "alice",
+			"actor_role": "sales",
+			"request_id": "req-1",
+			"bytes":      float64(rec.Body.Len()),
+		}
+		for k, v := range want {
+			if line[k] != v {
+				t.Errorf("%s: %s = %v, want %v", tt.path, k, line[k], v)
+			}
+		}
+		if _, ok := line["latency_ms"].(float64); !ok {
+			t.Errorf("%s: latency_ms missing: %v", tt.path, line)
+		}
+	}
+}
+
+func TestAccessLogImplicitStatus(t *testing.T) {
+	var buf bytes.Buffer
+	h := middleware.RequestID(slog.New(slog.NewJSONHandler(&buf, nil)))(middleware.AccessLog(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})))
+	h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/nowhere", nil))
+
+	lines := logLines(t, &buf)
+	if len(lines) != 1 || lines[0]["status"] != float64(http.StatusOK) || lines[0]["route"] != "" || lines[0]["actor_uid"] != "" {
+		t.Errorf("log lines %v, want one 200 without route or actor", lines)
+	}
+}
@@ -0,0 +1,30 @@
+package middleware
+
+import (
+	"net/http"
+	"time"
+
+	"mypremier-backend/internal/logging"
+	"mypremier-backend/internal/metrics"
+)
+
+// Metrics records request counts and latency per route pattern
+func Metrics(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		ctx := r.Context()
+		info := logging.Info(ctx)
+		if info == nil {
+			ctx, info = logging.WithRequestInfo(ctx)
+		}
+		rec := &statusRecorder{ResponseWriter: w}
+
+		next.ServeHTTP(rec, r.WithContext(ctx))
+
+		status := rec.status
+		if status == 0 {
+			status = http.StatusOK
+		}
+		metrics.ObserveHTTP(r.Method, info.Route(), status, time.Since(start))
+	})
+}
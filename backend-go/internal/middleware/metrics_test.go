@@ -0,0 +1,58 @@
+package middleware_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"mypremier-backend/internal/logging"
+	"mypremier-backend/internal/metrics"
+	"mypremier-backend/internal/middleware"
+)
+
+// httpSeries returns the methods recorded for route by requests_total
+func httpSeries(t *testing.T, route string) map[string]float64 {
+	t.Helper()
+	families, err := metrics.Registry.Gather()
+	if err != nil {
+		t.Fatal(err)
+	}
+	methods := map[string]float64{}
+	for _, f := range families {
+		if f.GetName() != "mypremier_http_requests_total" {
+			continue
+		}
+		for _, m := range f.GetMetric() {
+			labels := map[string]string{}
+			for _, l := range m.GetLabel() {
+				labels[l.GetName()] = l.GetValue()
+			}
+			if labels["route"] == route {
+				methods[labels["method"]] += m.GetCounter().GetValue()
+			}
+		}
+	}
+	return methods
+}
+
+func TestMetricsMethodLabel(t *testing.T) {
+	const route = "/metrics-test/{id}"
+	h := middleware.Metrics(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		logging.Info(r.Context()).SetRoute(route)
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	for _, method := range []string{"GET", "POST", "FOO1", "FOO2", "get", "PROPFIND"} {
+		h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(method, "/metrics-test/1", nil))
+	}
+
+	got := httpSeries(t, route)
+	want := map[string]float64{"GET": 1, "POST": 1, "OTHER": 4}
+	if len(got) != len(want) {
+		t.Errorf("methods %v, want %v", got, want)
+	}
+	for method, n := range want {
+		if got[method] != n {
+			t.Errorf("%s: %v requests, want %v", method, got[method], n)
+		}
+	}
+}
@@ -2,65 +2,87 @@ package middleware
 
 import (
 	"context"
-	"log"
+	"errors"
 	"net/http"
 
-	"mypremier-backend/internal/modules/user"
+	"mypremier-backend/internal/apierror"
+	"mypremier-backend/internal/auth"
+	"mypremier-backend/internal/logging"
+	"mypremier-backend/internal/store"
 )
 
-type contextKey string
-
 const userRoleKey contextKey = "userRole"
 
-// LoadUserRole middleware loads the user role from Firestore and injects it into context
-// This should be used after AuthRequired middleware
-func LoadUserRole(next http.Handler) http.Handler {
-	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		// Get UID from context (set by AuthRequired middleware)
-		uid := GetUserUID(r.Context())
-		if uid == "" {
-			http.Error(w, "User UID not found in context", http.StatusInternalServerError)
-			return
-		}
+// Access is what authorisation needs to know about a signed-in user
+type Access struct {
+	Role   string
+	Active bool
+}
 
-		// Get user repository
-		repo, err := user.NewRepository()
-		if err != nil {
-			log.Printf("Error creating user repository: %v", err)
-			http.Error(w, "Internal server error", http.StatusInternalServerError)
-			return
-		}
+// AccessLookup returns the access of a principal, from the claims of its
+// token or from storage. A user without a document is reported with a
+// store.ErrNotFound error.
+type AccessLookup func(ctx context.Context, p *auth.Principal) (Access, error)
 
-		// Fetch user from Firestore
-		userData, err := repo.GetByUID(r.Context(), uid)
-		if err != nil {
-			log.Printf("Error fetching user: %v", err)
-			http.Error(w, "User not found or error fetching user", http.StatusNotFound)
-			return
-		}
+// LoadUserRole middleware loads the user role using lookup and injects it into context.
+// Deactivated users are rejected with 403, as are roles for which known
+// returns false, e.g. one removed from the registry.
+// This should be used after AuthRequired middleware
+func LoadUserRole(lookup AccessLookup, known func(role string) bool) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			// Get the principal from context (set by AuthRequired middleware)
+			principal := auth.FromContext(r.Context())
+			if principal == nil {
+				apierror.Write(w, r, apierror.New(http.StatusInternalServerError, "internal_error", "Principal not found in context"))
+				return
+			}
 
-		// Inject role into context
-		ctx := context.WithValue(r.Context(), userRoleKey, userData.Role)
-		next.ServeHTTP(w, r.WithContext(ctx))
-	})
+			access, err := lookup(r.Context(), principal)
+			if err != nil {
+				apierror.WriteError(w, r, err)
+				return
+			}
+			if !access.Active {
+				writeDisabled(w, r)
+				return
+			}
+			if !known(access.Role) {
+				logging.FromContext(r.Context()).Warn("user has an unknown role", "role", access.Role)
+				apierror.Write(w, r, apierror.Forbidden("unknown_role", "Your role is not recognised"))
+				return
+			}
+
+			// Inject role into context
+			logging.Info(r.Context()).SetRole(access.Role)
+			ctx := context.WithValue(r.Context(), userRoleKey, access.Role)
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
 }
 
-// RequireRole checks if the user has the required role
-// This should be used after LoadUserRole middleware
-func RequireRole(requiredRole string) func(http.Handler) http.Handler {
+// RequireActive rejects deactivated users on routes that need no role.
+// Signed-in users without a document, such as customers, pass.
+// This should be used after AuthRequired middleware
+func RequireActive(lookup AccessLookup) func(http.Handler) http.Handler {
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-			role, ok := r.Context().Value(userRoleKey).(string)
-			if !ok || role == "" {
-				http.Error(w, "User role not found in context", http.StatusInternalServerError)
+			principal := auth.FromContext(r.Context())
+			if principal == nil {
+				apierror.Write(w, r, apierror.New(http.StatusInternalServerError, "internal_error", "Principal not found in context"))
 				return
 			}
 
-			if role != requiredRole {
-				http.Error(w, "Insufficient permissions", http.StatusForbidden)
+			access, err := lookup(r.Context(), principal)
+			switch {
+			case errors.Is(err, store.ErrNotFound):
+			case err != nil:
+				apierror.WriteError(w, r, err)
+				return
+			case !access.Active:
+				writeDisabled(w, r)
 				return
 			}
-
 			next.ServeHTTP(w, r)
 		})
 	}
@@ -72,3 +94,7 @@ func GetUserRole(ctx context.Context) string {
 	return role
 }
 
+// writeDisabled answers 403 to a deactivated user
+func writeDisabled(w http.ResponseWriter, r *http.Request) {
+	apierror.Write(w, r, apierror.Forbidden("account_disabled", "Your account has been deactivated"))
+}
@@ -0,0 +1,69 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+
+	"mypremier-backend/internal/apierror"
+	"mypremier-backend/internal/auth"
+	"mypremier-backend/internal/logging"
+	"mypremier-backend/internal/store"
+)
+
+// RevocationLookup returns when the sessions of the user with the given UID
+// were last revoked, or zero if never
+type RevocationLookup func(ctx context.Context, uid string) (time.Time, error)
+
+// SessionPolicy is the extra checking of tokens on sensitive routes
+type SessionPolicy struct {
+	// RevokedAt, if set, refuses tokens issued before the last revocation
+	// of their user's sessions
+	RevokedAt RevocationLookup
+	// MaxAge refuses tokens whose user signed in (auth_time) longer ago;
+	// 0 accepts any age
+	MaxAge time.Duration
+}
+
+// CheckSession applies policy to the principal loaded by AuthRequired.
+// Revoked and too old tokens get 401, telling the client to sign in again.
+// This should be used after AuthRequired middleware
+func CheckSession(policy SessionPolicy) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		if policy.RevokedAt == nil && policy.MaxAge <= 0 {
+			return next
+		}
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			principal := auth.FromContext(r.Context())
+			if principal == nil {
+				apierror.Write(w, r, apierror.New(http.StatusInternalServerError, "internal_error", "Principal not found in context"))
+				return
+			}
+
+			if policy.MaxAge > 0 && time.Since(principal.AuthTime) > policy.MaxAge {
+				apierror.Write(w, r, apierror.Unauthorized("reauthentication_required", "Sign in again to continue"))
+				return
+			}
+
+			if policy.RevokedAt != nil {
+				revokedAt, err := policy.RevokedAt(r.Context(), principal.UID)
+				switch {
+				case errors.Is(err, store.ErrNotFound):
+					// The account was deleted
+					apierror.Write(w, r, apierror.Unauthorized("token_revoked", "Your session has ended, sign in again"))
+					return
+				case err != nil:
+					logging.FromContext(r.Context()).Error("checking token revocation", "err", err)
+					apierror.Write(w, r, apierror.New(http.StatusServiceUnavailable, "auth_unavailable", "Could not check your session"))
+					return
+				case principal.IssuedAt.Before(revokedAt):
+					apierror.Write(w, r, apierror.Unauthorized("token_revoked", "Your session has ended, sign in again"))
+					return
+				}
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
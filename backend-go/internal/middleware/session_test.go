@@ -0,0 +1,77 @@
+package middleware_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"mypremier-backend/internal/auth"
+	"mypremier-backend/internal/middleware"
+	"mypremier-backend/internal/store"
+)
+
+func TestCheckSession(t *testing.T) {
+	now := time.Now()
+	revokedAt := func(at time.Time, err error) middleware.RevocationLookup {
+		return func(ctx context.Context, uid string) (time.Time, error) {
+			return at, err
+		}
+	}
+
+	tests := []struct {
+		name     string
+		policy   middleware.SessionPolicy
+		token    *auth.Principal
+		wantCode int
+		wantErr  string
+	}{
+		{"no policy", middleware.SessionPolicy{}, &auth.Principal{UID: "alice"}, http.StatusOK, ""},
+		{"never revoked", middleware.SessionPolicy{RevokedAt: revokedAt(time.Time{}, nil)},
+			&auth.Principal{UID: "alice", IssuedAt: now}, http.StatusOK, ""},
+		{"issued after the revocation", middleware.SessionPolicy{RevokedAt: revokedAt(now.Add(-time.Minute), nil)},
+			&auth.Principal{UID: "alice", IssuedAt: now}, http.StatusOK, ""},
+		{"issued before the revocation", middleware.SessionPolicy{RevokedAt: revokedAt(now.Add(-time.Minute), nil)},
+			&auth.Principal{UID: "alice", IssuedAt: now.Add(-time.Hour)}, http.StatusUnauthorized, "token_revoked"},
+		{"deleted account", middleware.SessionPolicy{RevokedAt: revokedAt(time.Time{}, store.NotFound("user", "alice"))},
+			&auth.Principal{UID: "alice", IssuedAt: now}, http.StatusUnauthorized, "token_revoked"},
+		{"lookup fails", middleware.SessionPolicy{RevokedAt: revokedAt(time.Time{}, errors.New("auth unavailable"))},
+			&auth.Principal{UID: "alice", IssuedAt: now}, http.StatusServiceUnavailable, "auth_unavailable"},
+		{"recent sign-in", middleware.SessionPolicy{MaxAge: time.Hour},
+			&auth.Principal{UID: "alice", AuthTime: now.Add(-time.Minute)}, http.StatusOK, ""},
+		{"sign-in too old", middleware.SessionPolicy{MaxAge: time.Hour},
+			&auth.Principal{UID: "alice", AuthTime: now.Add(-2 * time.Hour)}, http.StatusUnauthorized, "reauthentication_required"},
+		{"sign-in too old, not revoked", middleware.SessionPolicy{RevokedAt: revokedAt(time.Time{}, nil), MaxAge: time.Hour},
+			&auth.Principal{UID: "alice", IssuedAt: now, AuthTime: now.Add(-2 * time.Hour)}, http.StatusUnauthorized, "reauthentication_required"},
+		{"no principal", middleware.SessionPolicy{MaxAge: time.Hour}, nil, http.StatusInternalServerError, "internal_error"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			called := false
+			handler := middleware.CheckSession(tt.policy)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				called = true
+			}))
+
+			req := httptest.NewRequest(http.MethodGet, "/admin/users", nil)
+			if tt.token != nil {
+				req = req.WithContext(auth.NewContext(req.Context(), tt.token))
+			}
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+
+			if rec.Code != tt.wantCode {
+				t.Fatalf("status %d, want %d: %s", rec.Code, tt.wantCode, rec.Body)
+			}
+			if called != (tt.wantCode == http.StatusOK) {
+				t.Errorf("next called = %v", called)
+			}
+			if tt.wantErr != "" && !strings.Contains(rec.Body.String(), `"code":"`+tt.wantErr+`"`) {
+				t.Errorf("body %s, want code %s", rec.Body, tt.wantErr)
+			}
+		})
+	}
+}
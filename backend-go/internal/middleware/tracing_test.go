@@ -0,0 +1,115 @@
+package middleware_test
+
+import (
+	"bytes"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"mypremier-backend/internal/apierror"
+	"mypremier-backend/internal/logging"
+	"mypremier-backend/internal/middleware"
+	"mypremier-backend/internal/router"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+// recordSpans installs a tracer provider keeping finished spans in memory
+func recordSpans(t *testing.T) *tracetest.InMemoryExporter {
+	exporter := tracetest.NewInMemoryExporter()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter))
+	prevProvider, prevPropagator := otel.GetTracerProvider(), otel.GetTextMapPropagator()
+	otel.SetTracerProvider(provider)
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+	t.Cleanup(func() {
+		provider.Shutdown(t.Context())
+		otel.SetTracerProvider(prevProvider)
+		otel.SetTextMapPropagator(prevPropagator)
+	})
+	return exporter
+}
+
+func TestTracing(t *testing.T) {
+	exporter := recordSpans(t)
+	var logs bytes.Buffer
+	rt := router.New([]router.Route{
+		{Method: http.MethodGet, Path: "/products/{id}", Handler: func(w http.ResponseWriter, r *http.Request) {
+			switch r.PathValue("id") {
+			case "broken":
+				apierror.Write(w, r, apierror.Internal())
+			case "missing":
+				apierror.Write(w, r, apierror.NotFound("product_not_found", "Product not found"))
+			default:
+				logging.FromContext(r.Context()).Info("handled")
+			}
+		}},
+	})
+	h := middleware.Tracing(middleware.RequestID(slog.New(slog.NewJSONHandler(&logs, nil)))(rt))
+
+	const parent = "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"
+	tests := []struct {
+		name        string
+		path        string
+		traceparent string
+		wantName    string
+		wantStatus  codes.Code
+	}{
+		{"ok", "/products/p1", "", "GET /products/{id}", codes.Unset},
+		{"client error", "/products/missing", "", "GET /products/{id}", codes.Unset},
+		{"server error", "/products/broken", "", "GET /products/{id}", codes.Error},
+		{"unmatched", "/nowhere", "", "GET", codes.Unset},
+		{"incoming trace", "/products/p1", parent, "GET /products/{id}", codes.Unset},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			exporter.Reset()
+			logs.Reset()
+			req := httptest.NewRequest(http.MethodGet, tt.path, nil)
+			if tt.traceparent != "" {
+				req.Header.Set("traceparent", tt.traceparent)
+			}
+			h.ServeHTTP(httptest.NewRecorder(), req)
+
+			spans := exporter.GetSpans()
+			if len(spans) != 1 {
+				t.Fatalf("%d spans, want 1", len(spans))
+			}
+			span := spans[0]
+			if span.Name != tt.wantName {
+				t.Errorf("span name %q, want %q", span.Name, tt.wantName)
+			}
+			if span.Status.Code != tt.wantStatus {
+				t.Errorf("span status %v, want %v", span.Status.Code, tt.wantStatus)
+			}
+			traceID := span.SpanContext.TraceID().String()
+			if tt.traceparent != "" && traceID != "4bf92f3577b34da6a3ce929d0e0e4736" {
+				t.Errorf("trace ID %s, want the incoming trace continued", traceID)
+			}
+
+			// Log lines join the trace, and the span the request ID
+			lines := logLines(t, &logs)
+			if tt.path == "/products/p1" && len(lines) == 0 {
+				t.Error("the handler's log line is missing")
+			}
+			for _, line := range lines {
+				if line["trace_id"] != traceID || line["span_id"] != span.SpanContext.SpanID().String() {
+					t.Errorf("log line %v, want trace %s", line, traceID)
+				}
+			}
+			var requestID string
+			for _, attr := range span.Attributes {
+				if attr.Key == "request.id" {
+					requestID = attr.Value.AsString()
+				}
+			}
+			if requestID == "" {
+				t.Errorf("span attributes %v lack request.id", span.Attributes)
+			}
+		})
+	}
+}
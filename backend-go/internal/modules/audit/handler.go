@@ -3,45 +3,39 @@ package audit
 import (
 	"context"
 	"encoding/json"
-	"log"
 	"net/http"
 
+	"mypremier-backend/internal/apierror"
+	"mypremier-backend/internal/logging"
+	"mypremier-backend/internal/metrics"
 	"mypremier-backend/internal/middleware"
+	"mypremier-backend/internal/tracing"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
 )
 
 type Handler struct {
-	repo *Repository
+	repo Repository
 }
 
-func NewHandler() (*Handler, error) {
-	repo, err := NewRepository()
-	if err != nil {
-		return nil, err
-	}
-
+func NewHandler(repo Repository) *Handler {
 	return &Handler{
 		repo: repo,
-	}, nil
+	}
 }
 
 func (h *Handler) GetAuditLogs(w http.ResponseWriter, r *http.Request) {
-	if r.Method != http.MethodGet {
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
-		return
-	}
-
 	logs, err := h.repo.GetAll(r.Context())
 	if err != nil {
-		log.Printf("Error fetching audit logs: %v", err)
-		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		apierror.WriteError(w, r, err)
 		return
 	}
 
 	w.Header().Set("Content-Type", "application/json")
 	if err := json.NewEncoder(w).Encode(logs); err != nil {
-		log.Printf("Error encoding response: %v", err)
-		http.Error(w, "Internal server error", http.StatusInternalServerError)
-		return
+		logging.FromContext(r.Context()).Error("encoding response", "err", err)
 	}
 }
 
@@ -53,6 +47,13 @@ func (h *Handler) LogAction(ctx context.Context, action, entity, entityID string
 		return nil
 	}
 
+	ctx, span := tracing.Tracer().Start(ctx, "audit.write", trace.WithAttributes(
+		attribute.String("audit.action", action),
+		attribute.String("audit.entity", entity),
+		attribute.String("audit.entity_id", entityID),
+	))
+	defer span.End()
+
 	logEntry := AuditLog{
 		ActorUID: actorUID,
 		Action:   action,
@@ -60,6 +61,13 @@ func (h *Handler) LogAction(ctx context.Context, action, entity, entityID string
 		EntityID: entityID,
 	}
 
-	return h.repo.Create(ctx, logEntry)
-}
+	if err := h.repo.Create(ctx, logEntry); err != nil {
+		metrics.AuditWriteFailures.Inc()
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		logging.FromContext(ctx).Error("writing audit log", "action", action, "entity", entity, "entity_id", entityID, "err", err)
+		return err
+	}
 
+	return nil
+}
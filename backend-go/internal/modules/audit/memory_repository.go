@@ -0,0 +1,35 @@
+package audit
+
+import (
+	"context"
+
+	"mypremier-backend/internal/store"
+)
+
+// MemoryRepository stores audit logs in memory. It is intended for local
+// development and tests.
+type MemoryRepository struct {
+	docs *store.Collection[AuditLog]
+}
+
+func NewMemoryRepository() *MemoryRepository {
+	return &MemoryRepository{
+		docs: store.NewCollection[AuditLog](),
+	}
+}
+
+func (r *MemoryRepository) Create(ctx context.Context, log AuditLog) error {
+	id := store.NewID()
+	log.ID = id
+	log.CreatedAt = r.docs.ServerTimestamp()
+	r.docs.Set(id, log)
+
+	return nil
+}
+
+func (r *MemoryRepository) GetAll(ctx context.Context) ([]AuditLog, error) {
+	// Newest first, matching OrderBy("created_at", firestore.Desc)
+	return r.docs.Query(nil, func(a, b AuditLog) bool {
+		return a.CreatedAt.After(b.CreatedAt)
+	}), nil
+}
@@ -5,38 +5,45 @@ import (
 	"fmt"
 
 	"cloud.google.com/go/firestore"
-	"mypremier-backend/internal/config"
+	"google.golang.org/api/iterator"
+	"mypremier-backend/internal/logging"
+	"mypremier-backend/internal/store"
 )
 
-type Repository struct {
+// Repository is the storage contract for audit logs
+type Repository interface {
+	Create(ctx context.Context, log AuditLog) error
+	GetAll(ctx context.Context) ([]AuditLog, error)
+}
+
+// FirestoreRepository stores audit logs in Firestore
+type FirestoreRepository struct {
 	client     *firestore.Client
 	collection string
 }
 
-func NewRepository() (*Repository, error) {
-	client, err := config.FirebaseApp.Firestore(context.Background())
-	if err != nil {
-		return nil, fmt.Errorf("failed to get firestore client: %w", err)
-	}
-
-	return &Repository{
+func NewFirestoreRepository(client *firestore.Client, collection string) *FirestoreRepository {
+	return &FirestoreRepository{
 		client:     client,
-		collection: "audit_logs",
-	}, nil
+		collection: collection,
+	}
 }
 
-func (r *Repository) Create(ctx context.Context, log AuditLog) error {
+func (r *FirestoreRepository) Create(ctx context.Context, log AuditLog) (err error) {
+	ctx, done := store.Track(ctx, r.collection, "create")
+	defer func() { done(err) }()
+
 	docRef := r.client.Collection(r.collection).NewDoc()
 
 	logData := map[string]interface{}{
-		"actor_uid": log.ActorUID,
-		"action":    log.Action,
-		"entity":    log.Entity,
-		"entity_id": log.EntityID,
+		"actor_uid":  log.ActorUID,
+		"action":     log.Action,
+		"entity":     log.Entity,
+		"entity_id":  log.EntityID,
 		"created_at": firestore.ServerTimestamp,
 	}
 
-	_, err := docRef.Set(ctx, logData)
+	_, err = docRef.Set(ctx, logData)
 	if err != nil {
 		return fmt.Errorf("failed to create audit log: %w", err)
 	}
@@ -44,7 +51,10 @@ func (r *Repository) Create(ctx context.Context, log AuditLog) error {
 	return nil
 }
 
-func (r *Repository) GetAll(ctx context.Context) ([]AuditLog, error) {
+func (r *FirestoreRepository) GetAll(ctx context.Context) (_ []AuditLog, err error) {
+	ctx, done := store.Track(ctx, r.collection, "list")
+	defer func() { done(err) }()
+
 	iter := r.client.Collection(r.collection).
 		OrderBy("created_at", firestore.Desc).
 		Documents(ctx)
@@ -53,12 +63,16 @@ func (r *Repository) GetAll(ctx context.Context) ([]AuditLog, error) {
 	var logs []AuditLog
 	for {
 		doc, err := iter.Next()
-		if err != nil {
+		if err == iterator.Done {
 			break
 		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to list audit logs: %w", err)
+		}
 
 		var log AuditLog
 		if err := doc.DataTo(&log); err != nil {
+			logging.FromContext(ctx).Warn("skipping malformed document", "collection", r.collection, "id", doc.Ref.ID, "err", err)
 			continue
 		}
 
@@ -72,4 +86,3 @@ func (r *Repository) GetAll(ctx context.Context) ([]AuditLog, error) {
 
 	return logs, nil
 }
-
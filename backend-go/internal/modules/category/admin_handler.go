@@ -2,75 +2,44 @@ package category
 
 import (
 	"encoding/json"
-	"log"
 	"net/http"
-	"strings"
 
+	"mypremier-backend/internal/apierror"
+	"mypremier-backend/internal/decode"
+	"mypremier-backend/internal/logging"
 	"mypremier-backend/internal/modules/audit"
 )
 
 type AdminHandler struct {
-	repo        *Repository
+	repo         Repository
 	auditHandler *audit.Handler
 }
 
-func NewAdminHandler() (*AdminHandler, error) {
-	repo, err := NewRepository()
-	if err != nil {
-		return nil, err
-	}
-
-	auditHandler, err := audit.NewHandler()
-	if err != nil {
-		return nil, err
-	}
-
+func NewAdminHandler(repo Repository, auditHandler *audit.Handler) *AdminHandler {
 	return &AdminHandler{
-		repo:        repo,
+		repo:         repo,
 		auditHandler: auditHandler,
-	}, nil
+	}
 }
 
 func (h *AdminHandler) GetCategories(w http.ResponseWriter, r *http.Request) {
-	if r.Method != http.MethodGet {
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
-		return
-	}
-
 	categories, err := h.repo.GetAll(r.Context())
 	if err != nil {
-		log.Printf("Error fetching categories: %v", err)
-		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		apierror.WriteError(w, r, err)
 		return
 	}
 
 	w.Header().Set("Content-Type", "application/json")
 	if err := json.NewEncoder(w).Encode(categories); err != nil {
-		log.Printf("Error encoding response: %v", err)
-		http.Error(w, "Internal server error", http.StatusInternalServerError)
-		return
+		logging.FromContext(r.Context()).Error("encoding response", "err", err)
 	}
 }
 
 func (h *AdminHandler) CreateCategory(w http.ResponseWriter, r *http.Request) {
-	if r.Method != http.MethodPost {
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
-		return
-	}
-
-	var input struct {
-		Name     string `json:"name"`
-		ParentID string `json:"parent_id"`
-	}
-
-	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
-		log.Printf("Error decoding request body: %v", err)
-		http.Error(w, "Invalid request body", http.StatusBadRequest)
-		return
-	}
+	var input CategoryInput
 
-	if input.Name == "" {
-		http.Error(w, "Name is required", http.StatusBadRequest)
+	if err := decode.JSON(r, &input); err != nil {
+		apierror.Write(w, r, err)
 		return
 	}
 
@@ -81,8 +50,7 @@ func (h *AdminHandler) CreateCategory(w http.ResponseWriter, r *http.Request) {
 
 	id, err := h.repo.Create(r.Context(), category)
 	if err != nil {
-		log.Printf("Error creating category: %v", err)
-		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		apierror.WriteError(w, r, err)
 		return
 	}
 
@@ -91,44 +59,23 @@ func (h *AdminHandler) CreateCategory(w http.ResponseWriter, r *http.Request) {
 
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusCreated)
-	response := map[string]interface{}{
-		"id":        id,
-		"name":      category.Name,
-		"parent_id": category.ParentID,
+	response := CategoryResponse{
+		ID:       id,
+		Name:     category.Name,
+		ParentID: category.ParentID,
 	}
 	if err := json.NewEncoder(w).Encode(response); err != nil {
-		log.Printf("Error encoding response: %v", err)
-		http.Error(w, "Internal server error", http.StatusInternalServerError)
-		return
+		logging.FromContext(r.Context()).Error("encoding response", "err", err)
 	}
 }
 
 func (h *AdminHandler) UpdateCategory(w http.ResponseWriter, r *http.Request) {
-	if r.Method != http.MethodPut {
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
-		return
-	}
+	id := r.PathValue("id")
 
-	// Extract category ID from path /admin/categories/{id}
-	path := strings.TrimPrefix(r.URL.Path, "/admin/categories/")
-	if path == "" || path == r.URL.Path {
-		http.Error(w, "Category ID is required", http.StatusBadRequest)
-		return
-	}
-
-	var input struct {
-		Name     string `json:"name"`
-		ParentID string `json:"parent_id"`
-	}
-
-	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
-		log.Printf("Error decoding request body: %v", err)
-		http.Error(w, "Invalid request body", http.StatusBadRequest)
-		return
-	}
+	var input CategoryInput
 
-	if input.Name == "" {
-		http.Error(w, "Name is required", http.StatusBadRequest)
+	if err := decode.JSON(r, &input); err != nil {
+		apierror.Write(w, r, err)
 		return
 	}
 
@@ -137,70 +84,45 @@ func (h *AdminHandler) UpdateCategory(w http.ResponseWriter, r *http.Request) {
 		ParentID: input.ParentID,
 	}
 
-	err := h.repo.Update(r.Context(), path, category)
+	err := h.repo.Update(r.Context(), id, category)
 	if err != nil {
-		log.Printf("Error updating category: %v", err)
-		if strings.Contains(err.Error(), "not found") {
-			http.Error(w, "Category not found", http.StatusNotFound)
-			return
-		}
-		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		apierror.WriteError(w, r, err)
 		return
 	}
 
 	// Log audit action
-	_ = h.auditHandler.LogAction(r.Context(), "updated", "category", path)
+	_ = h.auditHandler.LogAction(r.Context(), "updated", "category", id)
 
 	w.Header().Set("Content-Type", "application/json")
-	response := map[string]interface{}{
-		"id":        path,
-		"name":      category.Name,
-		"parent_id": category.ParentID,
+	response := CategoryResponse{
+		ID:       id,
+		Name:     category.Name,
+		ParentID: category.ParentID,
 	}
 	if err := json.NewEncoder(w).Encode(response); err != nil {
-		log.Printf("Error encoding response: %v", err)
-		http.Error(w, "Internal server error", http.StatusInternalServerError)
-		return
+		logging.FromContext(r.Context()).Error("encoding response", "err", err)
 	}
 }
 
 func (h *AdminHandler) DeleteCategory(w http.ResponseWriter, r *http.Request) {
-	if r.Method != http.MethodDelete {
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
-		return
-	}
+	id := r.PathValue("id")
 
-	// Extract category ID from path /admin/categories/{id}
-	path := strings.TrimPrefix(r.URL.Path, "/admin/categories/")
-	if path == "" || path == r.URL.Path {
-		http.Error(w, "Category ID is required", http.StatusBadRequest)
-		return
-	}
-
-	err := h.repo.Delete(r.Context(), path)
+	err := h.repo.Delete(r.Context(), id)
 	if err != nil {
-		log.Printf("Error deleting category: %v", err)
-		if strings.Contains(err.Error(), "not found") {
-			http.Error(w, "Category not found", http.StatusNotFound)
-			return
-		}
-		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		apierror.WriteError(w, r, err)
 		return
 	}
 
 	// Log audit action
-	_ = h.auditHandler.LogAction(r.Context(), "deleted", "category", path)
+	_ = h.auditHandler.LogAction(r.Context(), "deleted", "category", id)
 
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusOK)
-	response := map[string]interface{}{
-		"id":      path,
-		"message": "Category deleted successfully",
+	response := DeleteCategoryResponse{
+		ID:      id,
+		Message: "Category deleted successfully",
 	}
 	if err := json.NewEncoder(w).Encode(response); err != nil {
-		log.Printf("Error encoding response: %v", err)
-		http.Error(w, "Internal server error", http.StatusInternalServerError)
-		return
+		logging.FromContext(r.Context()).Error("encoding response", "err", err)
 	}
 }
-
@@ -1,44 +1,35 @@
 package category
 
 import (
-	"encoding/json"
-	"log"
 	"net/http"
+	"time"
+
+	"mypremier-backend/internal/apierror"
+	"mypremier-backend/internal/httpcache"
 )
 
 type Handler struct {
-	repo *Repository
+	repo  Repository
+	cache httpcache.Policy
 }
 
-func NewHandler() (*Handler, error) {
-	repo, err := NewRepository()
-	if err != nil {
-		return nil, err
-	}
-
+func NewHandler(repo Repository, cache httpcache.Policy) *Handler {
 	return &Handler{
-		repo: repo,
-	}, nil
+		repo:  repo,
+		cache: cache,
+	}
 }
 
 func (h *Handler) GetCategories(w http.ResponseWriter, r *http.Request) {
-	if r.Method != http.MethodGet {
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
-		return
-	}
-
 	categories, err := h.repo.GetAll(r.Context())
 	if err != nil {
-		log.Printf("Error fetching categories: %v", err)
-		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		apierror.WriteError(w, r, err)
 		return
 	}
 
-	w.Header().Set("Content-Type", "application/json")
-	if err := json.NewEncoder(w).Encode(categories); err != nil {
-		log.Printf("Error encoding response: %v", err)
-		http.Error(w, "Internal server error", http.StatusInternalServerError)
-		return
+	var lastModified time.Time
+	for _, c := range categories {
+		lastModified = httpcache.Latest(lastModified, c.CreatedAt, c.UpdatedAt)
 	}
+	h.cache.WriteJSON(w, r, categories, httpcache.Validators{LastModified: lastModified, IgnoreModifiedSince: true})
 }
-
@@ -0,0 +1,63 @@
+package category
+
+import (
+	"context"
+
+	"mypremier-backend/internal/store"
+)
+
+// MemoryRepository stores categories in memory. It is intended for local
+// development and tests.
+type MemoryRepository struct {
+	docs *store.Collection[Category]
+}
+
+func NewMemoryRepository() *MemoryRepository {
+	return &MemoryRepository{
+		docs: store.NewCollection[Category](),
+	}
+}
+
+func (r *MemoryRepository) GetAll(ctx context.Context) ([]Category, error) {
+	return r.docs.Query(nil, nil), nil
+}
+
+func (r *MemoryRepository) GetByID(ctx context.Context, id string) (*Category, error) {
+	category, ok := r.docs.Get(id)
+	if !ok {
+		return nil, store.NotFound("category", id)
+	}
+
+	return &category, nil
+}
+
+func (r *MemoryRepository) Create(ctx context.Context, category Category) (string, error) {
+	id := store.NewID()
+	category.ID = id
+	category.CreatedAt = r.docs.ServerTimestamp()
+	category.UpdatedAt = category.CreatedAt
+	r.docs.Set(id, category)
+
+	return id, nil
+}
+
+func (r *MemoryRepository) Update(ctx context.Context, id string, category Category) error {
+	ok := r.docs.Update(id, func(doc *Category) {
+		doc.Name = category.Name
+		doc.ParentID = category.ParentID
+		doc.UpdatedAt = r.docs.ServerTimestamp()
+	})
+	if !ok {
+		return store.NotFound("category", id)
+	}
+
+	return nil
+}
+
+func (r *MemoryRepository) Delete(ctx context.Context, id string) error {
+	if !r.docs.Delete(id) {
+		return store.NotFound("category", id)
+	}
+
+	return nil
+}
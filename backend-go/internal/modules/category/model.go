@@ -8,5 +8,25 @@ type Category struct {
 	Name      string    `firestore:"name" json:"name"`
 	ParentID  string    `firestore:"parent_id" json:"parent_id"`
 	CreatedAt time.Time `firestore:"created_at" json:"created_at"`
+	// UpdatedAt is missing on categories not edited since it was introduced
+	UpdatedAt time.Time `firestore:"updated_at" json:"updated_at,omitzero"`
 }
 
+// CategoryInput is the body of create and update requests
+type CategoryInput struct {
+	Name     string `json:"name" validate:"required,max=100"`
+	ParentID string `json:"parent_id" validate:"max=128"`
+}
+
+// CategoryResponse is returned after a create or update
+type CategoryResponse struct {
+	ID       string `json:"id"`
+	Name     string `json:"name"`
+	ParentID string `json:"parent_id"`
+}
+
+// DeleteCategoryResponse is returned after a delete
+type DeleteCategoryResponse struct {
+	ID      string `json:"id"`
+	Message string `json:"message"`
+}
@@ -5,39 +5,53 @@ import (
 	"fmt"
 
 	"cloud.google.com/go/firestore"
-	"mypremier-backend/internal/config"
+	"google.golang.org/api/iterator"
+	"mypremier-backend/internal/logging"
+	"mypremier-backend/internal/store"
 )
 
-type Repository struct {
+// Repository is the storage contract for categories
+type Repository interface {
+	GetAll(ctx context.Context) ([]Category, error)
+	GetByID(ctx context.Context, id string) (*Category, error)
+	Create(ctx context.Context, category Category) (string, error)
+	Update(ctx context.Context, id string, category Category) error
+	Delete(ctx context.Context, id string) error
+}
+
+// FirestoreRepository stores categories in Firestore
+type FirestoreRepository struct {
 	client     *firestore.Client
 	collection string
 }
 
-func NewRepository() (*Repository, error) {
-	client, err := config.FirebaseApp.Firestore(context.Background())
-	if err != nil {
-		return nil, fmt.Errorf("failed to get firestore client: %w", err)
-	}
-
-	return &Repository{
+func NewFirestoreRepository(client *firestore.Client, collection string) *FirestoreRepository {
+	return &FirestoreRepository{
 		client:     client,
-		collection: "categories",
-	}, nil
+		collection: collection,
+	}
 }
 
-func (r *Repository) GetAll(ctx context.Context) ([]Category, error) {
+func (r *FirestoreRepository) GetAll(ctx context.Context) (_ []Category, err error) {
+	ctx, done := store.Track(ctx, r.collection, "list")
+	defer func() { done(err) }()
+
 	iter := r.client.Collection(r.collection).Documents(ctx)
 	defer iter.Stop()
 
 	var categories []Category
 	for {
 		doc, err := iter.Next()
-		if err != nil {
+		if err == iterator.Done {
 			break
 		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to list categories: %w", err)
+		}
 
 		var category Category
 		if err := doc.DataTo(&category); err != nil {
+			logging.FromContext(ctx).Warn("skipping malformed document", "collection", r.collection, "id", doc.Ref.ID, "err", err)
 			continue
 		}
 
@@ -52,10 +66,13 @@ func (r *Repository) GetAll(ctx context.Context) ([]Category, error) {
 	return categories, nil
 }
 
-func (r *Repository) GetByID(ctx context.Context, id string) (*Category, error) {
+func (r *FirestoreRepository) GetByID(ctx context.Context, id string) (_ *Category, err error) {
+	ctx, done := store.Track(ctx, r.collection, "get")
+	defer func() { done(err) }()
+
 	doc, err := r.client.Collection(r.collection).Doc(id).Get(ctx)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get category: %w", err)
+		return nil, store.FromFirestore(err, "category", id, "get")
 	}
 
 	var category Category
@@ -71,16 +88,20 @@ func (r *Repository) GetByID(ctx context.Context, id string) (*Category, error)
 	return &category, nil
 }
 
-func (r *Repository) Create(ctx context.Context, category Category) (string, error) {
+func (r *FirestoreRepository) Create(ctx context.Context, category Category) (_ string, err error) {
+	ctx, done := store.Track(ctx, r.collection, "create")
+	defer func() { done(err) }()
+
 	docRef := r.client.Collection(r.collection).NewDoc()
 
 	categoryData := map[string]interface{}{
 		"name":       category.Name,
 		"parent_id":  category.ParentID,
 		"created_at": firestore.ServerTimestamp,
+		"updated_at": firestore.ServerTimestamp,
 	}
 
-	_, err := docRef.Set(ctx, categoryData)
+	_, err = docRef.Set(ctx, categoryData)
 	if err != nil {
 		return "", fmt.Errorf("failed to create category: %w", err)
 	}
@@ -88,29 +109,35 @@ func (r *Repository) Create(ctx context.Context, category Category) (string, err
 	return docRef.ID, nil
 }
 
-func (r *Repository) Update(ctx context.Context, id string, category Category) error {
+func (r *FirestoreRepository) Update(ctx context.Context, id string, category Category) (err error) {
+	ctx, done := store.Track(ctx, r.collection, "update")
+	defer func() { done(err) }()
+
 	docRef := r.client.Collection(r.collection).Doc(id)
 
 	updates := []firestore.Update{
 		{Path: "name", Value: category.Name},
 		{Path: "parent_id", Value: category.ParentID},
+		{Path: "updated_at", Value: firestore.ServerTimestamp},
 	}
 
-	_, err := docRef.Update(ctx, updates)
+	_, err = docRef.Update(ctx, updates)
 	if err != nil {
-		return fmt.Errorf("failed to update category: %w", err)
+		return store.FromFirestore(err, "category", id, "update")
 	}
 
 	return nil
 }
 
-func (r *Repository) Delete(ctx context.Context, id string) error {
+func (r *FirestoreRepository) Delete(ctx context.Context, id string) (err error) {
+	ctx, done := store.Track(ctx, r.collection, "delete")
+	defer func() { done(err) }()
+
 	docRef := r.client.Collection(r.collection).Doc(id)
-	_, err := docRef.Delete(ctx)
+	_, err = docRef.Delete(ctx, firestore.Exists)
 	if err != nil {
-		return fmt.Errorf("failed to delete category: %w", err)
+		return store.FromFirestore(err, "category", id, "delete")
 	}
 
 	return nil
 }
-
@@ -1,72 +1,47 @@
 package product
 
 import (
-	"encoding/json"
-	"log"
 	"net/http"
-	"strings"
+	"time"
+
+	"mypremier-backend/internal/apierror"
+	"mypremier-backend/internal/httpcache"
 )
 
 type Handler struct {
-	repo *Repository
+	repo  Repository
+	cache httpcache.Policy
 }
 
-func NewHandler() (*Handler, error) {
-	repo, err := NewRepository()
-	if err != nil {
-		return nil, err
-	}
-
+func NewHandler(repo Repository, cache httpcache.Policy) *Handler {
 	return &Handler{
-		repo: repo,
-	}, nil
+		repo:  repo,
+		cache: cache,
+	}
 }
 
 func (h *Handler) GetProducts(w http.ResponseWriter, r *http.Request) {
-	if r.Method != http.MethodGet {
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
-		return
-	}
-
 	products, err := h.repo.GetAll(r.Context())
 	if err != nil {
-		log.Printf("Error fetching products: %v", err)
-		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		apierror.WriteError(w, r, err)
 		return
 	}
 
-	w.Header().Set("Content-Type", "application/json")
-	if err := json.NewEncoder(w).Encode(products); err != nil {
-		log.Printf("Error encoding response: %v", err)
-		http.Error(w, "Internal server error", http.StatusInternalServerError)
-		return
+	var lastModified time.Time
+	for _, p := range products {
+		lastModified = httpcache.Latest(lastModified, p.UpdatedAt)
 	}
+	h.cache.WriteJSON(w, r, products, httpcache.Validators{LastModified: lastModified, IgnoreModifiedSince: true})
 }
 
 func (h *Handler) GetProduct(w http.ResponseWriter, r *http.Request) {
-	if r.Method != http.MethodGet {
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
-		return
-	}
-
-	// Extract product ID from path /products/{id}
-	path := strings.TrimPrefix(r.URL.Path, "/products/")
-	if path == "" || path == r.URL.Path {
-		http.Error(w, "Product ID is required", http.StatusBadRequest)
-		return
-	}
+	id := r.PathValue("id")
 
-	product, err := h.repo.GetByID(r.Context(), path)
+	product, err := h.repo.GetByID(r.Context(), id)
 	if err != nil {
-		log.Printf("Error fetching product: %v", err)
-		http.Error(w, "Product not found", http.StatusNotFound)
+		apierror.WriteError(w, r, err)
 		return
 	}
 
-	w.Header().Set("Content-Type", "application/json")
-	if err := json.NewEncoder(w).Encode(product); err != nil {
-		log.Printf("Error encoding response: %v", err)
-		http.Error(w, "Internal server error", http.StatusInternalServerError)
-		return
-	}
+	h.cache.WriteJSON(w, r, product, httpcache.Validators{LastModified: product.UpdatedAt})
 }
@@ -0,0 +1,64 @@
+package product
+
+import (
+	"context"
+
+	"mypremier-backend/internal/store"
+)
+
+// MemoryRepository stores products in memory. It is intended for local
+// development and tests.
+type MemoryRepository struct {
+	docs *store.Collection[Product]
+}
+
+func NewMemoryRepository() *MemoryRepository {
+	return &MemoryRepository{
+		docs: store.NewCollection[Product](),
+	}
+}
+
+func (r *MemoryRepository) GetAll(ctx context.Context) ([]Product, error) {
+	return r.docs.Query(nil, nil), nil
+}
+
+func (r *MemoryRepository) GetByID(ctx context.Context, id string) (*Product, error) {
+	product, ok := r.docs.Get(id)
+	if !ok {
+		return nil, store.NotFound("product", id)
+	}
+
+	return &product, nil
+}
+
+func (r *MemoryRepository) Create(ctx context.Context, product Product) (string, error) {
+	id := store.NewID()
+	product.ID = id
+	product.Images = append([]string(nil), product.Images...)
+	product.UpdatedAt = r.docs.ServerTimestamp()
+	r.docs.Set(id, product)
+
+	return id, nil
+}
+
+func (r *MemoryRepository) Update(ctx context.Context, id string, product Product) error {
+	ok := r.docs.Update(id, func(doc *Product) {
+		product.ID = doc.ID
+		product.Images = append([]string(nil), product.Images...)
+		product.UpdatedAt = r.docs.ServerTimestamp()
+		*doc = product
+	})
+	if !ok {
+		return store.NotFound("product", id)
+	}
+
+	return nil
+}
+
+func (r *MemoryRepository) Delete(ctx context.Context, id string) error {
+	if !r.docs.Delete(id) {
+		return store.NotFound("product", id)
+	}
+
+	return nil
+}
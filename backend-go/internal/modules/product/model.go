@@ -1,5 +1,7 @@
 package product
 
+import "time"
+
 // Product represents a product in Firestore
 type Product struct {
 	ID                 string   `firestore:"id" json:"id"`
@@ -12,4 +14,31 @@ type Product struct {
 	Images             []string `firestore:"images" json:"images"`
 	DatasheetURL       string   `firestore:"datasheet_url" json:"datasheet_url"`
 	IsActive           bool     `firestore:"is_active" json:"is_active"`
+	// UpdatedAt is missing on products not edited since it was introduced
+	UpdatedAt time.Time `firestore:"updated_at" json:"updated_at,omitzero"`
+}
+
+// ProductInput is the body of create and update requests
+type ProductInput struct {
+	Name               string   `json:"name" validate:"required,max=200"`
+	Brand              string   `json:"brand" validate:"max=100"`
+	Series             string   `json:"series" validate:"max=100"`
+	CategoryID         string   `json:"category_id" validate:"max=128"`
+	TechnicalOverview  string   `json:"technical_overview" validate:"max=10000"`
+	TypicalApplication string   `json:"typical_application" validate:"max=10000"`
+	Images             []string `json:"images" validate:"max=20,dive,required,url,max=2048"`
+	DatasheetURL       string   `json:"datasheet_url" validate:"url,max=2048"`
+	IsActive           bool     `json:"is_active"`
+}
+
+// ProductResponse is returned after a create or update
+type ProductResponse struct {
+	ID string `json:"id"`
+	ProductInput
+}
+
+// DeleteProductResponse is returned after a delete
+type DeleteProductResponse struct {
+	ID      string `json:"id"`
+	Message string `json:"message"`
 }
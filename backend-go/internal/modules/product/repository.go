@@ -4,43 +4,55 @@ import (
 	"context"
 	"fmt"
 
-	"mypremier-backend/internal/config"
+	"mypremier-backend/internal/logging"
+	"mypremier-backend/internal/store"
 
 	"cloud.google.com/go/firestore"
-	"google.golang.org/grpc/codes"
-	"google.golang.org/grpc/status"
+	"google.golang.org/api/iterator"
 )
 
-type Repository struct {
+// Repository is the storage contract for products
+type Repository interface {
+	GetAll(ctx context.Context) ([]Product, error)
+	GetByID(ctx context.Context, id string) (*Product, error)
+	Create(ctx context.Context, product Product) (string, error)
+	Update(ctx context.Context, id string, product Product) error
+	Delete(ctx context.Context, id string) error
+}
+
+// FirestoreRepository stores products in Firestore
+type FirestoreRepository struct {
 	client     *firestore.Client
 	collection string
 }
 
-func NewRepository() (*Repository, error) {
-	client, err := config.FirebaseApp.Firestore(context.Background())
-	if err != nil {
-		return nil, fmt.Errorf("failed to get firestore client: %w", err)
-	}
-
-	return &Repository{
+func NewFirestoreRepository(client *firestore.Client, collection string) *FirestoreRepository {
+	return &FirestoreRepository{
 		client:     client,
-		collection: "products",
-	}, nil
+		collection: collection,
+	}
 }
 
-func (r *Repository) GetAll(ctx context.Context) ([]Product, error) {
+func (r *FirestoreRepository) GetAll(ctx context.Context) (_ []Product, err error) {
+	ctx, done := store.Track(ctx, r.collection, "list")
+	defer func() { done(err) }()
+
 	iter := r.client.Collection(r.collection).Documents(ctx)
 	defer iter.Stop()
 
 	var products []Product
 	for {
 		doc, err := iter.Next()
-		if err != nil {
+		if err == iterator.Done {
 			break
 		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to list products: %w", err)
+		}
 
 		var product Product
 		if err := doc.DataTo(&product); err != nil {
+			logging.FromContext(ctx).Warn("skipping malformed document", "collection", r.collection, "id", doc.Ref.ID, "err", err)
 			continue
 		}
 
@@ -55,10 +67,13 @@ func (r *Repository) GetAll(ctx context.Context) ([]Product, error) {
 	return products, nil
 }
 
-func (r *Repository) GetByID(ctx context.Context, id string) (*Product, error) {
+func (r *FirestoreRepository) GetByID(ctx context.Context, id string) (_ *Product, err error) {
+	ctx, done := store.Track(ctx, r.collection, "get")
+	defer func() { done(err) }()
+
 	doc, err := r.client.Collection(r.collection).Doc(id).Get(ctx)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get product: %w", err)
+		return nil, store.FromFirestore(err, "product", id, "get")
 	}
 
 	var product Product
@@ -74,7 +89,10 @@ func (r *Repository) GetByID(ctx context.Context, id string) (*Product, error) {
 	return &product, nil
 }
 
-func (r *Repository) Create(ctx context.Context, product Product) (string, error) {
+func (r *FirestoreRepository) Create(ctx context.Context, product Product) (_ string, err error) {
+	ctx, done := store.Track(ctx, r.collection, "create")
+	defer func() { done(err) }()
+
 	docRef := r.client.Collection(r.collection).NewDoc()
 
 	productData := map[string]interface{}{
@@ -91,7 +109,7 @@ func (r *Repository) Create(ctx context.Context, product Product) (string, error
 		"updated_at":          firestore.ServerTimestamp,
 	}
 
-	_, err := docRef.Set(ctx, productData)
+	_, err = docRef.Set(ctx, productData)
 	if err != nil {
 		return "", fmt.Errorf("failed to create product: %w", err)
 	}
@@ -99,7 +117,10 @@ func (r *Repository) Create(ctx context.Context, product Product) (string, error
 	return docRef.ID, nil
 }
 
-func (r *Repository) Update(ctx context.Context, id string, product Product) error {
+func (r *FirestoreRepository) Update(ctx context.Context, id string, product Product) (err error) {
+	ctx, done := store.Track(ctx, r.collection, "update")
+	defer func() { done(err) }()
+
 	docRef := r.client.Collection(r.collection).Doc(id)
 
 	updates := []firestore.Update{
@@ -115,26 +136,23 @@ func (r *Repository) Update(ctx context.Context, id string, product Product) err
 		{Path: "updated_at", Value: firestore.ServerTimestamp},
 	}
 
-	_, err := docRef.Update(ctx, updates)
+	_, err = docRef.Update(ctx, updates)
 	if err != nil {
-		if status.Code(err) == codes.NotFound {
-			return fmt.Errorf("product not found")
-		}
-		return fmt.Errorf("failed to update product: %w", err)
+		return store.FromFirestore(err, "product", id, "update")
 	}
 
 	return nil
 }
 
-func (r *Repository) Delete(ctx context.Context, id string) error {
+func (r *FirestoreRepository) Delete(ctx context.Context, id string) (err error) {
+	ctx, done := store.Track(ctx, r.collection, "delete")
+	defer func() { done(err) }()
+
 	docRef := r.client.Collection(r.collection).Doc(id)
-	_, err := docRef.Delete(ctx)
+	_, err = docRef.Delete(ctx, firestore.Exists)
 	if err != nil {
-		if status.Code(err) == codes.NotFound {
-			return fmt.Errorf("product not found")
-		}
-		return fmt.Errorf("failed to delete product: %w", err)
+		return store.FromFirestore(err, "product", id, "delete")
 	}
 
 	return nil
-}
\ No newline at end of file
+}
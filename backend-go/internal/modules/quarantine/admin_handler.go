@@ -0,0 +1,133 @@
+package quarantine
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+
+	"mypremier-backend/internal/antispam"
+	"mypremier-backend/internal/apierror"
+	"mypremier-backend/internal/logging"
+	"mypremier-backend/internal/modules/audit"
+)
+
+// Releaser stores released data in the main collection of a form and
+// returns the new document ID
+type Releaser func(ctx context.Context, data map[string]interface{}) (string, error)
+
+type AdminHandler struct {
+	repo         Repository
+	releasers    map[string]Releaser
+	auditHandler *audit.Handler
+}
+
+// NewAdminHandler releases items through releasers, keyed by form
+func NewAdminHandler(repo Repository, releasers map[string]Releaser, auditHandler *audit.Handler) *AdminHandler {
+	return &AdminHandler{
+		repo:         repo,
+		releasers:    releasers,
+		auditHandler: auditHandler,
+	}
+}
+
+// Hold returns the function the anti-spam screener quarantines into
+func Hold(repo Repository) antispam.QuarantineFunc {
+	return func(ctx context.Context, s antispam.Submission, v antispam.Verdict) (string, error) {
+		return repo.Create(ctx, Item{
+			Form:      s.Form,
+			Data:      s.Data,
+			Score:     v.Score,
+			Reasons:   v.Reasons,
+			ClientIP:  s.ClientIP,
+			UserAgent: s.UserAgent,
+		})
+	}
+}
+
+func (h *AdminHandler) GetItems(w http.ResponseWriter, r *http.Request) {
+	items, err := h.repo.GetAll(r.Context())
+	if err != nil {
+		apierror.WriteError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(items); err != nil {
+		logging.FromContext(r.Context()).Error("encoding response", "err", err)
+	}
+}
+
+// ReleaseItem moves an item to the main collection of its form, as if it had
+// passed screening. The item leaves quarantine before it is released, so a
+// second release of the same item finds nothing to release.
+func (h *AdminHandler) ReleaseItem(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+
+	item, err := h.repo.Take(r.Context(), id)
+	if err != nil {
+		apierror.WriteError(w, r, err)
+		return
+	}
+
+	release, ok := h.releasers[item.Form]
+	if !ok {
+		logging.FromContext(r.Context()).Error("no releaser for quarantined form", "form", item.Form, "id", id)
+		h.restore(r.Context(), *item)
+		apierror.Write(w, r, apierror.Internal())
+		return
+	}
+
+	data := item.Data
+	if data == nil {
+		data = make(map[string]interface{})
+	}
+	createdID, err := release(r.Context(), data)
+	if err != nil {
+		h.restore(r.Context(), *item)
+		apierror.WriteError(w, r, err)
+		return
+	}
+
+	// Log audit action
+	_ = h.auditHandler.LogAction(r.Context(), "released", "quarantine", id)
+
+	w.Header().Set("Content-Type", "application/json")
+	response := ReleaseResponse{
+		ID:        id,
+		Form:      item.Form,
+		CreatedID: createdID,
+	}
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		logging.FromContext(r.Context()).Error("encoding response", "err", err)
+	}
+}
+
+// restore puts back an item whose release failed, even if the client has
+// gone away
+func (h *AdminHandler) restore(ctx context.Context, item Item) {
+	if err := h.repo.Restore(context.WithoutCancel(ctx), item); err != nil {
+		logging.FromContext(ctx).Error("restoring quarantined submission after a failed release", "id", item.ID, "err", err)
+	}
+}
+
+func (h *AdminHandler) DiscardItem(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+
+	err := h.repo.Delete(r.Context(), id)
+	if err != nil {
+		apierror.WriteError(w, r, err)
+		return
+	}
+
+	// Log audit action
+	_ = h.auditHandler.LogAction(r.Context(), "discarded", "quarantine", id)
+
+	w.Header().Set("Content-Type", "application/json")
+	response := DiscardResponse{
+		ID:      id,
+		Message: "Submission discarded",
+	}
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		logging.FromContext(r.Context()).Error("encoding response", "err", err)
+	}
+}
@@ -0,0 +1,102 @@
+package quarantine_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"mypremier-backend/internal/modules/audit"
+	"mypremier-backend/internal/modules/quarantine"
+)
+
+func release(h *quarantine.AdminHandler, id string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodPost, "/admin/quarantine/"+id+"/release", nil)
+	req.SetPathValue("id", id)
+	rec := httptest.NewRecorder()
+	h.ReleaseItem(rec, req)
+	return rec
+}
+
+// TestReleaseOnce releases one item from many requests at once: the
+// submission reaches its collection once and the other requests get 404
+func TestReleaseOnce(t *testing.T) {
+	repo := quarantine.NewMemoryRepository()
+	id, err := repo.Create(context.Background(), quarantine.Item{Form: "request", Data: map[string]interface{}{"name": "Alice"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var created atomic.Int32
+	h := quarantine.NewAdminHandler(repo, map[string]quarantine.Releaser{
+		"request": func(ctx context.Context, data map[string]interface{}) (string, error) {
+			created.Add(1)
+			return "r1", nil
+		},
+	}, audit.NewHandler(audit.NewMemoryRepository()))
+
+	const n = 10
+	codes := make(chan int, n)
+	var wg sync.WaitGroup
+	for range n {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			codes <- release(h, id).Code
+		}()
+	}
+	wg.Wait()
+	close(codes)
+
+	count := make(map[int]int)
+	for code := range codes {
+		count[code]++
+	}
+	if count[http.StatusOK] != 1 || count[http.StatusNotFound] != n-1 {
+		t.Errorf("statuses %v, want one 200 and %d 404", count, n-1)
+	}
+	if got := created.Load(); got != 1 {
+		t.Errorf("released %d times, want once", got)
+	}
+	if rec := release(h, id); rec.Code != http.StatusNotFound {
+		t.Errorf("release after release: status %d, want 404", rec.Code)
+	}
+}
+
+// TestReleaseFailureRestores keeps an item whose release failed in
+// quarantine, so it can be released again
+func TestReleaseFailureRestores(t *testing.T) {
+	repo := quarantine.NewMemoryRepository()
+	ctx := context.Background()
+	id, err := repo.Create(ctx, quarantine.Item{Form: "support"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	fail := true
+	h := quarantine.NewAdminHandler(repo, map[string]quarantine.Releaser{
+		"support": func(ctx context.Context, data map[string]interface{}) (string, error) {
+			if fail {
+				return "", errors.New("unavailable")
+			}
+			return "s1", nil
+		},
+	}, audit.NewHandler(audit.NewMemoryRepository()))
+
+	if rec := release(h, id); rec.Code != http.StatusInternalServerError {
+		t.Fatalf("failed release: status %d, want 500", rec.Code)
+	}
+	item, err := repo.GetByID(ctx, id)
+	if err != nil {
+		t.Fatalf("item gone after a failed release: %v", err)
+	}
+	if item.Form != "support" || item.CreatedAt.IsZero() {
+		t.Errorf("restored item %+v", item)
+	}
+
+	fail = false
+	if rec := release(h, id); rec.Code != http.StatusOK {
+		t.Errorf("second release: status %d, want 200: %s", rec.Code, rec.Body)
+	}
+}
@@ -0,0 +1,67 @@
+package quarantine
+
+import (
+	"context"
+
+	"mypremier-backend/internal/store"
+)
+
+// MemoryRepository stores quarantined submissions in memory. It is intended
+// for local development and tests.
+type MemoryRepository struct {
+	docs *store.Collection[Item]
+}
+
+func NewMemoryRepository() *MemoryRepository {
+	return &MemoryRepository{
+		docs: store.NewCollection[Item](),
+	}
+}
+
+func (r *MemoryRepository) Create(ctx context.Context, item Item) (string, error) {
+	id := store.NewID()
+	item.ID = id
+	item.CreatedAt = r.docs.ServerTimestamp()
+	r.docs.Set(id, item)
+
+	return id, nil
+}
+
+func (r *MemoryRepository) GetAll(ctx context.Context) ([]Item, error) {
+	// Newest first, matching OrderBy("created_at", firestore.Desc)
+	return r.docs.Query(nil, func(a, b Item) bool {
+		return a.CreatedAt.After(b.CreatedAt)
+	}), nil
+}
+
+func (r *MemoryRepository) GetByID(ctx context.Context, id string) (*Item, error) {
+	item, ok := r.docs.Get(id)
+	if !ok {
+		return nil, store.NotFound("quarantined submission", id)
+	}
+
+	return &item, nil
+}
+
+func (r *MemoryRepository) Take(ctx context.Context, id string) (*Item, error) {
+	item, ok := r.docs.Take(id)
+	if !ok {
+		return nil, store.NotFound("quarantined submission", id)
+	}
+
+	return &item, nil
+}
+
+func (r *MemoryRepository) Restore(ctx context.Context, item Item) error {
+	r.docs.Set(item.ID, item)
+
+	return nil
+}
+
+func (r *MemoryRepository) Delete(ctx context.Context, id string) error {
+	if !r.docs.Delete(id) {
+		return store.NotFound("quarantined submission", id)
+	}
+
+	return nil
+}
@@ -0,0 +1,34 @@
+package quarantine
+
+import (
+	"time"
+
+	"mypremier-backend/internal/antispam"
+)
+
+// Item is a public submission held back as likely spam
+type Item struct {
+	ID string `firestore:"id" json:"id"`
+	// Form is the form the submission was sent to: "request" or "support"
+	Form      string                 `firestore:"form" json:"form"`
+	Data      map[string]interface{} `firestore:"data" json:"data"`
+	Score     float64                `firestore:"score" json:"score"`
+	Reasons   []antispam.Reason      `firestore:"reasons" json:"reasons"`
+	ClientIP  string                 `firestore:"client_ip" json:"client_ip"`
+	UserAgent string                 `firestore:"user_agent" json:"user_agent"`
+	CreatedAt time.Time              `firestore:"created_at" json:"created_at"`
+}
+
+// ReleaseResponse is returned after releasing an item
+type ReleaseResponse struct {
+	ID string `json:"id"`
+	// Form and CreatedID locate the submission in its main collection
+	Form      string `json:"form"`
+	CreatedID string `json:"created_id"`
+}
+
+// DiscardResponse is returned after discarding an item
+type DiscardResponse struct {
+	ID      string `json:"id"`
+	Message string `json:"message"`
+}
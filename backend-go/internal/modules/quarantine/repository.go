@@ -0,0 +1,183 @@
+package quarantine
+
+import (
+	"context"
+	"fmt"
+
+	"cloud.google.com/go/firestore"
+	"google.golang.org/api/iterator"
+	"mypremier-backend/internal/logging"
+	"mypremier-backend/internal/store"
+)
+
+// Repository is the storage contract for quarantined submissions
+type Repository interface {
+	Create(ctx context.Context, item Item) (string, error)
+	GetAll(ctx context.Context) ([]Item, error)
+	GetByID(ctx context.Context, id string) (*Item, error)
+	// Take removes an item and returns it, so that only one caller can
+	// release it. Restore puts a taken item back.
+	Take(ctx context.Context, id string) (*Item, error)
+	Restore(ctx context.Context, item Item) error
+	Delete(ctx context.Context, id string) error
+}
+
+// FirestoreRepository stores quarantined submissions in Firestore
+type FirestoreRepository struct {
+	client     *firestore.Client
+	collection string
+}
+
+func NewFirestoreRepository(client *firestore.Client, collection string) *FirestoreRepository {
+	return &FirestoreRepository{
+		client:     client,
+		collection: collection,
+	}
+}
+
+func (r *FirestoreRepository) Create(ctx context.Context, item Item) (_ string, err error) {
+	ctx, done := store.Track(ctx, r.collection, "create")
+	defer func() { done(err) }()
+
+	docRef := r.client.Collection(r.collection).NewDoc()
+
+	itemData := map[string]interface{}{
+		"form":       item.Form,
+		"data":       item.Data,
+		"score":      item.Score,
+		"reasons":    item.Reasons,
+		"client_ip":  item.ClientIP,
+		"user_agent": item.UserAgent,
+		"created_at": firestore.ServerTimestamp,
+	}
+
+	_, err = docRef.Set(ctx, itemData)
+	if err != nil {
+		return "", fmt.Errorf("failed to quarantine submission: %w", err)
+	}
+
+	return docRef.ID, nil
+}
+
+func (r *FirestoreRepository) GetAll(ctx context.Context) (_ []Item, err error) {
+	ctx, done := store.Track(ctx, r.collection, "list")
+	defer func() { done(err) }()
+
+	iter := r.client.Collection(r.collection).
+		OrderBy("created_at", firestore.Desc).
+		Documents(ctx)
+	defer iter.Stop()
+
+	var items []Item
+	for {
+		doc, err := iter.Next()
+		if err == iterator.Done {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to list quarantined submissions: %w", err)
+		}
+
+		var item Item
+		if err := doc.DataTo(&item); err != nil {
+			logging.FromContext(ctx).Warn("skipping malformed document", "collection", r.collection, "id", doc.Ref.ID, "err", err)
+			continue
+		}
+
+		// Set ID from document ID if not present in data
+		if item.ID == "" {
+			item.ID = doc.Ref.ID
+		}
+
+		items = append(items, item)
+	}
+
+	return items, nil
+}
+
+func (r *FirestoreRepository) GetByID(ctx context.Context, id string) (_ *Item, err error) {
+	ctx, done := store.Track(ctx, r.collection, "get")
+	defer func() { done(err) }()
+
+	doc, err := r.client.Collection(r.collection).Doc(id).Get(ctx)
+	if err != nil {
+		return nil, store.FromFirestore(err, "quarantined submission", id, "get")
+	}
+
+	var item Item
+	if err := doc.DataTo(&item); err != nil {
+		return nil, fmt.Errorf("failed to parse quarantined submission: %w", err)
+	}
+
+	// Set ID from document ID if not present in data
+	if item.ID == "" {
+		item.ID = doc.Ref.ID
+	}
+
+	return &item, nil
+}
+
+func (r *FirestoreRepository) Take(ctx context.Context, id string) (_ *Item, err error) {
+	ctx, done := store.Track(ctx, r.collection, "take")
+	defer func() { done(err) }()
+
+	docRef := r.client.Collection(r.collection).Doc(id)
+	var item Item
+	err = r.client.RunTransaction(ctx, func(ctx context.Context, tx *firestore.Transaction) error {
+		doc, err := tx.Get(docRef)
+		if err != nil {
+			return err
+		}
+		if err := doc.DataTo(&item); err != nil {
+			return fmt.Errorf("failed to parse quarantined submission: %w", err)
+		}
+		return tx.Delete(docRef)
+	})
+	if err != nil {
+		return nil, store.FromFirestore(err, "quarantined submission", id, "take")
+	}
+
+	// Set ID from document ID if not present in data
+	if item.ID == "" {
+		item.ID = id
+	}
+
+	return &item, nil
+}
+
+func (r *FirestoreRepository) Restore(ctx context.Context, item Item) (err error) {
+	ctx, done := store.Track(ctx, r.collection, "restore")
+	defer func() { done(err) }()
+
+	docRef := r.client.Collection(r.collection).Doc(item.ID)
+
+	itemData := map[string]interface{}{
+		"form":       item.Form,
+		"data":       item.Data,
+		"score":      item.Score,
+		"reasons":    item.Reasons,
+		"client_ip":  item.ClientIP,
+		"user_agent": item.UserAgent,
+		"created_at": item.CreatedAt,
+	}
+
+	_, err = docRef.Create(ctx, itemData)
+	if err != nil {
+		return store.FromFirestore(err, "quarantined submission", item.ID, "restore")
+	}
+
+	return nil
+}
+
+func (r *FirestoreRepository) Delete(ctx context.Context, id string) (err error) {
+	ctx, done := store.Track(ctx, r.collection, "delete")
+	defer func() { done(err) }()
+
+	docRef := r.client.Collection(r.collection).Doc(id)
+	_, err = docRef.Delete(ctx, firestore.Exists)
+	if err != nil {
+		return store.FromFirestore(err, "quarantined submission", id, "delete")
+	}
+
+	return nil
+}
@@ -2,35 +2,33 @@ package request
 
 import (
 	"encoding/json"
-	"log"
 	"net/http"
+
+	"mypremier-backend/internal/antispam"
+	"mypremier-backend/internal/apierror"
+	"mypremier-backend/internal/decode"
+	"mypremier-backend/internal/logging"
+	"mypremier-backend/internal/metrics"
 )
 
 type Handler struct {
-	repo *Repository
+	repo     Repository
+	screener *antispam.Screener
 }
 
-func NewHandler() (*Handler, error) {
-	repo, err := NewRepository()
-	if err != nil {
-		return nil, err
-	}
-
+// NewHandler screens submissions with screener; a nil screener accepts
+// everything
+func NewHandler(repo Repository, screener *antispam.Screener) *Handler {
 	return &Handler{
-		repo: repo,
-	}, nil
+		repo:     repo,
+		screener: screener,
+	}
 }
 
 func (h *Handler) CreateRequest(w http.ResponseWriter, r *http.Request) {
-	if r.Method != http.MethodPost {
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
-		return
-	}
-
 	var input CreateRequestInput
-	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
-		log.Printf("Error decoding request body: %v", err)
-		http.Error(w, "Invalid request body", http.StatusBadRequest)
+	if err := decode.JSON(r, &input); err != nil {
+		apierror.Write(w, r, err)
 		return
 	}
 
@@ -39,11 +37,26 @@ func (h *Handler) CreateRequest(w http.ResponseWriter, r *http.Request) {
 		input.Data = make(map[string]interface{})
 	}
 
-	id, err := h.repo.Create(r.Context(), input.Data)
-	if err != nil {
-		log.Printf("Error creating request: %v", err)
-		http.Error(w, "Internal server error", http.StatusInternalServerError)
-		return
+	// Flagged submissions get the same answer as accepted ones, so a bot
+	// cannot tell it was caught
+	var id string
+	if h.screener != nil {
+		heldID, err := h.screener.Screen(r, antispam.FormRequest, input.Data, input.Antispam)
+		if err != nil {
+			apierror.WriteError(w, r, err)
+			return
+		}
+		id = heldID
+	}
+
+	if id == "" {
+		var err error
+		id, err = h.repo.Create(r.Context(), input.Data)
+		if err != nil {
+			apierror.WriteError(w, r, err)
+			return
+		}
+		metrics.RequestsCreated.Inc()
 	}
 
 	response := CreateRequestResponse{
@@ -54,9 +67,6 @@ func (h *Handler) CreateRequest(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusCreated)
 	if err := json.NewEncoder(w).Encode(response); err != nil {
-		log.Printf("Error encoding response: %v", err)
-		http.Error(w, "Internal server error", http.StatusInternalServerError)
-		return
+		logging.FromContext(r.Context()).Error("encoding response", "err", err)
 	}
 }
-
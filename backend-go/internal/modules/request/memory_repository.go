@@ -0,0 +1,38 @@
+package request
+
+import (
+	"context"
+
+	"mypremier-backend/internal/store"
+)
+
+// MemoryRepository stores request info submissions in memory. It is intended
+// for local development and tests.
+type MemoryRepository struct {
+	docs *store.Collection[Request]
+}
+
+func NewMemoryRepository() *MemoryRepository {
+	return &MemoryRepository{
+		docs: store.NewCollection[Request](),
+	}
+}
+
+func (r *MemoryRepository) Create(ctx context.Context, data map[string]interface{}) (string, error) {
+	id := store.NewID()
+	r.docs.Set(id, Request{
+		ID:        id,
+		Status:    "open",
+		CreatedAt: r.docs.ServerTimestamp(),
+		Data:      data,
+	})
+
+	return id, nil
+}
+
+func (r *MemoryRepository) GetAll(ctx context.Context) ([]Request, error) {
+	// Newest first, matching OrderBy("created_at", firestore.Desc)
+	return r.docs.Query(nil, func(a, b Request) bool {
+		return a.CreatedAt.After(b.CreatedAt)
+	}), nil
+}
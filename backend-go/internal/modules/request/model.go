@@ -1,6 +1,12 @@
 package request
 
-import "time"
+import (
+	"time"
+
+	"mypremier-backend/internal/antispam"
+	"mypremier-backend/internal/apierror"
+	"mypremier-backend/internal/validate"
+)
 
 // Request represents a request info/quotation in Firestore
 type Request struct {
@@ -12,7 +18,24 @@ type Request struct {
 
 // CreateRequestInput represents the input for creating a request
 type CreateRequestInput struct {
-	Data map[string]interface{} `json:"data"`
+	Data map[string]interface{} `json:"data" validate:"max=50"`
+	// Antispam carries the proofs checked by the anti-spam pipeline
+	Antispam antispam.Fields `json:"antispam"`
+}
+
+// dataRules checks the well-known fields of a request. Other fields are
+// stored as sent.
+var dataRules = map[string]string{
+	"name":    "max=200",
+	"company": "max=200",
+	"email":   "email,max=254",
+	"phone":   "phone",
+	"message": "max=5000",
+}
+
+// Validate checks the well-known fields of Data
+func (in *CreateRequestInput) Validate() []apierror.FieldError {
+	return validate.Map("data", in.Data, dataRules)
 }
 
 // CreateRequestResponse represents the response after creating a request
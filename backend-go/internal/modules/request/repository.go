@@ -5,27 +5,34 @@ import (
 	"fmt"
 
 	"cloud.google.com/go/firestore"
-	"mypremier-backend/internal/config"
+	"google.golang.org/api/iterator"
+	"mypremier-backend/internal/logging"
+	"mypremier-backend/internal/store"
 )
 
-type Repository struct {
+// Repository is the storage contract for request info submissions
+type Repository interface {
+	Create(ctx context.Context, data map[string]interface{}) (string, error)
+	GetAll(ctx context.Context) ([]Request, error)
+}
+
+// FirestoreRepository stores request info submissions in Firestore
+type FirestoreRepository struct {
 	client     *firestore.Client
 	collection string
 }
 
-func NewRepository() (*Repository, error) {
-	client, err := config.FirebaseApp.Firestore(context.Background())
-	if err != nil {
-		return nil, fmt.Errorf("failed to get firestore client: %w", err)
-	}
-
-	return &Repository{
+func NewFirestoreRepository(client *firestore.Client, collection string) *FirestoreRepository {
+	return &FirestoreRepository{
 		client:     client,
-		collection: "requests",
-	}, nil
+		collection: collection,
+	}
 }
 
-func (r *Repository) Create(ctx context.Context, data map[string]interface{}) (string, error) {
+func (r *FirestoreRepository) Create(ctx context.Context, data map[string]interface{}) (_ string, err error) {
+	ctx, done := store.Track(ctx, r.collection, "create")
+	defer func() { done(err) }()
+
 	docRef := r.client.Collection(r.collection).NewDoc()
 
 	requestData := map[string]interface{}{
@@ -34,7 +41,7 @@ func (r *Repository) Create(ctx context.Context, data map[string]interface{}) (s
 		"data":       data,
 	}
 
-	_, err := docRef.Set(ctx, requestData)
+	_, err = docRef.Set(ctx, requestData)
 	if err != nil {
 		return "", fmt.Errorf("failed to create request: %w", err)
 	}
@@ -42,7 +49,10 @@ func (r *Repository) Create(ctx context.Context, data map[string]interface{}) (s
 	return docRef.ID, nil
 }
 
-func (r *Repository) GetAll(ctx context.Context) ([]Request, error) {
+func (r *FirestoreRepository) GetAll(ctx context.Context) (_ []Request, err error) {
+	ctx, done := store.Track(ctx, r.collection, "list")
+	defer func() { done(err) }()
+
 	iter := r.client.Collection(r.collection).
 		OrderBy("created_at", firestore.Desc).
 		Documents(ctx)
@@ -51,12 +61,16 @@ func (r *Repository) GetAll(ctx context.Context) ([]Request, error) {
 	var requests []Request
 	for {
 		doc, err := iter.Next()
-		if err != nil {
+		if err == iterator.Done {
 			break
 		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to list requests: %w", err)
+		}
 
 		var request Request
 		if err := doc.DataTo(&request); err != nil {
+			logging.FromContext(ctx).Warn("skipping malformed document", "collection", r.collection, "id", doc.Ref.ID, "err", err)
 			continue
 		}
 
@@ -70,4 +84,3 @@ func (r *Repository) GetAll(ctx context.Context) ([]Request, error) {
 
 	return requests, nil
 }
-
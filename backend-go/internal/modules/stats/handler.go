@@ -2,27 +2,24 @@ package stats
 
 import (
 	"encoding/json"
-	"log"
 	"net/http"
+
+	"mypremier-backend/internal/apierror"
+	"mypremier-backend/internal/logging"
 )
 
 type Handler struct {
-	repo *Repository
+	repo Repository
 }
 
-func NewHandler() (*Handler, error) {
-	repo, err := NewRepository()
-	if err != nil {
-		return nil, err
-	}
-
+func NewHandler(repo Repository) *Handler {
 	return &Handler{
 		repo: repo,
-	}, nil
+	}
 }
 
 type SummaryResponse struct {
-	TotalProducts  int `json:"total_products"`
+	TotalProducts   int `json:"total_products"`
 	TotalCategories int `json:"total_categories"`
 	TotalRequests   int `json:"total_requests"`
 	SupportOpen     int `json:"support_open"`
@@ -30,50 +27,40 @@ type SummaryResponse struct {
 }
 
 func (h *Handler) GetSummary(w http.ResponseWriter, r *http.Request) {
-	if r.Method != http.MethodGet {
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
-		return
-	}
-
 	ctx := r.Context()
 
 	totalProducts, err := h.repo.CountProducts(ctx)
 	if err != nil {
-		log.Printf("Error counting products: %v", err)
-		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		apierror.WriteError(w, r, err)
 		return
 	}
 
 	totalCategories, err := h.repo.CountCategories(ctx)
 	if err != nil {
-		log.Printf("Error counting categories: %v", err)
-		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		apierror.WriteError(w, r, err)
 		return
 	}
 
 	totalRequests, err := h.repo.CountRequests(ctx)
 	if err != nil {
-		log.Printf("Error counting requests: %v", err)
-		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		apierror.WriteError(w, r, err)
 		return
 	}
 
 	supportOpen, err := h.repo.CountSupportsByStatus(ctx, "open")
 	if err != nil {
-		log.Printf("Error counting open supports: %v", err)
-		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		apierror.WriteError(w, r, err)
 		return
 	}
 
 	supportClosed, err := h.repo.CountSupportsByStatus(ctx, "closed")
 	if err != nil {
-		log.Printf("Error counting closed supports: %v", err)
-		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		apierror.WriteError(w, r, err)
 		return
 	}
 
 	response := SummaryResponse{
-		TotalProducts:  totalProducts,
+		TotalProducts:   totalProducts,
 		TotalCategories: totalCategories,
 		TotalRequests:   totalRequests,
 		SupportOpen:     supportOpen,
@@ -82,9 +69,6 @@ func (h *Handler) GetSummary(w http.ResponseWriter, r *http.Request) {
 
 	w.Header().Set("Content-Type", "application/json")
 	if err := json.NewEncoder(w).Encode(response); err != nil {
-		log.Printf("Error encoding response: %v", err)
-		http.Error(w, "Internal server error", http.StatusInternalServerError)
-		return
+		logging.FromContext(r.Context()).Error("encoding response", "err", err)
 	}
 }
-
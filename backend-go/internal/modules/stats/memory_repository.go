@@ -0,0 +1,72 @@
+package stats
+
+import (
+	"context"
+
+	"mypremier-backend/internal/modules/category"
+	"mypremier-backend/internal/modules/product"
+	"mypremier-backend/internal/modules/request"
+	"mypremier-backend/internal/modules/support"
+)
+
+// MemoryRepository counts documents through the other module repositories.
+// It is used with the in-memory storage backend, where there is no shared
+// database to query directly.
+type MemoryRepository struct {
+	products   product.Repository
+	categories category.Repository
+	requests   request.Repository
+	supports   support.Repository
+}
+
+func NewMemoryRepository(products product.Repository, categories category.Repository, requests request.Repository, supports support.Repository) *MemoryRepository {
+	return &MemoryRepository{
+		products:   products,
+		categories: categories,
+		requests:   requests,
+		supports:   supports,
+	}
+}
+
+func (r *MemoryRepository) CountProducts(ctx context.Context) (int, error) {
+	products, err := r.products.GetAll(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	return len(products), nil
+}
+
+func (r *MemoryRepository) CountCategories(ctx context.Context) (int, error) {
+	categories, err := r.categories.GetAll(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	return len(categories), nil
+}
+
+func (r *MemoryRepository) CountRequests(ctx context.Context) (int, error) {
+	requests, err := r.requests.GetAll(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	return len(requests), nil
+}
+
+func (r *MemoryRepository) CountSupportsByStatus(ctx context.Context, status string) (int, error) {
+	supports, err := r.supports.GetAll(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	count := 0
+	for _, support := range supports {
+		if support.Status == status {
+			count++
+		}
+	}
+
+	return count, nil
+}
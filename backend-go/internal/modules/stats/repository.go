@@ -5,74 +5,110 @@ import (
 	"fmt"
 
 	"cloud.google.com/go/firestore"
-	"mypremier-backend/internal/config"
+	"google.golang.org/api/iterator"
+	"mypremier-backend/internal/store"
 )
 
-type Repository struct {
-	client *firestore.Client
+// Repository is the storage contract for dashboard statistics
+type Repository interface {
+	CountProducts(ctx context.Context) (int, error)
+	CountCategories(ctx context.Context) (int, error)
+	CountRequests(ctx context.Context) (int, error)
+	CountSupportsByStatus(ctx context.Context, status string) (int, error)
 }
 
-func NewRepository() (*Repository, error) {
-	client, err := config.FirebaseApp.Firestore(context.Background())
-	if err != nil {
-		return nil, fmt.Errorf("failed to get firestore client: %w", err)
-	}
+// Collections names the Firestore collections counted by the dashboard
+type Collections struct {
+	Products   string
+	Categories string
+	Requests   string
+	Supports   string
+}
+
+// FirestoreRepository counts documents directly in Firestore
+type FirestoreRepository struct {
+	client      *firestore.Client
+	collections Collections
+}
 
-	return &Repository{
-		client: client,
-	}, nil
+func NewFirestoreRepository(client *firestore.Client, collections Collections) *FirestoreRepository {
+	return &FirestoreRepository{
+		client:      client,
+		collections: collections,
+	}
 }
 
-func (r *Repository) CountProducts(ctx context.Context) (int, error) {
-	iter := r.client.Collection("products").Documents(ctx)
+func (r *FirestoreRepository) CountProducts(ctx context.Context) (_ int, err error) {
+	ctx, done := store.Track(ctx, r.collections.Products, "count")
+	defer func() { done(err) }()
+
+	iter := r.client.Collection(r.collections.Products).Documents(ctx)
 	defer iter.Stop()
 
 	count := 0
 	for {
 		_, err := iter.Next()
-		if err != nil {
+		if err == iterator.Done {
 			break
 		}
+		if err != nil {
+			return 0, fmt.Errorf("failed to count %s: %w", r.collections.Products, err)
+		}
 		count++
 	}
 
 	return count, nil
 }
 
-func (r *Repository) CountCategories(ctx context.Context) (int, error) {
-	iter := r.client.Collection("categories").Documents(ctx)
+func (r *FirestoreRepository) CountCategories(ctx context.Context) (_ int, err error) {
+	ctx, done := store.Track(ctx, r.collections.Categories, "count")
+	defer func() { done(err) }()
+
+	iter := r.client.Collection(r.collections.Categories).Documents(ctx)
 	defer iter.Stop()
 
 	count := 0
 	for {
 		_, err := iter.Next()
-		if err != nil {
+		if err == iterator.Done {
 			break
 		}
+		if err != nil {
+			return 0, fmt.Errorf("failed to count %s: %w", r.collections.Categories, err)
+		}
 		count++
 	}
 
 	return count, nil
 }
 
-func (r *Repository) CountRequests(ctx context.Context) (int, error) {
-	iter := r.client.Collection("requests").Documents(ctx)
+func (r *FirestoreRepository) CountRequests(ctx context.Context) (_ int, err error) {
+	ctx, done := store.Track(ctx, r.collections.Requests, "count")
+	defer func() { done(err) }()
+
+	iter := r.client.Collection(r.collections.Requests).Documents(ctx)
 	defer iter.Stop()
 
 	count := 0
 	for {
 		_, err := iter.Next()
-		if err != nil {
+		if err == iterator.Done {
 			break
 		}
+		if err != nil {
+			return 0, fmt.Errorf("failed to count %s: %w", r.collections.Requests, err)
+		}
 		count++
 	}
 
 	return count, nil
 }
 
-func (r *Repository) CountSupportsByStatus(ctx context.Context, status string) (int, error) {
-	iter := r.client.Collection("supports").
+func (r *FirestoreRepository) CountSupportsByStatus(ctx context.Context, status string) (_ int, err error) {
+	ctx, done := store.Track(ctx, r.collections.Supports, "count")
+	defer func() { done(err) }()
+
+	iter := r.client.Collection(r.collections.Supports).
 		Where("status", "==", status).
 		Documents(ctx)
 	defer iter.Stop()
@@ -80,12 +116,14 @@ func (r *Repository) CountSupportsByStatus(ctx context.Context, status string) (
 	count := 0
 	for {
 		_, err := iter.Next()
-		if err != nil {
+		if err == iterator.Done {
 			break
 		}
+		if err != nil {
+			return 0, fmt.Errorf("failed to count %s: %w", r.collections.Supports, err)
+		}
 		count++
 	}
 
 	return count, nil
 }
-
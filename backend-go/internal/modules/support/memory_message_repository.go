@@ -0,0 +1,41 @@
+package support
+
+import (
+	"context"
+
+	"mypremier-backend/internal/store"
+)
+
+// MemoryMessageRepository stores support chat messages in memory. It is
+// intended for local development and tests.
+type MemoryMessageRepository struct {
+	docs *store.Collection[SupportMessage]
+}
+
+func NewMemoryMessageRepository() *MemoryMessageRepository {
+	return &MemoryMessageRepository{
+		docs: store.NewCollection[SupportMessage](),
+	}
+}
+
+func (r *MemoryMessageRepository) GetBySupportID(ctx context.Context, supportID string) ([]SupportMessage, error) {
+	// Oldest first, matching OrderBy("created_at", firestore.Asc)
+	return r.docs.Query(func(doc SupportMessage) bool {
+		return doc.SupportID == supportID
+	}, func(a, b SupportMessage) bool {
+		return a.CreatedAt.Before(b.CreatedAt)
+	}), nil
+}
+
+func (r *MemoryMessageRepository) Create(ctx context.Context, supportID string, senderType string, message string) (string, error) {
+	id := store.NewID()
+	r.docs.Set(id, SupportMessage{
+		ID:         id,
+		SupportID:  supportID,
+		SenderType: senderType,
+		Message:    message,
+		CreatedAt:  r.docs.ServerTimestamp(),
+	})
+
+	return id, nil
+}
@@ -0,0 +1,59 @@
+package support
+
+import (
+	"context"
+
+	"mypremier-backend/internal/store"
+)
+
+// MemoryRepository stores support requests in memory. It is intended for
+// local development and tests.
+type MemoryRepository struct {
+	docs *store.Collection[Support]
+}
+
+func NewMemoryRepository() *MemoryRepository {
+	return &MemoryRepository{
+		docs: store.NewCollection[Support](),
+	}
+}
+
+func (r *MemoryRepository) Create(ctx context.Context, data map[string]interface{}) (string, error) {
+	id := store.NewID()
+	r.docs.Set(id, Support{
+		ID:        id,
+		Status:    "open",
+		CreatedAt: r.docs.ServerTimestamp(),
+		Data:      data,
+	})
+
+	return id, nil
+}
+
+func (r *MemoryRepository) GetAll(ctx context.Context) ([]Support, error) {
+	// Newest first, matching OrderBy("created_at", firestore.Desc)
+	return r.docs.Query(nil, func(a, b Support) bool {
+		return a.CreatedAt.After(b.CreatedAt)
+	}), nil
+}
+
+func (r *MemoryRepository) UpdateStatus(ctx context.Context, id string, status string) error {
+	validStatuses := map[string]bool{
+		"open":      true,
+		"responded": true,
+		"closed":    true,
+	}
+
+	if !validStatuses[status] {
+		return store.Invalid("support", "status", "must be one of: open, responded, closed")
+	}
+
+	ok := r.docs.Update(id, func(doc *Support) {
+		doc.Status = status
+	})
+	if !ok {
+		return store.NotFound("support", id)
+	}
+
+	return nil
+}
@@ -13,8 +13,8 @@ type SupportMessage struct {
 
 // CreateMessageInput represents the input for creating a support message
 type CreateMessageInput struct {
-	Message    string `json:"message"`
-	SenderType string `json:"sender_type"` // "client" or "admin"
+	Message    string `json:"message" validate:"required,max=5000"`
+	SenderType string `json:"sender_type" validate:"oneof=client admin"` // "client" (default) or "admin"
 }
 
 // CreateMessageResponse represents the response after creating a message
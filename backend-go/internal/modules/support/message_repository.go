@@ -5,27 +5,34 @@ import (
 	"fmt"
 
 	"cloud.google.com/go/firestore"
-	"mypremier-backend/internal/config"
+	"google.golang.org/api/iterator"
+	"mypremier-backend/internal/logging"
+	"mypremier-backend/internal/store"
 )
 
-type MessageRepository struct {
+// MessageRepository is the storage contract for support chat messages
+type MessageRepository interface {
+	GetBySupportID(ctx context.Context, supportID string) ([]SupportMessage, error)
+	Create(ctx context.Context, supportID string, senderType string, message string) (string, error)
+}
+
+// FirestoreMessageRepository stores support chat messages in Firestore
+type FirestoreMessageRepository struct {
 	client     *firestore.Client
 	collection string
 }
 
-func NewMessageRepository() (*MessageRepository, error) {
-	client, err := config.FirebaseApp.Firestore(context.Background())
-	if err != nil {
-		return nil, fmt.Errorf("failed to get firestore client: %w", err)
-	}
-
-	return &MessageRepository{
+func NewFirestoreMessageRepository(client *firestore.Client, collection string) *FirestoreMessageRepository {
+	return &FirestoreMessageRepository{
 		client:     client,
-		collection: "support_messages",
-	}, nil
+		collection: collection,
+	}
 }
 
-func (r *MessageRepository) GetBySupportID(ctx context.Context, supportID string) ([]SupportMessage, error) {
+func (r *FirestoreMessageRepository) GetBySupportID(ctx context.Context, supportID string) (_ []SupportMessage, err error) {
+	ctx, done := store.Track(ctx, r.collection, "list")
+	defer func() { done(err) }()
+
 	iter := r.client.Collection(r.collection).
 		Where("support_id", "==", supportID).
 		OrderBy("created_at", firestore.Asc).
@@ -35,12 +42,16 @@ func (r *MessageRepository) GetBySupportID(ctx context.Context, supportID string
 	var messages []SupportMessage
 	for {
 		doc, err := iter.Next()
-		if err != nil {
+		if err == iterator.Done {
 			break
 		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to list support messages: %w", err)
+		}
 
 		var message SupportMessage
 		if err := doc.DataTo(&message); err != nil {
+			logging.FromContext(ctx).Warn("skipping malformed document", "collection", r.collection, "id", doc.Ref.ID, "err", err)
 			continue
 		}
 
@@ -55,7 +66,10 @@ func (r *MessageRepository) GetBySupportID(ctx context.Context, supportID string
 	return messages, nil
 }
 
-func (r *MessageRepository) Create(ctx context.Context, supportID string, senderType string, message string) (string, error) {
+func (r *FirestoreMessageRepository) Create(ctx context.Context, supportID string, senderType string, message string) (_ string, err error) {
+	ctx, done := store.Track(ctx, r.collection, "create")
+	defer func() { done(err) }()
+
 	docRef := r.client.Collection(r.collection).NewDoc()
 
 	messageData := map[string]interface{}{
@@ -65,11 +79,10 @@ func (r *MessageRepository) Create(ctx context.Context, supportID string, sender
 		"created_at":  firestore.ServerTimestamp,
 	}
 
-	_, err := docRef.Set(ctx, messageData)
+	_, err = docRef.Set(ctx, messageData)
 	if err != nil {
 		return "", fmt.Errorf("failed to create support message: %w", err)
 	}
 
 	return docRef.ID, nil
 }
-
@@ -1,6 +1,12 @@
 package support
 
-import "time"
+import (
+	"time"
+
+	"mypremier-backend/internal/antispam"
+	"mypremier-backend/internal/apierror"
+	"mypremier-backend/internal/validate"
+)
 
 // Support represents a support request in Firestore
 type Support struct {
@@ -12,7 +18,24 @@ type Support struct {
 
 // CreateSupportInput represents the input for creating a support request
 type CreateSupportInput struct {
-	Data map[string]interface{} `json:"data"`
+	Data map[string]interface{} `json:"data" validate:"max=50"`
+	// Antispam carries the proofs checked by the anti-spam pipeline
+	Antispam antispam.Fields `json:"antispam"`
+}
+
+// dataRules checks the well-known fields of a support request. Other fields are
+// stored as sent.
+var dataRules = map[string]string{
+	"name":    "max=200",
+	"company": "max=200",
+	"email":   "email,max=254",
+	"phone":   "phone",
+	"message": "max=5000",
+}
+
+// Validate checks the well-known fields of Data
+func (in *CreateSupportInput) Validate() []apierror.FieldError {
+	return validate.Map("data", in.Data, dataRules)
 }
 
 // CreateSupportResponse represents the response after creating a support request
@@ -21,3 +44,13 @@ type CreateSupportResponse struct {
 	Status string `json:"status"`
 }
 
+// UpdateSupportStatusInput is the body of a status change
+type UpdateSupportStatusInput struct {
+	Status string `json:"status" validate:"required,oneof=open responded closed"`
+}
+
+// UpdateSupportStatusResponse is returned after a status change
+type UpdateSupportStatusResponse struct {
+	ID     string `json:"id"`
+	Status string `json:"status"`
+}
@@ -5,27 +5,35 @@ import (
 	"fmt"
 
 	"cloud.google.com/go/firestore"
-	"mypremier-backend/internal/config"
+	"google.golang.org/api/iterator"
+	"mypremier-backend/internal/logging"
+	"mypremier-backend/internal/store"
 )
 
-type Repository struct {
+// Repository is the storage contract for support requests
+type Repository interface {
+	Create(ctx context.Context, data map[string]interface{}) (string, error)
+	GetAll(ctx context.Context) ([]Support, error)
+	UpdateStatus(ctx context.Context, id string, status string) error
+}
+
+// FirestoreRepository stores support requests in Firestore
+type FirestoreRepository struct {
 	client     *firestore.Client
 	collection string
 }
 
-func NewRepository() (*Repository, error) {
-	client, err := config.FirebaseApp.Firestore(context.Background())
-	if err != nil {
-		return nil, fmt.Errorf("failed to get firestore client: %w", err)
-	}
-
-	return &Repository{
+func NewFirestoreRepository(client *firestore.Client, collection string) *FirestoreRepository {
+	return &FirestoreRepository{
 		client:     client,
-		collection: "supports",
-	}, nil
+		collection: collection,
+	}
 }
 
-func (r *Repository) Create(ctx context.Context, data map[string]interface{}) (string, error) {
+func (r *FirestoreRepository) Create(ctx context.Context, data map[string]interface{}) (_ string, err error) {
+	ctx, done := store.Track(ctx, r.collection, "create")
+	defer func() { done(err) }()
+
 	docRef := r.client.Collection(r.collection).NewDoc()
 
 	supportData := map[string]interface{}{
@@ -34,7 +42,7 @@ func (r *Repository) Create(ctx context.Context, data map[string]interface{}) (s
 		"data":       data,
 	}
 
-	_, err := docRef.Set(ctx, supportData)
+	_, err = docRef.Set(ctx, supportData)
 	if err != nil {
 		return "", fmt.Errorf("failed to create support request: %w", err)
 	}
@@ -42,7 +50,10 @@ func (r *Repository) Create(ctx context.Context, data map[string]interface{}) (s
 	return docRef.ID, nil
 }
 
-func (r *Repository) GetAll(ctx context.Context) ([]Support, error) {
+func (r *FirestoreRepository) GetAll(ctx context.Context) (_ []Support, err error) {
+	ctx, done := store.Track(ctx, r.collection, "list")
+	defer func() { done(err) }()
+
 	iter := r.client.Collection(r.collection).
 		OrderBy("created_at", firestore.Desc).
 		Documents(ctx)
@@ -51,12 +62,16 @@ func (r *Repository) GetAll(ctx context.Context) ([]Support, error) {
 	var supports []Support
 	for {
 		doc, err := iter.Next()
-		if err != nil {
+		if err == iterator.Done {
 			break
 		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to list supports: %w", err)
+		}
 
 		var support Support
 		if err := doc.DataTo(&support); err != nil {
+			logging.FromContext(ctx).Warn("skipping malformed document", "collection", r.collection, "id", doc.Ref.ID, "err", err)
 			continue
 		}
 
@@ -71,7 +86,10 @@ func (r *Repository) GetAll(ctx context.Context) ([]Support, error) {
 	return supports, nil
 }
 
-func (r *Repository) UpdateStatus(ctx context.Context, id string, status string) error {
+func (r *FirestoreRepository) UpdateStatus(ctx context.Context, id string, status string) (err error) {
+	ctx, done := store.Track(ctx, r.collection, "update")
+	defer func() { done(err) }()
+
 	validStatuses := map[string]bool{
 		"open":      true,
 		"responded": true,
@@ -79,18 +97,17 @@ func (r *Repository) UpdateStatus(ctx context.Context, id string, status string)
 	}
 
 	if !validStatuses[status] {
-		return fmt.Errorf("invalid status: %s", status)
+		return store.Invalid("support", "status", "must be one of: open, responded, closed")
 	}
 
 	docRef := r.client.Collection(r.collection).Doc(id)
-	_, err := docRef.Update(ctx, []firestore.Update{
+	_, err = docRef.Update(ctx, []firestore.Update{
 		{Path: "status", Value: status},
 	})
 
 	if err != nil {
-		return fmt.Errorf("failed to update support status: %w", err)
+		return store.FromFirestore(err, "support", id, "update")
 	}
 
 	return nil
 }
-
@@ -0,0 +1,132 @@
+package user
+
+import (
+	"context"
+	"fmt"
+
+	"mypremier-backend/internal/logging"
+	"mypremier-backend/internal/store"
+
+	"firebase.google.com/go/auth"
+)
+
+// AuthAdmin manages Firebase Auth accounts. *auth.Client implements it.
+type AuthAdmin interface {
+	CreateUser(ctx context.Context, user *auth.UserToCreate) (*auth.UserRecord, error)
+	UpdateUser(ctx context.Context, uid string, user *auth.UserToUpdate) (*auth.UserRecord, error)
+	DeleteUser(ctx context.Context, uid string) error
+}
+
+// Accounts keeps Firebase Auth accounts and user documents in step. Each
+// change writes both; when the second write fails the first is undone, so
+// a user never exists in one place only.
+type Accounts struct {
+	auth AuthAdmin
+	repo Repository
+}
+
+func NewAccounts(authAdmin AuthAdmin, repo Repository) *Accounts {
+	return &Accounts{
+		auth: authAdmin,
+		repo: repo,
+	}
+}
+
+// Create creates the Auth account, then the user document. If the document
+// cannot be written the account is deleted again.
+func (a *Accounts) Create(ctx context.Context, input CreateUserInput) (*User, error) {
+	params := (&auth.UserToCreate{}).Email(input.Email).Password(input.Password)
+	if input.FullName != "" {
+		params = params.DisplayName(input.FullName)
+	}
+	record, err := a.auth.CreateUser(ctx, params)
+	if err != nil {
+		return nil, fromAuth(err, "")
+	}
+
+	user := User{
+		UID:      record.UID,
+		Email:    input.Email,
+		FullName: input.FullName,
+		Phone:    input.Phone,
+		Role:     input.Role,
+		IsActive: true,
+	}
+	if err := a.repo.Create(ctx, user); err != nil {
+		// Roll back even if the request was cancelled, so the email is free
+		// for a retry
+		if rbErr := a.auth.DeleteUser(context.WithoutCancel(ctx), record.UID); rbErr != nil {
+			logging.FromContext(ctx).Error("rolling back auth account", "uid", record.UID, "err", rbErr)
+		}
+		return nil, err
+	}
+
+	return a.repo.GetByUID(ctx, record.UID)
+}
+
+// Update changes the user document, then the Auth account. If the account
+// cannot be changed the document gets its previous profile back. In this
+// order nothing in Auth ever needs undoing, which matters as Auth cannot
+// clear an email and a password cannot be restored.
+func (a *Accounts) Update(ctx context.Context, uid string, input UpdateUserInput) (*User, error) {
+	old, err := a.repo.GetByUID(ctx, uid)
+	if err != nil {
+		return nil, err
+	}
+
+	profile := Profile{Email: input.Email, FullName: input.FullName, Phone: input.Phone}
+	if err := a.repo.UpdateProfile(ctx, uid, profile); err != nil {
+		return nil, err
+	}
+
+	params := (&auth.UserToUpdate{}).Email(input.Email).DisplayName(input.FullName)
+	if input.Password != "" {
+		params = params.Password(input.Password)
+	}
+	if _, err := a.auth.UpdateUser(ctx, uid, params); err != nil {
+		revert := Profile{Email: old.Email, FullName: old.FullName, Phone: old.Phone}
+		if rbErr := a.repo.UpdateProfile(context.WithoutCancel(ctx), uid, revert); rbErr != nil {
+			logging.FromContext(ctx).Error("restoring user document", "uid", uid, "err", rbErr)
+		}
+		return nil, fromAuth(err, uid)
+	}
+
+	old.Email, old.FullName, old.Phone = profile.Email, profile.FullName, profile.Phone
+	return old, nil
+}
+
+// Delete removes the user document, then the Auth account. If the account
+// cannot be deleted the document is restored. An account already missing
+// from Auth is not an error.
+func (a *Accounts) Delete(ctx context.Context, uid string) error {
+	old, err := a.repo.GetByUID(ctx, uid)
+	if err != nil {
+		return err
+	}
+	if err := a.repo.Delete(ctx, uid); err != nil {
+		return err
+	}
+
+	if err := a.auth.DeleteUser(ctx, uid); err != nil && !auth.IsUserNotFound(err) {
+		if rbErr := a.repo.Create(context.WithoutCancel(ctx), *old); rbErr != nil {
+			logging.FromContext(ctx).Error("restoring user document", "uid", uid, "err", rbErr)
+		}
+		return fromAuth(err, uid)
+	}
+
+	return nil
+}
+
+// fromAuth translates Firebase Auth errors into domain errors
+func fromAuth(err error, uid string) error {
+	switch {
+	case auth.IsUserNotFound(err):
+		return store.NotFound("user", uid)
+	case auth.IsEmailAlreadyExists(err):
+		return store.Conflict("user", uid, "Email is already used by another account")
+	case auth.IsInvalidEmail(err):
+		return store.Invalid("user", "email", "must be an email address")
+	default:
+		return fmt.Errorf("firebase auth: %w", err)
+	}
+}
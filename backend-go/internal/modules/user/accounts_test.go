@@ -0,0 +1,267 @@
+package user
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"mypremier-backend/internal/auth"
+	"mypremier-backend/internal/modules/audit"
+	"mypremier-backend/internal/store"
+
+	firebaseauth "firebase.google.com/go/auth"
+)
+
+// fakeAuthAdmin records the calls made to it. The parameters of a call
+// cannot be read back, as the SDK keeps them unexported.
+type fakeAuthAdmin struct {
+	calls []string
+	// fail makes the named method fail
+	fail map[string]error
+}
+
+func (f *fakeAuthAdmin) CreateUser(ctx context.Context, user *firebaseauth.UserToCreate) (*firebaseauth.UserRecord, error) {
+	f.calls = append(f.calls, "CreateUser")
+	if err := f.fail["CreateUser"]; err != nil {
+		return nil, err
+	}
+	return &firebaseauth.UserRecord{UserInfo: &firebaseauth.UserInfo{UID: "new-uid"}}, nil
+}
+
+func (f *fakeAuthAdmin) UpdateUser(ctx context.Context, uid string, user *firebaseauth.UserToUpdate) (*firebaseauth.UserRecord, error) {
+	f.calls = append(f.calls, "UpdateUser "+uid)
+	if err := f.fail["UpdateUser"]; err != nil {
+		return nil, err
+	}
+	return &firebaseauth.UserRecord{UserInfo: &firebaseauth.UserInfo{UID: uid}}, nil
+}
+
+func (f *fakeAuthAdmin) DeleteUser(ctx context.Context, uid string) error {
+	f.calls = append(f.calls, "DeleteUser "+uid)
+	return f.fail["DeleteUser"]
+}
+
+// failingRepository is a memory repository whose named methods fail
+type failingRepository struct {
+	*MemoryRepository
+	fail map[string]error
+}
+
+func (r *failingRepository) Create(ctx context.Context, user User) error {
+	if err := r.fail["Create"]; err != nil {
+		return err
+	}
+	return r.MemoryRepository.Create(ctx, user)
+}
+
+func (r *failingRepository) UpdateProfile(ctx context.Context, uid string, profile Profile) error {
+	if err := r.fail["UpdateProfile"]; err != nil {
+		return err
+	}
+	return r.MemoryRepository.UpdateProfile(ctx, uid, profile)
+}
+
+func (r *failingRepository) Delete(ctx context.Context, uid string) error {
+	if err := r.fail["Delete"]; err != nil {
+		return err
+	}
+	return r.MemoryRepository.Delete(ctx, uid)
+}
+
+var errDown = errors.New("down")
+
+// phoneOnly is an account without an email, which Auth cannot give back
+// once it has one
+var phoneOnly = User{UID: "u1", FullName: "Amal", Phone: "+62215550100", Role: "sales", IsActive: true,
+	CreatedAt: time.Date(2025, 1, 2, 3, 4, 5, 0, time.UTC)}
+
+func newAccounts(authFail, repoFail map[string]error) (*Accounts, *fakeAuthAdmin, *MemoryRepository) {
+	fake := &fakeAuthAdmin{fail: authFail}
+	memory := NewMemoryRepository(phoneOnly)
+	return NewAccounts(fake, &failingRepository{MemoryRepository: memory, fail: repoFail}), fake, memory
+}
+
+func TestAccountsCreate(t *testing.T) {
+	ctx := context.Background()
+	input := CreateUserInput{Email: "new@example.com", Password: "secret1", FullName: "New", Role: "sales"}
+
+	tests := []struct {
+		name      string
+		authFail  map[string]error
+		repoFail  map[string]error
+		wantCalls []string
+		wantDoc   bool
+	}{
+		{"created", nil, nil, []string{"CreateUser"}, true},
+		{"auth fails", map[string]error{"CreateUser": errDown}, nil, []string{"CreateUser"}, false},
+		{"document fails", nil, map[string]error{"Create": errDown}, []string{"CreateUser", "DeleteUser new-uid"}, false},
+		{"document and rollback fail", map[string]error{"DeleteUser": errDown}, map[string]error{"Create": errDown},
+			[]string{"CreateUser", "DeleteUser new-uid"}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			accounts, fake, memory := newAccounts(tt.authFail, tt.repoFail)
+			user, err := accounts.Create(ctx, input)
+			if tt.wantDoc != (err == nil) {
+				t.Fatalf("Create = %+v, %v", user, err)
+			}
+			if !reflect.DeepEqual(fake.calls, tt.wantCalls) {
+				t.Errorf("auth calls %v, want %v", fake.calls, tt.wantCalls)
+			}
+			doc, err := memory.GetByUID(ctx, "new-uid")
+			if !tt.wantDoc {
+				if !errors.Is(err, store.ErrNotFound) {
+					t.Errorf("document after a failed create: %+v, %v", doc, err)
+				}
+				return
+			}
+			if err != nil || doc.Email != input.Email || doc.Role != "sales" || !doc.IsActive {
+				t.Errorf("document %+v, %v", doc, err)
+			}
+		})
+	}
+}
+
+func TestAccountsUpdate(t *testing.T) {
+	ctx := context.Background()
+	input := UpdateUserInput{Email: "amal@example.com", FullName: "Amal R", Password: "secret1"}
+
+	tests := []struct {
+		name      string
+		uid       string
+		authFail  map[string]error
+		repoFail  map[string]error
+		wantCalls []string
+		wantErr   error
+	}{
+		{"updated", "u1", nil, nil, []string{"UpdateUser u1"}, nil},
+		{"missing user", "u2", nil, nil, nil, store.ErrNotFound},
+		{"document fails", "u1", nil, map[string]error{"UpdateProfile": errDown}, nil, errDown},
+		{"auth fails", "u1", map[string]error{"UpdateUser": errDown}, nil, []string{"UpdateUser u1"}, errDown},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			accounts, fake, memory := newAccounts(tt.authFail, tt.repoFail)
+			user, err := accounts.Update(ctx, tt.uid, input)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Update = %+v, %v; want %v", user, err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(fake.calls, tt.wantCalls) {
+				t.Errorf("auth calls %v, want %v", fake.calls, tt.wantCalls)
+			}
+
+			doc, _ := memory.GetByUID(ctx, "u1")
+			if tt.wantErr != nil {
+				// Nothing changed, including the missing email
+				if !reflect.DeepEqual(*doc, phoneOnly) {
+					t.Errorf("document after a failed update: %+v, want %+v", *doc, phoneOnly)
+				}
+				return
+			}
+			if doc.Email != input.Email || doc.FullName != input.FullName || doc.Phone != "" || doc.Role != "sales" {
+				t.Errorf("document %+v", doc)
+			}
+			if !reflect.DeepEqual(user, doc) {
+				t.Errorf("Update returned %+v, stored %+v", user, doc)
+			}
+		})
+	}
+}
+
+func TestAccountsDelete(t *testing.T) {
+	ctx := context.Background()
+	tests := []struct {
+		name      string
+		uid       string
+		authFail  map[string]error
+		repoFail  map[string]error
+		wantCalls []string
+		wantErr   error
+	}{
+		{"deleted", "u1", nil, nil, []string{"DeleteUser u1"}, nil},
+		{"missing user", "u2", nil, nil, nil, store.ErrNotFound},
+		{"document fails", "u1", nil, map[string]error{"Delete": errDown}, nil, errDown},
+		{"auth fails", "u1", map[string]error{"DeleteUser": errDown}, nil, []string{"DeleteUser u1"}, errDown},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			accounts, fake, memory := newAccounts(tt.authFail, tt.repoFail)
+			if err := accounts.Delete(ctx, tt.uid); !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Delete = %v, want %v", err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(fake.calls, tt.wantCalls) {
+				t.Errorf("auth calls %v, want %v", fake.calls, tt.wantCalls)
+			}
+
+			doc, err := memory.GetByUID(ctx, "u1")
+			if tt.wantErr == nil {
+				if !errors.Is(err, store.ErrNotFound) {
+					t.Errorf("document after delete: %+v, %v", doc, err)
+				}
+				return
+			}
+			// The document is back as it was
+			if err != nil || !reflect.DeepEqual(*doc, phoneOnly) {
+				t.Errorf("document after a failed delete: %+v, %v; want %+v", doc, err, phoneOnly)
+			}
+		})
+	}
+}
+
+// roleNames is a fixed set of assignable roles
+type roleNames []string
+
+func (r roleNames) Valid(name string) bool { return slices.Contains(r, name) }
+func (r roleNames) Names() []string        { return r }
+
+func TestAdminCannotLockThemselvesOut(t *testing.T) {
+	repo := NewMemoryRepository(
+		User{UID: "admin-uid", Role: "admin", IsActive: true},
+		User{UID: "other-uid", Role: "admin", IsActive: true},
+	)
+	h := NewAdminHandler(repo, roleNames{"admin", "sales"}, nil, NewClaimsSync(nil, repo, nil), nil,
+		audit.NewHandler(audit.NewMemoryRepository()))
+
+	tests := []struct {
+		name     string
+		handler  http.HandlerFunc
+		uid      string
+		body     string
+		wantCode int
+		wantErr  string
+	}{
+		{"demote self", h.UpdateUserRole, "admin-uid", `{"role":"sales"}`, http.StatusBadRequest, "cannot_change_own_role"},
+		{"demote another admin", h.UpdateUserRole, "other-uid", `{"role":"sales"}`, http.StatusOK, ""},
+		{"deactivate self", h.UpdateUserStatus, "admin-uid", `{"is_active":false}`, http.StatusBadRequest, "cannot_deactivate_self"},
+		{"activate self", h.UpdateUserStatus, "admin-uid", `{"is_active":true}`, http.StatusOK, ""},
+		{"deactivate another admin", h.UpdateUserStatus, "other-uid", `{"is_active":false}`, http.StatusOK, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPatch, "/admin/users/"+tt.uid, strings.NewReader(tt.body))
+			req.Header.Set("Content-Type", "application/json")
+			req.SetPathValue("uid", tt.uid)
+			req = req.WithContext(auth.NewContext(req.Context(), &auth.Principal{UID: "admin-uid"}))
+			rec := httptest.NewRecorder()
+			tt.handler(rec, req)
+
+			if rec.Code != tt.wantCode {
+				t.Fatalf("status %d, want %d: %s", rec.Code, tt.wantCode, rec.Body)
+			}
+			if tt.wantErr != "" && !strings.Contains(rec.Body.String(), `"code":"`+tt.wantErr+`"`) {
+				t.Errorf("body %s, want code %s", rec.Body, tt.wantErr)
+			}
+		})
+	}
+
+	self, _ := repo.GetByUID(context.Background(), "admin-uid")
+	if self.Role != "admin" || !self.IsActive {
+		t.Errorf("the admin locked themselves out: %+v", self)
+	}
+}
@@ -2,166 +2,292 @@ package user
 
 import (
 	"encoding/json"
-	"log"
 	"net/http"
 	"strings"
+
+	"mypremier-backend/internal/apierror"
+	"mypremier-backend/internal/decode"
+	"mypremier-backend/internal/logging"
+	"mypremier-backend/internal/middleware"
+	"mypremier-backend/internal/modules/audit"
 )
 
-type AdminHandler struct {
-	repo *Repository
+// Roles tells which roles can be assigned; *rbac.Registry implements it
+type Roles interface {
+	Valid(name string) bool
+	Names() []string
 }
 
-func NewAdminHandler() (*AdminHandler, error) {
-	repo, err := NewRepository()
-	if err != nil {
-		return nil, err
-	}
+type AdminHandler struct {
+	repo         Repository
+	roles        Roles
+	accounts     *Accounts
+	claims       *ClaimsSync
+	sessions     *Sessions
+	auditHandler *audit.Handler
+}
 
+// NewAdminHandler manages users. accounts and sessions are nil when Firebase
+// Auth is not configured; creating, updating and deleting users and revoking
+// sessions then fail with 503. Changes to roles and status are mirrored into
+// custom claims with claims.
+func NewAdminHandler(repo Repository, roles Roles, accounts *Accounts, claims *ClaimsSync, sessions *Sessions, auditHandler *audit.Handler) *AdminHandler {
 	return &AdminHandler{
-		repo: repo,
-	}, nil
+		repo:         repo,
+		roles:        roles,
+		accounts:     accounts,
+		claims:       claims,
+		sessions:     sessions,
+		auditHandler: auditHandler,
+	}
 }
 
 func (h *AdminHandler) GetUsers(w http.ResponseWriter, r *http.Request) {
-	if r.Method != http.MethodGet {
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
-		return
-	}
-
 	users, err := h.repo.GetAll(r.Context())
 	if err != nil {
-		log.Printf("Error fetching users: %v", err)
-		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		apierror.WriteError(w, r, err)
 		return
 	}
 
 	w.Header().Set("Content-Type", "application/json")
 	if err := json.NewEncoder(w).Encode(users); err != nil {
-		log.Printf("Error encoding response: %v", err)
-		http.Error(w, "Internal server error", http.StatusInternalServerError)
-		return
+		logging.FromContext(r.Context()).Error("encoding response", "err", err)
 	}
 }
 
 func (h *AdminHandler) UpdateUserRole(w http.ResponseWriter, r *http.Request) {
-	if r.Method != http.MethodPatch {
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	uid := r.PathValue("uid")
+
+	var input UpdateUserRoleInput
+
+	if err := decode.JSON(r, &input); err != nil {
+		apierror.Write(w, r, err)
 		return
 	}
-
-	// Extract UID from path /admin/users/{uid}/role
-	path := strings.TrimPrefix(r.URL.Path, "/admin/users/")
-	if path == "" || path == r.URL.Path {
-		http.Error(w, "User UID is required", http.StatusBadRequest)
+	if !h.validRole(w, r, input.Role) {
 		return
 	}
-
-	// Remove /role suffix if present
-	uid := strings.TrimSuffix(path, "/role")
-	if uid == "" {
-		http.Error(w, "User UID is required", http.StatusBadRequest)
+	// An admin changing their own role could lock everyone out of user
+	// management
+	if uid == middleware.GetUserUID(r.Context()) {
+		apierror.Write(w, r, apierror.BadRequest("cannot_change_own_role", "You cannot change your own role"))
 		return
 	}
 
-	var input struct {
-		Role string `json:"role"`
+	err := h.repo.UpdateRole(r.Context(), uid, input.Role)
+	if err != nil {
+		apierror.WriteError(w, r, err)
+		return
 	}
+	h.applyClaims(r, uid)
 
-	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
-		log.Printf("Error decoding request body: %v", err)
-		http.Error(w, "Invalid request body", http.StatusBadRequest)
-		return
+	// Log audit action
+	_ = h.auditHandler.LogAction(r.Context(), "role_changed", "user", uid)
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	response := UpdateUserRoleResponse{
+		UID:  uid,
+		Role: input.Role,
+	}
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		logging.FromContext(r.Context()).Error("encoding response", "err", err)
 	}
+}
+
+func (h *AdminHandler) UpdateUserStatus(w http.ResponseWriter, r *http.Request) {
+	uid := r.PathValue("uid")
 
-	if input.Role == "" {
-		http.Error(w, "Role is required", http.StatusBadRequest)
+	var input UpdateUserStatusInput
+
+	if err := decode.JSON(r, &input); err != nil {
+		apierror.Write(w, r, err)
 		return
 	}
 
-	if !IsValidRole(input.Role) {
-		http.Error(w, "Invalid role. Must be one of: admin, sales, client", http.StatusBadRequest)
+	if !*input.IsActive && uid == middleware.GetUserUID(r.Context()) {
+		apierror.Write(w, r, apierror.BadRequest("cannot_deactivate_self", "You cannot deactivate your own account"))
 		return
 	}
 
-	err := h.repo.UpdateRole(r.Context(), uid, input.Role)
+	err := h.repo.UpdateStatus(r.Context(), uid, *input.IsActive)
 	if err != nil {
-		log.Printf("Error updating user role: %v", err)
-		if strings.Contains(err.Error(), "not found") {
-			http.Error(w, "User not found", http.StatusNotFound)
-			return
-		}
-		if strings.Contains(err.Error(), "invalid role") {
-			http.Error(w, err.Error(), http.StatusBadRequest)
-			return
-		}
-		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		apierror.WriteError(w, r, err)
 		return
 	}
+	h.applyClaims(r, uid)
+
+	// Log audit action
+	_ = h.auditHandler.LogAction(r.Context(), "status_changed", "user", uid)
 
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusOK)
-	response := map[string]interface{}{
-		"uid":  uid,
-		"role": input.Role,
+	response := UpdateUserStatusResponse{
+		UID:      uid,
+		IsActive: *input.IsActive,
 	}
 	if err := json.NewEncoder(w).Encode(response); err != nil {
-		log.Printf("Error encoding response: %v", err)
-		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		logging.FromContext(r.Context()).Error("encoding response", "err", err)
+	}
+}
+
+func (h *AdminHandler) CreateUser(w http.ResponseWriter, r *http.Request) {
+	if !h.accountsAvailable(w, r) {
+		return
+	}
+
+	var input CreateUserInput
+
+	if err := decode.JSON(r, &input); err != nil {
+		apierror.Write(w, r, err)
+		return
+	}
+	if !h.validRole(w, r, input.Role) {
+		return
+	}
+
+	user, err := h.accounts.Create(r.Context(), input)
+	if err != nil {
+		apierror.WriteError(w, r, err)
 		return
 	}
+	h.applyClaims(r, user.UID)
+
+	// Log audit action
+	_ = h.auditHandler.LogAction(r.Context(), "created", "user", user.UID)
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	if err := json.NewEncoder(w).Encode(user); err != nil {
+		logging.FromContext(r.Context()).Error("encoding response", "err", err)
+	}
 }
 
-func (h *AdminHandler) UpdateUserStatus(w http.ResponseWriter, r *http.Request) {
-	if r.Method != http.MethodPatch {
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+func (h *AdminHandler) UpdateUser(w http.ResponseWriter, r *http.Request) {
+	uid := r.PathValue("uid")
+
+	if !h.accountsAvailable(w, r) {
 		return
 	}
 
-	// Extract UID from path /admin/users/{uid}/status
-	path := strings.TrimPrefix(r.URL.Path, "/admin/users/")
-	if path == "" || path == r.URL.Path {
-		http.Error(w, "User UID is required", http.StatusBadRequest)
+	var input UpdateUserInput
+
+	if err := decode.JSON(r, &input); err != nil {
+		apierror.Write(w, r, err)
 		return
 	}
 
-	// Remove /status suffix if present
-	uid := strings.TrimSuffix(path, "/status")
-	if uid == "" {
-		http.Error(w, "User UID is required", http.StatusBadRequest)
+	user, err := h.accounts.Update(r.Context(), uid, input)
+	if err != nil {
+		apierror.WriteError(w, r, err)
 		return
 	}
 
-	var input struct {
-		IsActive bool `json:"is_active"`
+	// Log audit action
+	_ = h.auditHandler.LogAction(r.Context(), "updated", "user", uid)
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(user); err != nil {
+		logging.FromContext(r.Context()).Error("encoding response", "err", err)
 	}
+}
+
+func (h *AdminHandler) DeleteUser(w http.ResponseWriter, r *http.Request) {
+	uid := r.PathValue("uid")
 
-	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
-		log.Printf("Error decoding request body: %v", err)
-		http.Error(w, "Invalid request body", http.StatusBadRequest)
+	if !h.accountsAvailable(w, r) {
+		return
+	}
+	if uid == middleware.GetUserUID(r.Context()) {
+		apierror.Write(w, r, apierror.BadRequest("cannot_delete_self", "You cannot delete your own account"))
 		return
 	}
 
-	err := h.repo.UpdateStatus(r.Context(), uid, input.IsActive)
+	err := h.accounts.Delete(r.Context(), uid)
 	if err != nil {
-		log.Printf("Error updating user status: %v", err)
-		if strings.Contains(err.Error(), "not found") {
-			http.Error(w, "User not found", http.StatusNotFound)
-			return
-		}
-		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		apierror.WriteError(w, r, err)
 		return
 	}
+	// Tokens of the deleted user stay valid until they expire; this makes
+	// them fail the lookup of the now missing document
+	if err := h.claims.Invalidate(r.Context(), uid); err != nil {
+		logging.FromContext(r.Context()).Error("invalidating claims of deleted user", "uid", uid, "err", err)
+	}
+
+	// Log audit action
+	_ = h.auditHandler.LogAction(r.Context(), "deleted", "user", uid)
 
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusOK)
-	response := map[string]interface{}{
-		"uid":       uid,
-		"is_active": input.IsActive,
+	response := DeleteUserResponse{
+		UID:     uid,
+		Message: "User deleted successfully",
 	}
 	if err := json.NewEncoder(w).Encode(response); err != nil {
-		log.Printf("Error encoding response: %v", err)
-		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		logging.FromContext(r.Context()).Error("encoding response", "err", err)
+	}
+}
+
+func (h *AdminHandler) RevokeSessions(w http.ResponseWriter, r *http.Request) {
+	uid := r.PathValue("uid")
+
+	if h.sessions == nil {
+		writeAuthUnavailable(w, r)
 		return
 	}
+
+	err := h.sessions.Revoke(r.Context(), uid)
+	if err != nil {
+		apierror.WriteError(w, r, err)
+		return
+	}
+
+	// Log audit action
+	_ = h.auditHandler.LogAction(r.Context(), "sessions_revoked", "user", uid)
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	response := RevokeSessionsResponse{
+		UID:     uid,
+		Message: "Sessions revoked successfully",
+	}
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		logging.FromContext(r.Context()).Error("encoding response", "err", err)
+	}
 }
 
+// applyClaims mirrors the stored role and status of uid into its claims.
+// The change is already stored, so a failure is logged rather than
+// returned; the reconciliation job retries it.
+func (h *AdminHandler) applyClaims(r *http.Request, uid string) {
+	if err := h.claims.Apply(r.Context(), uid); err != nil {
+		logging.FromContext(r.Context()).Error("mirroring custom claims", "uid", uid, "err", err)
+	}
+}
+
+// validRole reports whether role is in the registry, answering 400 if not
+func (h *AdminHandler) validRole(w http.ResponseWriter, r *http.Request, role string) bool {
+	if h.roles.Valid(role) {
+		return true
+	}
+	apierror.Write(w, r, apierror.Validation(apierror.FieldError{
+		Field:   "role",
+		Message: "must be one of: " + strings.Join(h.roles.Names(), ", "),
+	}))
+	return false
+}
+
+// accountsAvailable answers 503 when there is no Auth client to manage
+// accounts with
+func (h *AdminHandler) accountsAvailable(w http.ResponseWriter, r *http.Request) bool {
+	if h.accounts != nil {
+		return true
+	}
+	writeAuthUnavailable(w, r)
+	return false
+}
+
+// writeAuthUnavailable answers 503 to requests that need Firebase Auth
+func writeAuthUnavailable(w http.ResponseWriter, r *http.Request) {
+	apierror.Write(w, r, apierror.New(http.StatusServiceUnavailable, "auth_unavailable", "Firebase auth is not configured"))
+}
@@ -0,0 +1,255 @@
+package user
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"mypremier-backend/internal/auth"
+	"mypremier-backend/internal/cache"
+	"mypremier-backend/internal/logging"
+	"mypremier-backend/internal/metrics"
+	"mypremier-backend/internal/middleware"
+
+	firebaseauth "firebase.google.com/go/auth"
+	"google.golang.org/api/iterator"
+)
+
+// changeTTL is how long a change is remembered: the lifetime of an ID token
+// plus the clock skew Firebase allows when verifying one. Every token issued
+// before the change has expired by then.
+const changeTTL = time.Hour + 5*time.Minute
+
+// ClaimsAdmin sets custom claims, revokes sessions and lists accounts.
+// FirebaseClaimsAdmin adapts *firebaseauth.Client to it.
+type ClaimsAdmin interface {
+	SetCustomUserClaims(ctx context.Context, uid string, customClaims map[string]interface{}) error
+	RevokeRefreshTokens(ctx context.Context, uid string) error
+	// Accounts iterates over every Auth account
+	Accounts(ctx context.Context) AccountIterator
+}
+
+// AccountIterator walks Auth accounts, returning iterator.Done after the
+// last one. *firebaseauth.UserIterator implements it.
+type AccountIterator interface {
+	Next() (*firebaseauth.ExportedUserRecord, error)
+}
+
+// FirebaseClaimsAdmin is the ClaimsAdmin of a Firebase Auth client
+type FirebaseClaimsAdmin struct {
+	*firebaseauth.Client
+}
+
+func (a FirebaseClaimsAdmin) Accounts(ctx context.Context) AccountIterator {
+	return a.Users(ctx, "")
+}
+
+// ClaimsSync mirrors the role and status of users into Firebase custom
+// claims, so that requests can be authorised from the verified token alone.
+// The users collection stays the source of truth: each change is recorded in
+// changes, and tokens issued before the latest change of their user are
+// checked against storage instead.
+type ClaimsSync struct {
+	auth    ClaimsAdmin
+	repo    Repository
+	changes cache.Cache
+}
+
+// NewClaimsSync records changes in changes, which must be shared by every
+// replica for them to take effect everywhere at once. With a nil changes no
+// claims are trusted, and every request is authorised from storage. authAdmin
+// is nil when Firebase Auth is not configured; claims are then left alone.
+func NewClaimsSync(authAdmin ClaimsAdmin, repo Repository, changes cache.Cache) *ClaimsSync {
+	return &ClaimsSync{
+		auth:    authAdmin,
+		repo:    repo,
+		changes: changes,
+	}
+}
+
+// Claims returns the custom claims of u
+func Claims(u User) map[string]interface{} {
+	return map[string]interface{}{
+		auth.ClaimRole:   u.Role,
+		auth.ClaimActive: u.IsActive,
+	}
+}
+
+// Access returns the role and status of p, a middleware.AccessLookup. The
+// claims of the token are used unless they are missing or the user changed
+// since the token was issued.
+func (s *ClaimsSync) Access(ctx context.Context, p *auth.Principal) (middleware.Access, error) {
+	if access, ok := claimsAccess(p.Claims); ok && s.fresh(ctx, p) {
+		metrics.AccessLookups.WithLabelValues("claims").Inc()
+		return access, nil
+	}
+
+	metrics.AccessLookups.WithLabelValues("storage").Inc()
+	u, err := s.repo.GetByUID(ctx, p.UID)
+	if err != nil {
+		return middleware.Access{}, err
+	}
+	return middleware.Access{Role: u.Role, Active: u.IsActive}, nil
+}
+
+// Apply mirrors the user document of uid into its claims after a change to
+// its role or status. A deactivated user also loses their refresh tokens, so
+// they cannot obtain new ID tokens.
+func (s *ClaimsSync) Apply(ctx context.Context, uid string) error {
+	u, err := s.repo.GetByUID(ctx, uid)
+	if err != nil {
+		return err
+	}
+	return s.apply(ctx, *u)
+}
+
+// Invalidate stops trusting the claims of every token of uid issued so far,
+// e.g. after the user is deleted
+func (s *ClaimsSync) Invalidate(ctx context.Context, uid string) error {
+	if s.changes == nil {
+		return nil
+	}
+	stamp := strconv.FormatInt(time.Now().Unix(), 10)
+	if err := s.changes.Set(ctx, changeKey(uid), []byte(stamp), changeTTL); err != nil {
+		return fmt.Errorf("recording access change: %w", err)
+	}
+	return nil
+}
+
+// Reconcile corrects the claims of every user whose claims differ from
+// their document, e.g. after a failed Apply or a change made in the
+// Firebase console. It returns the number of users corrected.
+func (s *ClaimsSync) Reconcile(ctx context.Context) (int, error) {
+	if s.auth == nil {
+		return 0, nil
+	}
+
+	users, err := s.repo.GetAll(ctx)
+	if err != nil {
+		return 0, err
+	}
+	byUID := make(map[string]User, len(users))
+	for _, u := range users {
+		byUID[u.UID] = u
+	}
+
+	fixed := 0
+	var errs []error
+	iter := s.auth.Accounts(ctx)
+	for {
+		record, err := iter.Next()
+		if err == iterator.Done {
+			break
+		}
+		if err != nil {
+			return fixed, fmt.Errorf("listing auth accounts: %w", err)
+		}
+
+		// Accounts without a document, e.g. customers, carry no claims
+		u, ok := byUID[record.UID]
+		if !ok {
+			continue
+		}
+		if access, ok := claimsAccess(record.CustomClaims); ok && access == (middleware.Access{Role: u.Role, Active: u.IsActive}) {
+			continue
+		}
+
+		logging.FromContext(ctx).Info("correcting custom claims", "uid", u.UID, "role", u.Role, "active", u.IsActive)
+		if err := s.apply(ctx, u); err != nil {
+			metrics.ClaimsReconciled.WithLabelValues("failed").Inc()
+			errs = append(errs, fmt.Errorf("user %s: %w", u.UID, err))
+			continue
+		}
+		metrics.ClaimsReconciled.WithLabelValues("corrected").Inc()
+		fixed++
+	}
+
+	return fixed, errors.Join(errs...)
+}
+
+// Run reconciles every interval until ctx is cancelled, a worker for
+// lifecycle.Manager. A failed pass is logged and retried at the next one.
+func (s *ClaimsSync) Run(ctx context.Context, interval time.Duration) error {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		fixed, err := s.Reconcile(ctx)
+		if err != nil && ctx.Err() == nil {
+			logging.FromContext(ctx).Error("reconciling custom claims", "corrected", fixed, "err", err)
+		} else if fixed > 0 {
+			logging.FromContext(ctx).Info("reconciled custom claims", "corrected", fixed)
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+// apply sets the claims of u and revokes its sessions if it is inactive.
+// The change is recorded last, so that tokens refreshed before the new
+// claims were set are not trusted either.
+func (s *ClaimsSync) apply(ctx context.Context, u User) error {
+	var errs []error
+	if s.auth != nil {
+		if err := s.auth.SetCustomUserClaims(ctx, u.UID, Claims(u)); err != nil {
+			errs = append(errs, fmt.Errorf("setting custom claims: %w", err))
+		}
+		if !u.IsActive {
+			if err := s.auth.RevokeRefreshTokens(ctx, u.UID); err != nil {
+				errs = append(errs, fmt.Errorf("revoking refresh tokens: %w", err))
+			}
+		}
+	}
+	if err := s.Invalidate(ctx, u.UID); err != nil {
+		errs = append(errs, err)
+	}
+	return errors.Join(errs...)
+}
+
+// fresh reports whether the token of p was issued after the latest recorded
+// change of its user. When changes are not recorded, or the record cannot
+// be read, the token is not trusted.
+func (s *ClaimsSync) fresh(ctx context.Context, p *auth.Principal) bool {
+	if s.changes == nil {
+		return false
+	}
+	stamp, ok, err := s.changes.Get(ctx, changeKey(p.UID))
+	if err != nil {
+		logging.FromContext(ctx).Warn("reading access changes, using storage", "err", err)
+		return false
+	}
+	if !ok {
+		return true
+	}
+	changed, err := strconv.ParseInt(string(stamp), 10, 64)
+	if err != nil {
+		return false
+	}
+	// iat has a precision of one second, so a token issued in the second of
+	// the change is not trusted
+	return p.IssuedAt.Unix() > changed
+}
+
+// claimsAccess reads the role and status from claims, reporting false if
+// either is missing
+func claimsAccess(claims map[string]interface{}) (middleware.Access, bool) {
+	role, ok := claims[auth.ClaimRole].(string)
+	if !ok {
+		return middleware.Access{}, false
+	}
+	active, ok := claims[auth.ClaimActive].(bool)
+	if !ok {
+		return middleware.Access{}, false
+	}
+	return middleware.Access{Role: role, Active: active}, true
+}
+
+func changeKey(uid string) string {
+	return "changed:" + uid
+}
@@ -0,0 +1,270 @@
+package user
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"mypremier-backend/internal/auth"
+	"mypremier-backend/internal/cache"
+	"mypremier-backend/internal/middleware"
+	"mypremier-backend/internal/store"
+
+	firebaseauth "firebase.google.com/go/auth"
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+	"google.golang.org/api/iterator"
+)
+
+// fakeClaimsAdmin keeps custom claims in memory
+type fakeClaimsAdmin struct {
+	mu      sync.Mutex
+	claims  map[string]map[string]interface{}
+	revoked []string
+	// failUID makes SetCustomUserClaims fail for that user
+	failUID string
+}
+
+func newFakeClaimsAdmin() *fakeClaimsAdmin {
+	return &fakeClaimsAdmin{claims: map[string]map[string]interface{}{}}
+}
+
+func (f *fakeClaimsAdmin) SetCustomUserClaims(ctx context.Context, uid string, claims map[string]interface{}) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if uid == f.failUID {
+		return errors.New("auth unavailable")
+	}
+	f.claims[uid] = claims
+	return nil
+}
+
+func (f *fakeClaimsAdmin) RevokeRefreshTokens(ctx context.Context, uid string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.revoked = append(f.revoked, uid)
+	return nil
+}
+
+func (f *fakeClaimsAdmin) Accounts(ctx context.Context) AccountIterator {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	it := &fakeAccounts{}
+	for uid, claims := range f.claims {
+		it.records = append(it.records, &firebaseauth.ExportedUserRecord{
+			UserRecord: &firebaseauth.UserRecord{
+				UserInfo:     &firebaseauth.UserInfo{UID: uid},
+				CustomClaims: claims,
+			},
+		})
+	}
+	return it
+}
+
+type fakeAccounts struct {
+	records []*firebaseauth.ExportedUserRecord
+}
+
+func (it *fakeAccounts) Next() (*firebaseauth.ExportedUserRecord, error) {
+	if len(it.records) == 0 {
+		return nil, iterator.Done
+	}
+	next := it.records[0]
+	it.records = it.records[1:]
+	return next, nil
+}
+
+// failingCache fails every operation
+type failingCache struct{}
+
+func (failingCache) Get(ctx context.Context, key string) ([]byte, bool, error) {
+	return nil, false, errors.New("cache down")
+}
+
+func (failingCache) Set(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+	return errors.New("cache down")
+}
+
+func (failingCache) Delete(ctx context.Context, keys ...string) error {
+	return errors.New("cache down")
+}
+
+func principal(uid string, claims map[string]interface{}, issuedAt time.Time) *auth.Principal {
+	return &auth.Principal{UID: uid, Claims: claims, IssuedAt: issuedAt}
+}
+
+func TestAccess(t *testing.T) {
+	ctx := context.Background()
+	repo := NewMemoryRepository(User{UID: "alice", Role: "sales", IsActive: true})
+	// The token claims a role storage does not have, to tell where the
+	// answer came from
+	adminClaims := Claims(User{Role: "admin", IsActive: true})
+	now := time.Now()
+
+	stale := cache.NewMemoryCache()
+	if err := NewClaimsSync(nil, repo, stale).Invalidate(ctx, "alice"); err != nil {
+		t.Fatal(err)
+	}
+	garbled := cache.NewMemoryCache()
+	garbled.Set(ctx, changeKey("alice"), []byte("yesterday"), time.Hour)
+
+	tests := []struct {
+		name    string
+		changes cache.Cache
+		p       *auth.Principal
+		want    middleware.Access
+		wantErr error
+	}{
+		{"fresh claims are trusted", cache.NewMemoryCache(), principal("alice", adminClaims, now), middleware.Access{Role: "admin", Active: true}, nil},
+		{"no change store", nil, principal("alice", adminClaims, now), middleware.Access{Role: "sales", Active: true}, nil},
+		{"token issued before a change", stale, principal("alice", adminClaims, now.Add(-time.Minute)), middleware.Access{Role: "sales", Active: true}, nil},
+		{"token issued after a change", stale, principal("alice", adminClaims, now.Add(2*time.Second)), middleware.Access{Role: "admin", Active: true}, nil},
+		{"unreadable change", garbled, principal("alice", adminClaims, now), middleware.Access{Role: "sales", Active: true}, nil},
+		{"change store down", failingCache{}, principal("alice", adminClaims, now), middleware.Access{Role: "sales", Active: true}, nil},
+		{"no claims", cache.NewMemoryCache(), principal("alice", nil, now), middleware.Access{Role: "sales", Active: true}, nil},
+		{"incomplete claims", cache.NewMemoryCache(), principal("alice", map[string]interface{}{auth.ClaimRole: "admin"}, now), middleware.Access{Role: "sales", Active: true}, nil},
+		{"no document", cache.NewMemoryCache(), principal("bob", nil, now), middleware.Access{}, store.ErrNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := NewClaimsSync(nil, repo, tt.changes).Access(ctx, tt.p)
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("err = %v, want %v", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil || got != tt.want {
+				t.Errorf("Access = %+v, %v; want %+v", got, err, tt.want)
+			}
+		})
+	}
+}
+
+// TestDeactivationIsImmediate deactivates a user through one replica while
+// another keeps serving their old token
+func TestDeactivationIsImmediate(t *testing.T) {
+	ctx := context.Background()
+	mr := miniredis.RunT(t)
+	rdb := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() { rdb.Close() })
+
+	repo := NewMemoryRepository(User{UID: "alice", Role: "admin", IsActive: true})
+	fake := newFakeClaimsAdmin()
+	replicaA := NewClaimsSync(fake, repo, cache.NewRedisCache(rdb, "test:"))
+	replicaB := NewClaimsSync(fake, repo, cache.NewRedisCache(rdb, "test:"))
+
+	ok := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})
+	known := func(role string) bool { return role == "admin" }
+	serve := func(replica *ClaimsSync, p *auth.Principal) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/admin/users", nil)
+		req = req.WithContext(auth.NewContext(req.Context(), p))
+		rec := httptest.NewRecorder()
+		middleware.LoadUserRole(replica.Access, known)(ok).ServeHTTP(rec, req)
+		return rec
+	}
+
+	token := principal("alice", Claims(User{Role: "admin", IsActive: true}), time.Now().Add(-time.Minute))
+	if rec := serve(replicaB, token); rec.Code != http.StatusOK {
+		t.Fatalf("before deactivation: status %d", rec.Code)
+	}
+
+	if err := repo.UpdateStatus(ctx, "alice", false); err != nil {
+		t.Fatal(err)
+	}
+	if err := replicaA.Apply(ctx, "alice"); err != nil {
+		t.Fatal(err)
+	}
+
+	for name, replica := range map[string]*ClaimsSync{"same replica": replicaA, "other replica": replicaB} {
+		rec := serve(replica, token)
+		if rec.Code != http.StatusForbidden || !strings.Contains(rec.Body.String(), "account_disabled") {
+			t.Errorf("%s: status %d %s, want 403 account_disabled", name, rec.Code, rec.Body)
+		}
+	}
+
+	if fake.claims["alice"][auth.ClaimActive] != false {
+		t.Errorf("claims = %v, want active false", fake.claims["alice"])
+	}
+	if len(fake.revoked) != 1 || fake.revoked[0] != "alice" {
+		t.Errorf("revoked = %v, want alice", fake.revoked)
+	}
+
+	// A token refreshed after the change carries the new claims
+	refreshed := principal("alice", fake.claims["alice"], time.Now().Add(2*time.Second))
+	if rec := serve(replicaB, refreshed); rec.Code != http.StatusForbidden {
+		t.Errorf("refreshed token: status %d, want 403", rec.Code)
+	}
+}
+
+func TestReconcile(t *testing.T) {
+	ctx := context.Background()
+	repo := NewMemoryRepository(
+		User{UID: "in-step", Role: "sales", IsActive: true},
+		User{UID: "demoted", Role: "sales", IsActive: true},
+		User{UID: "deactivated", Role: "admin", IsActive: false},
+		User{UID: "unsynced", Role: "engineer", IsActive: true},
+		User{UID: "failing", Role: "sales", IsActive: true},
+	)
+	fake := newFakeClaimsAdmin()
+	fake.claims = map[string]map[string]interface{}{
+		"in-step":     Claims(User{Role: "sales", IsActive: true}),
+		"demoted":     Claims(User{Role: "admin", IsActive: true}),
+		"deactivated": Claims(User{Role: "admin", IsActive: true}),
+		"unsynced":    nil,
+		"failing":     nil,
+		// A customer, who has an account but no document
+		"customer": nil,
+	}
+	fake.failUID = "failing"
+	changes := cache.NewMemoryCache()
+	claims := NewClaimsSync(fake, repo, changes)
+
+	fixed, err := claims.Reconcile(ctx)
+	if fixed != 3 {
+		t.Errorf("fixed = %d, want 3", fixed)
+	}
+	if err == nil || !strings.Contains(err.Error(), "user failing") {
+		t.Errorf("err = %v, want the failure of user failing", err)
+	}
+
+	for _, uid := range []string{"in-step", "demoted", "deactivated", "unsynced"} {
+		u, _ := repo.GetByUID(ctx, uid)
+		if got, ok := claimsAccess(fake.claims[uid]); !ok || got != (middleware.Access{Role: u.Role, Active: u.IsActive}) {
+			t.Errorf("claims of %s = %v, want %s/%v", uid, fake.claims[uid], u.Role, u.IsActive)
+		}
+	}
+	if fake.claims["customer"] != nil {
+		t.Errorf("customer got claims %v", fake.claims["customer"])
+	}
+	if len(fake.revoked) != 1 || fake.revoked[0] != "deactivated" {
+		t.Errorf("revoked = %v, want deactivated", fake.revoked)
+	}
+	for uid, wantChange := range map[string]bool{"in-step": false, "demoted": true, "customer": false} {
+		if _, ok, _ := changes.Get(ctx, changeKey(uid)); ok != wantChange {
+			t.Errorf("change recorded for %s = %v, want %v", uid, ok, wantChange)
+		}
+	}
+
+	// Once in step, nothing is left to correct
+	fake.failUID = ""
+	if fixed, err := claims.Reconcile(ctx); fixed != 1 || err != nil {
+		t.Errorf("second pass = %d, %v; want 1, nil", fixed, err)
+	}
+	if fixed, err := claims.Reconcile(ctx); fixed != 0 || err != nil {
+		t.Errorf("third pass = %d, %v; want 0, nil", fixed, err)
+	}
+}
+
+func TestReconcileWithoutAuth(t *testing.T) {
+	claims := NewClaimsSync(nil, NewMemoryRepository(User{UID: "alice"}), nil)
+	if fixed, err := claims.Reconcile(context.Background()); fixed != 0 || err != nil {
+		t.Errorf("Reconcile = %d, %v; want 0, nil", fixed, err)
+	}
+}
@@ -2,60 +2,47 @@ package user
 
 import (
 	"encoding/json"
-	"log"
 	"net/http"
 
+	"mypremier-backend/internal/apierror"
+	"mypremier-backend/internal/logging"
 	"mypremier-backend/internal/middleware"
 )
 
 type MeHandler struct {
-	repo *Repository
+	repo Repository
 }
 
-func NewMeHandler() (*MeHandler, error) {
-	repo, err := NewRepository()
-	if err != nil {
-		return nil, err
-	}
-
+func NewMeHandler(repo Repository) *MeHandler {
 	return &MeHandler{
 		repo: repo,
-	}, nil
+	}
 }
 
 func (h *MeHandler) GetMe(w http.ResponseWriter, r *http.Request) {
-	if r.Method != http.MethodGet {
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
-		return
-	}
-
 	// Get UID from context (set by AuthRequired middleware)
 	uid := middleware.GetUserUID(r.Context())
 	if uid == "" {
-		http.Error(w, "User UID not found", http.StatusUnauthorized)
+		apierror.Write(w, r, apierror.Unauthorized("unauthenticated", "User UID not found"))
 		return
 	}
 
 	// Fetch user from Firestore
 	userData, err := h.repo.GetByUID(r.Context(), uid)
 	if err != nil {
-		log.Printf("Error fetching user: %v", err)
-		http.Error(w, "User not found", http.StatusNotFound)
+		apierror.WriteError(w, r, err)
 		return
 	}
 
 	// Return uid, email, role
-	response := map[string]interface{}{
-		"uid":   userData.UID,
-		"email": userData.Email,
-		"role":  userData.Role,
+	response := MeResponse{
+		UID:   userData.UID,
+		Email: userData.Email,
+		Role:  userData.Role,
 	}
 
 	w.Header().Set("Content-Type", "application/json")
 	if err := json.NewEncoder(w).Encode(response); err != nil {
-		log.Printf("Error encoding response: %v", err)
-		http.Error(w, "Internal server error", http.StatusInternalServerError)
-		return
+		logging.FromContext(r.Context()).Error("encoding response", "err", err)
 	}
 }
-
@@ -0,0 +1,96 @@
+package user
+
+import (
+	"context"
+
+	"mypremier-backend/internal/store"
+)
+
+// MemoryRepository stores users in memory. It is intended for local
+// development and tests.
+type MemoryRepository struct {
+	docs *store.Collection[User]
+}
+
+// NewMemoryRepository creates a repository seeded with the given users
+func NewMemoryRepository(users ...User) *MemoryRepository {
+	r := &MemoryRepository{
+		docs: store.NewCollection[User](),
+	}
+	for _, user := range users {
+		if user.CreatedAt.IsZero() {
+			user.CreatedAt = r.docs.ServerTimestamp()
+		}
+		r.docs.Set(user.UID, user)
+	}
+
+	return r
+}
+
+func (r *MemoryRepository) GetAll(ctx context.Context) ([]User, error) {
+	return r.docs.Query(nil, nil), nil
+}
+
+func (r *MemoryRepository) GetByUID(ctx context.Context, uid string) (*User, error) {
+	user, ok := r.docs.Get(uid)
+	if !ok {
+		return nil, store.NotFound("user", uid)
+	}
+
+	return &user, nil
+}
+
+func (r *MemoryRepository) UpdateRole(ctx context.Context, uid string, role string) error {
+	ok := r.docs.Update(uid, func(doc *User) {
+		doc.Role = role
+	})
+	if !ok {
+		return store.NotFound("user", uid)
+	}
+
+	return nil
+}
+
+func (r *MemoryRepository) UpdateStatus(ctx context.Context, uid string, isActive bool) error {
+	ok := r.docs.Update(uid, func(doc *User) {
+		doc.IsActive = isActive
+	})
+	if !ok {
+		return store.NotFound("user", uid)
+	}
+
+	return nil
+}
+
+func (r *MemoryRepository) Create(ctx context.Context, user User) error {
+	if _, ok := r.docs.Get(user.UID); ok {
+		return store.Conflict("user", user.UID, "User already exists")
+	}
+	if user.CreatedAt.IsZero() {
+		user.CreatedAt = r.docs.ServerTimestamp()
+	}
+	r.docs.Set(user.UID, user)
+
+	return nil
+}
+
+func (r *MemoryRepository) UpdateProfile(ctx context.Context, uid string, profile Profile) error {
+	ok := r.docs.Update(uid, func(doc *User) {
+		doc.Email = profile.Email
+		doc.FullName = profile.FullName
+		doc.Phone = profile.Phone
+	})
+	if !ok {
+		return store.NotFound("user", uid)
+	}
+
+	return nil
+}
+
+func (r *MemoryRepository) Delete(ctx context.Context, uid string) error {
+	if !r.docs.Delete(uid) {
+		return store.NotFound("user", uid)
+	}
+
+	return nil
+}
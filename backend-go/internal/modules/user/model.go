@@ -8,20 +8,79 @@ import (
 type User struct {
 	UID       string    `firestore:"uid" json:"uid"`
 	Email     string    `firestore:"email" json:"email"`
-	Role      string    `firestore:"role" json:"role"` // admin/sales/client
+	FullName  string    `firestore:"full_name" json:"full_name"`
+	Phone     string    `firestore:"phone" json:"phone"`
+	Role      string    `firestore:"role" json:"role"` // a role of the registry, e.g. sales
 	IsActive  bool      `firestore:"is_active" json:"is_active"`
 	CreatedAt time.Time `firestore:"created_at" json:"created_at"`
 }
 
-// Valid roles
-const (
-	RoleAdmin  = "admin"
-	RoleSales  = "sales"
-	RoleClient = "client"
-)
+// Profile is the part of a user that admins edit as a whole. Email and
+// FullName are mirrored in Firebase Auth, as email and display name.
+type Profile struct {
+	Email    string
+	FullName string
+	Phone    string
+}
 
-// IsValidRole checks if the role is valid
-func IsValidRole(role string) bool {
-	return role == RoleAdmin || role == RoleSales || role == RoleClient
+// CreateUserInput is the body of a user creation
+type CreateUserInput struct {
+	Email    string `json:"email" validate:"required,email,max=254"`
+	Password string `json:"password" validate:"required,min=6,max=128"`
+	FullName string `json:"full_name" validate:"max=200"`
+	Phone    string `json:"phone" validate:"phone"`
+	// Role is the name of a role listed by GET /admin/roles
+	Role string `json:"role" validate:"required,max=64"`
 }
 
+// UpdateUserInput is the body of a profile update. A password, if given,
+// replaces the current one.
+type UpdateUserInput struct {
+	Email    string `json:"email" validate:"required,email,max=254"`
+	Password string `json:"password" validate:"min=6,max=128"`
+	FullName string `json:"full_name" validate:"max=200"`
+	Phone    string `json:"phone" validate:"phone"`
+}
+
+// DeleteUserResponse is returned after a delete
+type DeleteUserResponse struct {
+	UID     string `json:"uid"`
+	Message string `json:"message"`
+}
+
+// RevokeSessionsResponse is returned after signing a user out everywhere
+type RevokeSessionsResponse struct {
+	UID     string `json:"uid"`
+	Message string `json:"message"`
+}
+
+// UpdateUserRoleInput is the body of a role change
+type UpdateUserRoleInput struct {
+	// Role is the name of a role listed by GET /admin/roles
+	Role string `json:"role" validate:"required,max=64"`
+}
+
+// UpdateUserRoleResponse is returned after a role change
+type UpdateUserRoleResponse struct {
+	UID  string `json:"uid"`
+	Role string `json:"role"`
+}
+
+// UpdateUserStatusInput is the body of an activation change
+type UpdateUserStatusInput struct {
+	// IsActive is a pointer so that a missing value is not read as false
+	IsActive *bool `json:"is_active" validate:"required"`
+}
+
+// UpdateUserStatusResponse is returned after an activation change
+type UpdateUserStatusResponse struct {
+	UID      string `json:"uid"`
+	IsActive bool   `json:"is_active"`
+}
+
+// MeResponse describes the signed-in user
+type MeResponse struct {
+	UID   string `json:"uid"`
+	Email string `json:"email"`
+	Role  string `json:"role"`
+}
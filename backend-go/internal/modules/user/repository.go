@@ -4,43 +4,59 @@ import (
 	"context"
 	"fmt"
 
-	"mypremier-backend/internal/config"
+	"mypremier-backend/internal/logging"
+	"mypremier-backend/internal/store"
 
 	"cloud.google.com/go/firestore"
-	"google.golang.org/grpc/codes"
-	"google.golang.org/grpc/status"
+	"google.golang.org/api/iterator"
 )
 
-type Repository struct {
+// Repository is the storage contract for users
+type Repository interface {
+	GetAll(ctx context.Context) ([]User, error)
+	GetByUID(ctx context.Context, uid string) (*User, error)
+	// Create stores user under its UID, failing if the document exists. A
+	// zero CreatedAt is set to the current time.
+	Create(ctx context.Context, user User) error
+	UpdateProfile(ctx context.Context, uid string, profile Profile) error
+	Delete(ctx context.Context, uid string) error
+	UpdateRole(ctx context.Context, uid string, role string) error
+	UpdateStatus(ctx context.Context, uid string, isActive bool) error
+}
+
+// FirestoreRepository stores users in Firestore
+type FirestoreRepository struct {
 	client     *firestore.Client
 	collection string
 }
 
-func NewRepository() (*Repository, error) {
-	client, err := config.FirebaseApp.Firestore(context.Background())
-	if err != nil {
-		return nil, fmt.Errorf("failed to get firestore client: %w", err)
-	}
-
-	return &Repository{
+func NewFirestoreRepository(client *firestore.Client, collection string) *FirestoreRepository {
+	return &FirestoreRepository{
 		client:     client,
-		collection: "users",
-	}, nil
+		collection: collection,
+	}
 }
 
-func (r *Repository) GetAll(ctx context.Context) ([]User, error) {
+func (r *FirestoreRepository) GetAll(ctx context.Context) (_ []User, err error) {
+	ctx, done := store.Track(ctx, r.collection, "list")
+	defer func() { done(err) }()
+
 	iter := r.client.Collection(r.collection).Documents(ctx)
 	defer iter.Stop()
 
 	var users []User
 	for {
 		doc, err := iter.Next()
-		if err != nil {
+		if err == iterator.Done {
 			break
 		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to list users: %w", err)
+		}
 
 		var user User
 		if err := doc.DataTo(&user); err != nil {
+			logging.FromContext(ctx).Warn("skipping malformed document", "collection", r.collection, "id", doc.Ref.ID, "err", err)
 			continue
 		}
 
@@ -55,13 +71,13 @@ func (r *Repository) GetAll(ctx context.Context) ([]User, error) {
 	return users, nil
 }
 
-func (r *Repository) GetByUID(ctx context.Context, uid string) (*User, error) {
+func (r *FirestoreRepository) GetByUID(ctx context.Context, uid string) (_ *User, err error) {
+	ctx, done := store.Track(ctx, r.collection, "get")
+	defer func() { done(err) }()
+
 	doc, err := r.client.Collection(r.collection).Doc(uid).Get(ctx)
 	if err != nil {
-		if status.Code(err) == codes.NotFound {
-			return nil, fmt.Errorf("user not found")
-		}
-		return nil, fmt.Errorf("failed to get user: %w", err)
+		return nil, store.FromFirestore(err, "user", uid, "get")
 	}
 
 	var user User
@@ -77,21 +93,11 @@ func (r *Repository) GetByUID(ctx context.Context, uid string) (*User, error) {
 	return &user, nil
 }
 
-func (r *Repository) UpdateRole(ctx context.Context, uid string, role string) error {
-	if !IsValidRole(role) {
-		return fmt.Errorf("invalid role: %s. Must be one of: admin, sales, client", role)
-	}
+func (r *FirestoreRepository) UpdateRole(ctx context.Context, uid string, role string) (err error) {
+	ctx, done := store.Track(ctx, r.collection, "update")
+	defer func() { done(err) }()
 
 	docRef := r.client.Collection(r.collection).Doc(uid)
-	
-	// Check if document exists
-	_, err := docRef.Get(ctx)
-	if err != nil {
-		if status.Code(err) == codes.NotFound {
-			return fmt.Errorf("user not found")
-		}
-		return fmt.Errorf("failed to check user existence: %w", err)
-	}
 
 	updates := []firestore.Update{
 		{Path: "role", Value: role},
@@ -99,39 +105,84 @@ func (r *Repository) UpdateRole(ctx context.Context, uid string, role string) er
 
 	_, err = docRef.Update(ctx, updates)
 	if err != nil {
-		if status.Code(err) == codes.NotFound {
-			return fmt.Errorf("user not found")
-		}
-		return fmt.Errorf("failed to update user role: %w", err)
+		return store.FromFirestore(err, "user", uid, "update")
 	}
 
 	return nil
 }
 
-func (r *Repository) UpdateStatus(ctx context.Context, uid string, isActive bool) error {
+func (r *FirestoreRepository) UpdateStatus(ctx context.Context, uid string, isActive bool) (err error) {
+	ctx, done := store.Track(ctx, r.collection, "update")
+	defer func() { done(err) }()
+
 	docRef := r.client.Collection(r.collection).Doc(uid)
-	
-	// Check if document exists
-	_, err := docRef.Get(ctx)
+
+	updates := []firestore.Update{
+		{Path: "is_active", Value: isActive},
+	}
+
+	_, err = docRef.Update(ctx, updates)
 	if err != nil {
-		if status.Code(err) == codes.NotFound {
-			return fmt.Errorf("user not found")
-		}
-		return fmt.Errorf("failed to check user existence: %w", err)
+		return store.FromFirestore(err, "user", uid, "update")
 	}
 
+	return nil
+}
+
+func (r *FirestoreRepository) Create(ctx context.Context, user User) (err error) {
+	ctx, done := store.Track(ctx, r.collection, "create")
+	defer func() { done(err) }()
+
+	userData := map[string]interface{}{
+		"uid":        user.UID,
+		"email":      user.Email,
+		"full_name":  user.FullName,
+		"phone":      user.Phone,
+		"role":       user.Role,
+		"is_active":  user.IsActive,
+		"created_at": firestore.ServerTimestamp,
+	}
+	if !user.CreatedAt.IsZero() {
+		userData["created_at"] = user.CreatedAt
+	}
+
+	_, err = r.client.Collection(r.collection).Doc(user.UID).Create(ctx, userData)
+	if err != nil {
+		return store.FromFirestore(err, "user", user.UID, "create")
+	}
+
+	return nil
+}
+
+func (r *FirestoreRepository) UpdateProfile(ctx context.Context, uid string, profile Profile) (err error) {
+	ctx, done := store.Track(ctx, r.collection, "update")
+	defer func() { done(err) }()
+
+	docRef := r.client.Collection(r.collection).Doc(uid)
+
 	updates := []firestore.Update{
-		{Path: "is_active", Value: isActive},
+		{Path: "email", Value: profile.Email},
+		{Path: "full_name", Value: profile.FullName},
+		{Path: "phone", Value: profile.Phone},
 	}
 
 	_, err = docRef.Update(ctx, updates)
 	if err != nil {
-		if status.Code(err) == codes.NotFound {
-			return fmt.Errorf("user not found")
-		}
-		return fmt.Errorf("failed to update user status: %w", err)
+		return store.FromFirestore(err, "user", uid, "update")
 	}
 
 	return nil
 }
 
+func (r *FirestoreRepository) Delete(ctx context.Context, uid string) (err error) {
+	ctx, done := store.Track(ctx, r.collection, "delete")
+	defer func() { done(err) }()
+
+	docRef := r.client.Collection(r.collection).Doc(uid)
+	_, err = docRef.Delete(ctx, firestore.Exists)
+	if err != nil {
+		return store.FromFirestore(err, "user", uid, "delete")
+	}
+
+	return nil
+}
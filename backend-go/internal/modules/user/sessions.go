@@ -0,0 +1,59 @@
+package user
+
+import (
+	"context"
+	"time"
+
+	"mypremier-backend/internal/cache"
+
+	"firebase.google.com/go/auth"
+)
+
+// SessionAdmin reads and revokes the sign-in sessions of Auth accounts.
+// *auth.Client implements it.
+type SessionAdmin interface {
+	GetUser(ctx context.Context, uid string) (*auth.UserRecord, error)
+	RevokeRefreshTokens(ctx context.Context, uid string) error
+}
+
+// Sessions tells when the sessions of a user were last revoked, so that ID
+// tokens issued before can be refused although they have not expired.
+// Answers from Firebase Auth are cached by loader for a short while.
+type Sessions struct {
+	auth   SessionAdmin
+	loader *cache.Loader
+}
+
+func NewSessions(authAdmin SessionAdmin, loader *cache.Loader) *Sessions {
+	return &Sessions{
+		auth:   authAdmin,
+		loader: loader,
+	}
+}
+
+// RevokedAt returns when the sessions of uid were last revoked, a
+// middleware.RevocationLookup. It is zero for accounts never revoked.
+func (s *Sessions) RevokedAt(ctx context.Context, uid string) (time.Time, error) {
+	millis, err := cache.Load(ctx, s.loader, uid, func(ctx context.Context) (int64, error) {
+		record, err := s.auth.GetUser(ctx, uid)
+		if err != nil {
+			return 0, fromAuth(err, uid)
+		}
+		return record.TokensValidAfterMillis, nil
+	})
+	if err != nil || millis == 0 {
+		return time.Time{}, err
+	}
+	return time.UnixMilli(millis), nil
+}
+
+// Revoke signs uid out of every device. Refresh tokens stop working at
+// once, and ID tokens already issued are refused wherever revocation is
+// checked. Replicas with their own cache notice once their entry expires.
+func (s *Sessions) Revoke(ctx context.Context, uid string) error {
+	if err := s.auth.RevokeRefreshTokens(ctx, uid); err != nil {
+		return fromAuth(err, uid)
+	}
+	s.loader.Invalidate(ctx, uid)
+	return nil
+}
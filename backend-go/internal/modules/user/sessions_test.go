@@ -0,0 +1,113 @@
+package user
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"mypremier-backend/internal/auth"
+	"mypremier-backend/internal/cache"
+	"mypremier-backend/internal/middleware"
+	"mypremier-backend/internal/store"
+
+	firebaseauth "firebase.google.com/go/auth"
+)
+
+// fakeSessionAdmin keeps the revocation time of accounts in memory
+type fakeSessionAdmin struct {
+	mu         sync.Mutex
+	validAfter map[string]int64
+	gets       int
+}
+
+func (f *fakeSessionAdmin) GetUser(ctx context.Context, uid string) (*firebaseauth.UserRecord, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.gets++
+	millis, ok := f.validAfter[uid]
+	if !ok {
+		// Firebase errors cannot be built outside the SDK; a wrapped
+		// store.ErrNotFound is what fromAuth makes of a missing account
+		return nil, store.NotFound("user", uid)
+	}
+	return &firebaseauth.UserRecord{TokensValidAfterMillis: millis}, nil
+}
+
+func (f *fakeSessionAdmin) RevokeRefreshTokens(ctx context.Context, uid string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if _, ok := f.validAfter[uid]; !ok {
+		return store.NotFound("user", uid)
+	}
+	f.validAfter[uid] = time.Now().UnixMilli()
+	return nil
+}
+
+func TestSessions(t *testing.T) {
+	ctx := context.Background()
+	fake := &fakeSessionAdmin{validAfter: map[string]int64{"alice": 0}}
+	sessions := NewSessions(fake, cache.NewLoader(cache.NewMemoryCache(), "revocations", time.Minute))
+
+	check := middleware.CheckSession(middleware.SessionPolicy{RevokedAt: sessions.RevokedAt})(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	serve := func(p *auth.Principal) int {
+		req := httptest.NewRequest(http.MethodGet, "/admin/users", nil)
+		req = req.WithContext(auth.NewContext(req.Context(), p))
+		rec := httptest.NewRecorder()
+		check.ServeHTTP(rec, req)
+		return rec.Code
+	}
+	token := &auth.Principal{UID: "alice", IssuedAt: time.Now().Add(-time.Minute)}
+
+	for i := 0; i < 3; i++ {
+		if at, err := sessions.RevokedAt(ctx, "alice"); !at.IsZero() || err != nil {
+			t.Fatalf("RevokedAt = %s, %v; want zero", at, err)
+		}
+	}
+	if code := serve(token); code != http.StatusOK {
+		t.Errorf("before revocation: status %d", code)
+	}
+	if fake.gets != 1 {
+		t.Errorf("GetUser called %d times, want 1: the answer is cached", fake.gets)
+	}
+
+	if err := sessions.Revoke(ctx, "alice"); err != nil {
+		t.Fatal(err)
+	}
+	at, err := sessions.RevokedAt(ctx, "alice")
+	if err != nil || time.Since(at) > time.Second {
+		t.Errorf("RevokedAt after Revoke = %s, %v; want now", at, err)
+	}
+	if fake.gets != 2 {
+		t.Errorf("GetUser called %d times, want 2: Revoke drops the cached answer", fake.gets)
+	}
+	if code := serve(token); code != http.StatusUnauthorized {
+		t.Errorf("token issued before the revocation: status %d, want 401", code)
+	}
+	if code := serve(&auth.Principal{UID: "alice", IssuedAt: time.Now().Add(time.Second)}); code != http.StatusOK {
+		t.Errorf("token issued after the revocation: status %d, want 200", code)
+	}
+
+	// A deleted account is never let through, nor its answer cached
+	delete(fake.validAfter, "alice")
+	sessions.loader.Invalidate(ctx, "alice")
+	gets := fake.gets
+	for i := 0; i < 2; i++ {
+		if _, err := sessions.RevokedAt(ctx, "alice"); !errors.Is(err, store.ErrNotFound) {
+			t.Errorf("RevokedAt of a deleted account: %v, want not found", err)
+		}
+		if code := serve(&auth.Principal{UID: "alice", IssuedAt: time.Now()}); code != http.StatusUnauthorized {
+			t.Errorf("deleted account: status %d, want 401", code)
+		}
+	}
+	if fake.gets != gets+4 {
+		t.Errorf("GetUser called %d times for 4 lookups of a deleted account", fake.gets-gets)
+	}
+	if err := sessions.Revoke(ctx, "alice"); !errors.Is(err, store.ErrNotFound) {
+		t.Errorf("Revoke of a deleted account: %v, want not found", err)
+	}
+}
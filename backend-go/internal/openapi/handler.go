@@ -0,0 +1,54 @@
+package openapi
+
+import (
+	"encoding/json"
+	"html/template"
+	"net/http"
+
+	"mypremier-backend/internal/apierror"
+	"mypremier-backend/internal/logging"
+)
+
+// SpecHandler serves doc as JSON. The document is encoded once.
+func SpecHandler(doc *Document) http.HandlerFunc {
+	data, err := json.MarshalIndent(doc, "", "  ")
+	return func(w http.ResponseWriter, r *http.Request) {
+		if err != nil {
+			logging.FromContext(r.Context()).Error("encoding openapi document", "err", err)
+			apierror.Write(w, r, apierror.Internal())
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(data)
+	}
+}
+
+var docsPage = template.Must(template.New("docs").Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+  <meta charset="utf-8">
+  <meta name="viewport" content="width=device-width, initial-scale=1">
+  <title>{{.Title}}</title>
+</head>
+<body>
+  <redoc spec-url="{{.SpecURL}}"></redoc>
+  <script src="https://cdn.redoc.ly/redoc/v2.5.0/bundles/redoc.standalone.js"></script>
+</body>
+</html>
+`))
+
+// docsCSP lets the docs page load Redoc and the document, and nothing else.
+// Redoc injects styles and renders in a web worker.
+const docsCSP = "default-src 'none'; script-src https://cdn.redoc.ly; style-src 'unsafe-inline'; " +
+	"img-src data: https:; font-src https:; connect-src 'self'; worker-src blob:; frame-ancestors 'none'"
+
+// DocsHandler serves an HTML page that renders the document at specURL
+func DocsHandler(title, specURL string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		w.Header().Set("Content-Security-Policy", docsCSP)
+		if err := docsPage.Execute(w, struct{ Title, SpecURL string }{title, specURL}); err != nil {
+			logging.FromContext(r.Context()).Error("rendering docs page", "err", err)
+		}
+	}
+}
@@ -0,0 +1,302 @@
+package openapi
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"mypremier-backend/internal/router"
+)
+
+// Version is the OpenAPI version of generated documents
+const Version = "3.1.0"
+
+// Info describes the API as a whole
+type Info struct {
+	Title       string `json:"title"`
+	Version     string `json:"version"`
+	Description string `json:"description,omitempty"`
+}
+
+// Operation documents one route. Request and Response are sample values,
+// usually zero values of the model structs, whose types are turned into
+// schemas.
+type Operation struct {
+	Summary     string
+	Description string
+	Tags        []string
+	// Auth marks routes that need a Firebase ID token of an active account
+	Auth bool
+	// Permission is the permission the caller's role must grant, e.g.
+	// product:write. It implies Auth.
+	Permission string
+	// Request is the JSON request body, or nil for none
+	Request interface{}
+	// Response is the JSON success body, or nil for none
+	Response interface{}
+	// Status is the success status. It defaults to 200.
+	Status int
+	// ContentType of the success body. It defaults to application/json;
+	// other types are documented as plain strings.
+	ContentType string
+	// Conditional documents the ETag and Last-Modified validators, the
+	// If-None-Match and If-Modified-Since headers and 304 answers
+	Conditional bool
+	// Idempotent documents the optional Idempotency-Key header and the 409
+	// and 422 answers to retries
+	Idempotent bool
+	// Errors lists error statuses beyond those implied by the route: 400, 413
+	// and 415 for routes with a body, 401 and 403 for Auth routes and 404 for
+	// path parameters
+	Errors []int
+}
+
+// Document is an OpenAPI document
+type Document struct {
+	OpenAPI    string                           `json:"openapi"`
+	Info       Info                             `json:"info"`
+	Paths      map[string]map[string]*operation `json:"paths"`
+	Components components                       `json:"components"`
+}
+
+type components struct {
+	Schemas         map[string]*Schema        `json:"schemas"`
+	SecuritySchemes map[string]securityScheme `json:"securitySchemes"`
+}
+
+type securityScheme struct {
+	Type         string `json:"type"`
+	Scheme       string `json:"scheme"`
+	BearerFormat string `json:"bearerFormat,omitempty"`
+	Description  string `json:"description,omitempty"`
+}
+
+type operation struct {
+	OperationID string                `json:"operationId"`
+	Summary     string                `json:"summary,omitempty"`
+	Description string                `json:"description,omitempty"`
+	Tags        []string              `json:"tags,omitempty"`
+	Parameters  []parameter           `json:"parameters,omitempty"`
+	RequestBody *requestBody          `json:"requestBody,omitempty"`
+	Responses   map[string]response   `json:"responses"`
+	Security    []map[string][]string `json:"security,omitempty"`
+}
+
+type parameter struct {
+	Name        string  `json:"name"`
+	In          string  `json:"in"`
+	Description string  `json:"description,omitempty"`
+	Required    bool    `json:"required"`
+	Schema      *Schema `json:"schema"`
+}
+
+type requestBody struct {
+	Required bool                 `json:"required"`
+	Content  map[string]mediaType `json:"content"`
+}
+
+type response struct {
+	Description string               `json:"description"`
+	Headers     map[string]header    `json:"headers,omitempty"`
+	Content     map[string]mediaType `json:"content,omitempty"`
+}
+
+type header struct {
+	Description string  `json:"description,omitempty"`
+	Schema      *Schema `json:"schema"`
+}
+
+type mediaType struct {
+	Schema *Schema `json:"schema"`
+}
+
+// errorEnvelope mirrors the body written by apierror.Write
+type errorEnvelope struct {
+	Error struct {
+		Code      string      `json:"code"`
+		Message   string      `json:"message"`
+		Details   interface{} `json:"details,omitempty"`
+		RequestID string      `json:"request_id,omitempty"`
+	} `json:"error"`
+}
+
+const bearerAuth = "bearerAuth"
+
+var wildcard = regexp.MustCompile(`\{([^}]*?)(\.\.\.)?\}`)
+
+// Build generates the document for routes. ops holds the documentation of
+// each route keyed by its pattern, e.g. "GET /products/{id}". The document
+// is always returned; the error lists routes without documentation and
+// documentation without a route.
+func Build(info Info, routes []router.Route, ops map[string]Operation) (*Document, error) {
+	doc := &Document{
+		OpenAPI: Version,
+		Info:    info,
+		Paths:   make(map[string]map[string]*operation),
+		Components: components{
+			SecuritySchemes: map[string]securityScheme{
+				bearerAuth: {
+					Type:         "http",
+					Scheme:       "bearer",
+					BearerFormat: "JWT",
+					Description:  "Firebase ID token",
+				},
+			},
+		},
+	}
+	sch := newSchemas()
+	errorType := reflect.TypeOf(errorEnvelope{})
+	sch.owners["Error"] = errorType
+	sch.components["Error"] = sch.object(errorType)
+	errorRef := &Schema{Ref: "#/components/schemas/Error"}
+
+	var errs []error
+	documented := make(map[string]bool, len(routes))
+	for _, rt := range routes {
+		pattern := rt.Pattern()
+		op, ok := ops[pattern]
+		if !ok {
+			errs = append(errs, fmt.Errorf("route %s is not documented", pattern))
+			continue
+		}
+		documented[pattern] = true
+
+		path := wildcard.ReplaceAllString(rt.Path, "{$1}")
+		if doc.Paths[path] == nil {
+			doc.Paths[path] = make(map[string]*operation)
+		}
+		doc.Paths[path][strings.ToLower(rt.Method)] = build(rt, op, sch, errorRef)
+	}
+
+	var stale []string
+	for pattern := range ops {
+		if !documented[pattern] {
+			stale = append(stale, pattern)
+		}
+	}
+	sort.Strings(stale)
+	for _, pattern := range stale {
+		errs = append(errs, fmt.Errorf("documented route %s is not registered", pattern))
+	}
+
+	doc.Components.Schemas = sch.components
+	errs = append(errs, sch.errs...)
+
+	return doc, errors.Join(errs...)
+}
+
+func build(rt router.Route, op Operation, sch *schemas, errorRef *Schema) *operation {
+	out := &operation{
+		OperationID: operationID(rt),
+		Summary:     op.Summary,
+		Description: op.Description,
+		Tags:        op.Tags,
+		Responses:   make(map[string]response),
+	}
+
+	errorStatuses := append([]int(nil), op.Errors...)
+
+	for _, m := range wildcard.FindAllStringSubmatch(rt.Path, -1) {
+		out.Parameters = append(out.Parameters, parameter{
+			Name:     m[1],
+			In:       "path",
+			Required: true,
+			Schema:   &Schema{Type: "string"},
+		})
+	}
+	if len(out.Parameters) > 0 {
+		errorStatuses = append(errorStatuses, http.StatusNotFound)
+	}
+
+	if op.Idempotent {
+		maxLen := 255
+		out.Parameters = append(out.Parameters, parameter{
+			Name: "Idempotency-Key",
+			In:   "header",
+			Description: "Unique key of this request, e.g. a UUID. A retry with the same key and body gets the stored response " +
+				"with Idempotent-Replayed: true instead of running again.",
+			Schema: &Schema{Type: "string", MaxLength: &maxLen},
+		})
+		errorStatuses = append(errorStatuses, http.StatusConflict, http.StatusUnprocessableEntity)
+	}
+
+	if op.Request != nil {
+		out.RequestBody = &requestBody{
+			Required: true,
+			Content:  map[string]mediaType{"application/json": {Schema: sch.of(op.Request)}},
+		}
+		errorStatuses = append(errorStatuses, http.StatusBadRequest, http.StatusRequestEntityTooLarge, http.StatusUnsupportedMediaType)
+	}
+
+	if op.Auth || op.Permission != "" {
+		out.Security = []map[string][]string{{bearerAuth: {}}}
+		errorStatuses = append(errorStatuses, http.StatusUnauthorized, http.StatusForbidden)
+	}
+	if op.Permission != "" {
+		out.Description = strings.TrimSpace(out.Description + "\n\nRequires the `" + op.Permission + "` permission.")
+	}
+
+	status := op.Status
+	if status == 0 {
+		status = http.StatusOK
+	}
+	success := response{Description: http.StatusText(status)}
+	if op.Response != nil {
+		contentType := op.ContentType
+		schema := &Schema{Type: "string"}
+		if contentType == "" {
+			contentType = "application/json"
+			schema = sch.of(op.Response)
+		}
+		success.Content = map[string]mediaType{contentType: {Schema: schema}}
+	}
+	if op.Conditional {
+		validators := map[string]header{
+			"ETag":          {Description: "Strong entity tag of the content", Schema: &Schema{Type: "string"}},
+			"Last-Modified": {Description: "When the content last changed, if known", Schema: &Schema{Type: "string"}},
+			"Cache-Control": {Schema: &Schema{Type: "string"}},
+		}
+		success.Headers = validators
+		out.Responses[strconv.Itoa(http.StatusNotModified)] = response{
+			Description: "The cached copy named by If-None-Match or If-Modified-Since is current",
+			Headers:     validators,
+		}
+		out.Parameters = append(out.Parameters,
+			parameter{Name: "If-None-Match", In: "header", Description: "ETags of cached copies", Schema: &Schema{Type: "string"}},
+			parameter{Name: "If-Modified-Since", In: "header", Description: "Date of a cached copy; ignored when If-None-Match is sent", Schema: &Schema{Type: "string"}},
+		)
+	}
+	out.Responses[strconv.Itoa(status)] = success
+
+	for _, s := range errorStatuses {
+		out.Responses[strconv.Itoa(s)] = response{
+			Description: http.StatusText(s),
+			Content:     map[string]mediaType{"application/json": {Schema: errorRef}},
+		}
+	}
+	out.Responses["default"] = response{
+		Description: "Error",
+		Content:     map[string]mediaType{"application/json": {Schema: errorRef}},
+	}
+
+	return out
+}
+
+// operationID turns "PATCH /admin/users/{uid}/role" into
+// "patchAdminUsersUidRole"
+func operationID(rt router.Route) string {
+	var b strings.Builder
+	b.WriteString(strings.ToLower(rt.Method))
+	words := strings.FieldsFunc(rt.Path, func(r rune) bool {
+		return !('a' <= r && r <= 'z' || 'A' <= r && r <= 'Z' || '0' <= r && r <= '9')
+	})
+	for _, w := range words {
+		b.WriteString(strings.ToUpper(w[:1]) + w[1:])
+	}
+	return b.String()
+}
@@ -0,0 +1,210 @@
+package openapi
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+
+	"mypremier-backend/internal/validate"
+)
+
+// Schema is a JSON Schema (2020-12) object as used by OpenAPI 3.1
+type Schema struct {
+	Ref                  string             `json:"$ref,omitempty"`
+	Type                 interface{}        `json:"type,omitempty"`
+	Format               string             `json:"format,omitempty"`
+	Description          string             `json:"description,omitempty"`
+	Enum                 []string           `json:"enum,omitempty"`
+	MinLength            *int               `json:"minLength,omitempty"`
+	MaxLength            *int               `json:"maxLength,omitempty"`
+	MinItems             *int               `json:"minItems,omitempty"`
+	MaxItems             *int               `json:"maxItems,omitempty"`
+	Items                *Schema            `json:"items,omitempty"`
+	Properties           map[string]*Schema `json:"properties,omitempty"`
+	Required             []string           `json:"required,omitempty"`
+	AdditionalProperties interface{}        `json:"additionalProperties,omitempty"`
+}
+
+var (
+	timeType       = reflect.TypeOf(time.Time{})
+	rawMessageType = reflect.TypeOf(json.RawMessage{})
+)
+
+// schemas collects the named component schemas reachable from operations
+type schemas struct {
+	components map[string]*Schema
+	// owners maps component names to their Go type, to catch two packages
+	// exporting types with the same name
+	owners map[string]reflect.Type
+	errs   []error
+}
+
+func newSchemas() *schemas {
+	return &schemas{
+		components: make(map[string]*Schema),
+		owners:     make(map[string]reflect.Type),
+	}
+}
+
+// of returns the schema of a Go value's type. Named struct types become
+// components referenced by $ref.
+func (s *schemas) of(v interface{}) *Schema {
+	return s.forType(reflect.TypeOf(v))
+}
+
+func (s *schemas) forType(t reflect.Type) *Schema {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+
+	switch t {
+	case timeType:
+		return &Schema{Type: "string", Format: "date-time"}
+	case rawMessageType:
+		return &Schema{}
+	}
+
+	switch t.Kind() {
+	case reflect.String:
+		return &Schema{Type: "string"}
+	case reflect.Bool:
+		return &Schema{Type: "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		sch := &Schema{Type: "integer"}
+		if t.Size() == 8 {
+			sch.Format = "int64"
+		} else {
+			sch.Format = "int32"
+		}
+		return sch
+	case reflect.Float32, reflect.Float64:
+		return &Schema{Type: "number"}
+	case reflect.Slice, reflect.Array:
+		// nil slices encode as null
+		return &Schema{Type: []string{"array", "null"}, Items: s.forType(t.Elem())}
+	case reflect.Map:
+		return &Schema{Type: []string{"object", "null"}, AdditionalProperties: s.forType(t.Elem())}
+	case reflect.Interface:
+		// Any JSON value
+		return &Schema{}
+	case reflect.Struct:
+		if t.Name() == "" {
+			return s.object(t)
+		}
+		return s.ref(t)
+	default:
+		s.errs = append(s.errs, fmt.Errorf("openapi: unsupported type %s", t))
+		return &Schema{}
+	}
+}
+
+// ref registers a named struct as a component and returns a reference to it
+func (s *schemas) ref(t reflect.Type) *Schema {
+	name := t.Name()
+	if owner, ok := s.owners[name]; ok {
+		if owner != t {
+			s.errs = append(s.errs, fmt.Errorf("openapi: schema name %s is used by both %s and %s", name, owner, t))
+		}
+	} else {
+		s.owners[name] = t
+		// Register before recursing so self-references terminate
+		s.components[name] = &Schema{}
+		*s.components[name] = *s.object(t)
+	}
+
+	return &Schema{Ref: "#/components/schemas/" + name}
+}
+
+// object describes a struct by its JSON encoding
+func (s *schemas) object(t reflect.Type) *Schema {
+	sch := &Schema{Type: "object", Properties: make(map[string]*Schema)}
+	s.fields(t, sch)
+	return sch
+}
+
+func (s *schemas) fields(t reflect.Type, sch *Schema) {
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		tag := f.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+		name, _, _ := strings.Cut(tag, ",")
+
+		// Embedded structs without a name are flattened, like encoding/json does
+		if f.Anonymous && name == "" {
+			ft := f.Type
+			if ft.Kind() == reflect.Pointer {
+				ft = ft.Elem()
+			}
+			if ft.Kind() == reflect.Struct {
+				s.fields(ft, sch)
+				continue
+			}
+		}
+		if !f.IsExported() {
+			continue
+		}
+		if name == "" {
+			name = f.Name
+		}
+
+		prop := s.forType(f.Type)
+		fieldRules, itemRules := validate.ParseTag(f.Tag.Get("validate"))
+		if constrain(prop, fieldRules) {
+			sch.Required = append(sch.Required, name)
+		}
+		if prop.Items != nil {
+			constrain(prop.Items, itemRules)
+		}
+		sch.Properties[name] = prop
+	}
+}
+
+// constrain adds the validate rules of a field to its schema and reports
+// whether the field is required. References are left alone; their
+// constraints are in the component.
+func constrain(sch *Schema, rules []validate.Rule) (required bool) {
+	if sch.Ref != "" {
+		return false
+	}
+	isString := sch.Type == "string"
+	for _, r := range rules {
+		n := r.Int()
+		switch r.Name {
+		case "required":
+			required = true
+			if isString {
+				sch.MinLength = &one
+			} else if sch.Items != nil {
+				sch.MinItems = &one
+			}
+		case "min":
+			if isString {
+				sch.MinLength = &n
+			} else if sch.Items != nil {
+				sch.MinItems = &n
+			}
+		case "max":
+			if isString {
+				sch.MaxLength = &n
+			} else if sch.Items != nil {
+				sch.MaxItems = &n
+			}
+		case "oneof":
+			sch.Enum = r.Options()
+		case "url":
+			sch.Format = "uri"
+		case "email":
+			sch.Format = "email"
+		case "phone":
+			sch.Description = "Phone number, optionally starting with + and a country code"
+		}
+	}
+	return required
+}
+
+var one = 1
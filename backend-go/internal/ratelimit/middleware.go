@@ -0,0 +1,64 @@
+package ratelimit
+
+import (
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"mypremier-backend/internal/apierror"
+	"mypremier-backend/internal/logging"
+	"mypremier-backend/internal/metrics"
+)
+
+// Limiter enforces limits per client IP and route
+type Limiter struct {
+	store    Store
+	clientIP func(*http.Request) string
+	now      func() time.Time
+}
+
+// New creates a limiter keeping buckets in store. clientIP identifies the
+// caller, see clientip.Resolver.
+func New(store Store, clientIP func(*http.Request) string) *Limiter {
+	return &Limiter{
+		store:    store,
+		clientIP: clientIP,
+		now:      time.Now,
+	}
+}
+
+// Middleware limits requests to route, which names the bucket, e.g. the
+// route pattern. Over the limit it answers 429 with Retry-After. If the
+// store fails the request is let through, so an outage of the store does
+// not take the API down with it.
+func (l *Limiter) Middleware(route string, limit Limit) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ip := l.clientIP(r)
+			res, err := l.store.Take(r.Context(), route+"|"+ip, limit, l.now())
+			if err != nil {
+				logging.FromContext(r.Context()).Warn("rate limiter unavailable, allowing request", "route", route, "err", err)
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			w.Header().Set("X-RateLimit-Limit", strconv.Itoa(int(limit.capacity())))
+			w.Header().Set("X-RateLimit-Remaining", strconv.Itoa(res.Remaining))
+			if !res.Allowed {
+				seconds := int(math.Ceil(res.RetryAfter.Seconds()))
+				if seconds < 1 {
+					seconds = 1
+				}
+				metrics.RateLimited.WithLabelValues(route).Inc()
+				logging.FromContext(r.Context()).Info("rate limited", "route", route, "client_ip", ip, "retry_after_s", seconds)
+				w.Header().Set("Retry-After", strconv.Itoa(seconds))
+				apierror.Write(w, r, apierror.New(http.StatusTooManyRequests, "rate_limited", "Too many requests, retry in "+strconv.Itoa(seconds)+"s").
+					WithDetails(map[string]int{"retry_after_seconds": seconds}))
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
@@ -0,0 +1,64 @@
+package ratelimit
+
+import (
+	"context"
+	"math"
+	"time"
+)
+
+// Limit is a token bucket: Burst tokens at most, refilled at Requests per Per
+type Limit struct {
+	Requests int
+	Per      time.Duration
+	Burst    int
+}
+
+// rate returns the refill rate in tokens per second
+func (l Limit) rate() float64 {
+	return float64(l.Requests) / l.Per.Seconds()
+}
+
+// capacity returns the bucket size, which defaults to Requests
+func (l Limit) capacity() float64 {
+	if l.Burst > 0 {
+		return float64(l.Burst)
+	}
+	return float64(l.Requests)
+}
+
+// fillTime is how long an empty bucket takes to fill up; idle buckets can be
+// forgotten after it
+func (l Limit) fillTime() time.Duration {
+	return time.Duration(l.capacity() / l.rate() * float64(time.Second))
+}
+
+// Result is the outcome of taking a token
+type Result struct {
+	Allowed bool
+	// Remaining is the number of whole tokens left
+	Remaining int
+	// RetryAfter is how long until a token is available when not allowed
+	RetryAfter time.Duration
+}
+
+// Store keeps token buckets. Take removes a token from the bucket at key,
+// creating a full bucket first if there is none.
+type Store interface {
+	Take(ctx context.Context, key string, limit Limit, now time.Time) (Result, error)
+}
+
+// take refills a bucket that held tokens at last and tries to remove one.
+// It returns the new token count.
+func take(tokens float64, last, now time.Time, limit Limit) (float64, Result) {
+	if elapsed := now.Sub(last); elapsed > 0 {
+		tokens = math.Min(limit.capacity(), tokens+elapsed.Seconds()*limit.rate())
+	}
+
+	if tokens >= 1 {
+		tokens--
+		return tokens, Result{Allowed: true, Remaining: int(tokens)}
+	}
+
+	wait := time.Duration((1 - tokens) / limit.rate() * float64(time.Second))
+	return tokens, Result{RetryAfter: wait}
+}
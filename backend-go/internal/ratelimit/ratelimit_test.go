@@ -0,0 +1,101 @@
+package ratelimit
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+)
+
+func stores(t *testing.T) map[string]Store {
+	mr := miniredis.RunT(t)
+	rdb := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() { rdb.Close() })
+
+	return map[string]Store{
+		"memory": NewMemoryStore(),
+		"redis":  NewRedisStore(rdb, "test:"),
+	}
+}
+
+func TestStoreTokenBucket(t *testing.T) {
+	limit := Limit{Requests: 2, Per: time.Second, Burst: 3}
+	start := time.Unix(1700000000, 0)
+
+	steps := []struct {
+		after      time.Duration
+		allowed    bool
+		remaining  int
+		retryAfter time.Duration
+	}{
+		{0, true, 2, 0},
+		{0, true, 1, 0},
+		{0, true, 0, 0},
+		{0, false, 0, 500 * time.Millisecond},
+		{250 * time.Millisecond, false, 0, 250 * time.Millisecond},
+		{500 * time.Millisecond, true, 0, 0},
+		// A long pause refills up to Burst and no further
+		{10 * time.Second, true, 2, 0},
+	}
+
+	for name, store := range stores(t) {
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+			for i, step := range steps {
+				res, err := store.Take(ctx, "POST /support|192.0.2.1", limit, start.Add(step.after))
+				if err != nil {
+					t.Fatalf("step %d: %v", i, err)
+				}
+				if res.Allowed != step.allowed || res.Remaining != step.remaining || res.RetryAfter != step.retryAfter {
+					t.Errorf("step %d: got %+v, want allowed=%v remaining=%d retry_after=%s",
+						i, res, step.allowed, step.remaining, step.retryAfter)
+				}
+			}
+
+			// Other keys have buckets of their own
+			res, err := store.Take(ctx, "POST /support|192.0.2.2", limit, start)
+			if err != nil || !res.Allowed {
+				t.Errorf("other client: got %+v, %v", res, err)
+			}
+		})
+	}
+}
+
+func TestMiddleware(t *testing.T) {
+	limit := Limit{Requests: 1, Per: time.Minute}
+
+	for name, store := range stores(t) {
+		t.Run(name, func(t *testing.T) {
+			limiter := New(store, func(r *http.Request) string { return r.RemoteAddr })
+			handler := limiter.Middleware("POST /request-info", limit)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusCreated)
+			}))
+
+			send := func(addr string) *httptest.ResponseRecorder {
+				req := httptest.NewRequest(http.MethodPost, "/request-info", nil)
+				req.RemoteAddr = addr
+				rec := httptest.NewRecorder()
+				handler.ServeHTTP(rec, req)
+				return rec
+			}
+
+			if rec := send("192.0.2.1"); rec.Code != http.StatusCreated {
+				t.Fatalf("first request: status %d", rec.Code)
+			}
+			rec := send("192.0.2.1")
+			if rec.Code != http.StatusTooManyRequests {
+				t.Fatalf("second request: status %d, want 429", rec.Code)
+			}
+			if got := rec.Header().Get("Retry-After"); got != "60" {
+				t.Errorf("Retry-After = %q, want 60", got)
+			}
+			if rec := send("192.0.2.2"); rec.Code != http.StatusCreated {
+				t.Errorf("other client: status %d", rec.Code)
+			}
+		})
+	}
+}
@@ -0,0 +1,91 @@
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// takeScript is the token bucket of take, run atomically in Redis. The
+// bucket is a hash of the token count and the time of the last take in
+// milliseconds, and expires once it would be full again.
+var takeScript = redis.NewScript(`
+local rate = tonumber(ARGV[1])
+local capacity = tonumber(ARGV[2])
+local now = tonumber(ARGV[3])
+
+local state = redis.call("HMGET", KEYS[1], "tokens", "last")
+local tokens = tonumber(state[1])
+local last = tonumber(state[2])
+if tokens == nil or last == nil then
+  tokens = capacity
+  last = now
+end
+
+if now > last then
+  tokens = math.min(capacity, tokens + (now - last) * rate)
+  last = now
+end
+
+local allowed = 0
+local wait = 0
+if tokens >= 1 then
+  tokens = tokens - 1
+  allowed = 1
+else
+  wait = math.ceil((1 - tokens) / rate)
+end
+
+redis.call("HSET", KEYS[1], "tokens", tostring(tokens), "last", tostring(last))
+redis.call("PEXPIRE", KEYS[1], math.ceil((capacity - tokens) / rate) + 1000)
+
+-- Lua numbers become integers in replies, so send the token count as text
+return {allowed, tostring(tokens), wait}
+`)
+
+// RedisStore keeps buckets in Redis, or anything speaking its protocol and
+// Lua scripting, so limits hold across replicas
+type RedisStore struct {
+	client redis.Scripter
+	prefix string
+}
+
+// NewRedisStore stores buckets under keys starting with prefix
+func NewRedisStore(client redis.Scripter, prefix string) *RedisStore {
+	return &RedisStore{
+		client: client,
+		prefix: prefix,
+	}
+}
+
+func (s *RedisStore) Take(ctx context.Context, key string, limit Limit, now time.Time) (Result, error) {
+	perMilli := limit.rate() / 1000
+	reply, err := takeScript.Run(ctx, s.client, []string{s.prefix + key},
+		strconv.FormatFloat(perMilli, 'g', -1, 64),
+		strconv.FormatFloat(limit.capacity(), 'g', -1, 64),
+		now.UnixMilli(),
+	).Slice()
+	if err != nil {
+		return Result{}, fmt.Errorf("rate limit script: %w", err)
+	}
+	if len(reply) != 3 {
+		return Result{}, fmt.Errorf("rate limit script: unexpected reply %v", reply)
+	}
+
+	allowed, _ := reply[0].(int64)
+	tokensText, _ := reply[1].(string)
+	wait, _ := reply[2].(int64)
+	tokens, err := strconv.ParseFloat(tokensText, 64)
+	if err != nil {
+		return Result{}, fmt.Errorf("rate limit script: invalid token count %q", tokensText)
+	}
+
+	return Result{
+		Allowed:    allowed == 1,
+		Remaining:  int(tokens),
+		RetryAfter: time.Duration(wait) * time.Millisecond,
+	}, nil
+}
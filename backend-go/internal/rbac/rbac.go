@@ -0,0 +1,79 @@
+// Package rbac decides what each role may do. Routes require a permission;
+// roles, defined in configuration and kept in a Registry, grant a set of
+// permissions.
+package rbac
+
+import (
+	"context"
+	"net/http"
+
+	"mypremier-backend/internal/apierror"
+	"mypremier-backend/internal/logging"
+	"mypremier-backend/internal/metrics"
+	"mypremier-backend/internal/middleware"
+)
+
+// Permission names an action on a kind of data, e.g. product:write
+type Permission string
+
+const (
+	ProductRead     Permission = "product:read"
+	ProductWrite    Permission = "product:write"
+	CategoryRead    Permission = "category:read"
+	CategoryWrite   Permission = "category:write"
+	RequestRead     Permission = "request:read"
+	SupportRead     Permission = "support:read"
+	SupportWrite    Permission = "support:write"
+	QuarantineRead  Permission = "quarantine:read"
+	QuarantineWrite Permission = "quarantine:write"
+	UserRead        Permission = "user:read"
+	UserManage      Permission = "user:manage"
+	StatsRead       Permission = "stats:read"
+	AuditRead       Permission = "audit:read"
+)
+
+// Permissions lists every permission routes can require
+var Permissions = []Permission{
+	ProductRead, ProductWrite, CategoryRead, CategoryWrite,
+	RequestRead, SupportRead, SupportWrite, QuarantineRead, QuarantineWrite,
+	UserRead, UserManage, StatsRead, AuditRead,
+}
+
+// Auditor records denied attempts; *audit.Handler implements it
+type Auditor interface {
+	LogAction(ctx context.Context, action, entity, entityID string) error
+}
+
+// Enforcer checks the caller's role against the registry
+type Enforcer struct {
+	roles   *Registry
+	auditor Auditor
+}
+
+func NewEnforcer(roles *Registry, auditor Auditor) *Enforcer {
+	return &Enforcer{
+		roles:   roles,
+		auditor: auditor,
+	}
+}
+
+// Require lets the request through only if the caller's role grants perm.
+// Others get 403 and the attempt is written to the audit trail. It runs
+// after middleware.LoadUserRole.
+func (e *Enforcer) Require(perm Permission) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ctx := r.Context()
+			role := middleware.GetUserRole(ctx)
+			if e.roles.Allows(role, perm) {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			metrics.AccessDenied.WithLabelValues(string(perm)).Inc()
+			logging.FromContext(ctx).Warn("access denied", "role", role, "permission", perm)
+			_ = e.auditor.LogAction(ctx, "access_denied", "route", r.Pattern)
+			apierror.Write(w, r, apierror.Forbidden("insufficient_permissions", "Your role does not grant "+string(perm)))
+		})
+	}
+}
@@ -0,0 +1,81 @@
+package rbac
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"slices"
+
+	"mypremier-backend/internal/logging"
+)
+
+// Role is a role users can be assigned, with the permissions it grants
+type Role struct {
+	Name        string       `json:"name"`
+	DisplayName string       `json:"display_name"`
+	Description string       `json:"description"`
+	Permissions []Permission `json:"permissions"`
+}
+
+// Registry holds the roles of the deployment. It is built once at startup
+// and read-only afterwards.
+type Registry struct {
+	roles  []Role
+	byName map[string]Role
+}
+
+// NewRegistry checks roles and returns a registry of them. Duplicate names
+// and unknown permissions are errors, so a typo fails at startup rather
+// than silently denying access.
+func NewRegistry(roles []Role) (*Registry, error) {
+	reg := &Registry{
+		byName: make(map[string]Role, len(roles)),
+	}
+	for _, role := range roles {
+		if role.Name == "" {
+			return nil, fmt.Errorf("role without a name")
+		}
+		if _, ok := reg.byName[role.Name]; ok {
+			return nil, fmt.Errorf("role %q is defined more than once", role.Name)
+		}
+		for _, perm := range role.Permissions {
+			if !slices.Contains(Permissions, perm) {
+				return nil, fmt.Errorf("role %q grants unknown permission %q", role.Name, perm)
+			}
+		}
+		role.Permissions = slices.Clone(role.Permissions)
+		reg.roles = append(reg.roles, role)
+		reg.byName[role.Name] = role
+	}
+
+	return reg, nil
+}
+
+// Valid reports whether a role with the given name exists
+func (reg *Registry) Valid(name string) bool {
+	_, ok := reg.byName[name]
+	return ok
+}
+
+// Names returns the role names in registry order
+func (reg *Registry) Names() []string {
+	names := make([]string, len(reg.roles))
+	for i, role := range reg.roles {
+		names[i] = role.Name
+	}
+	return names
+}
+
+// Allows reports whether the named role grants perm. Unknown roles grant
+// nothing.
+func (reg *Registry) Allows(name string, perm Permission) bool {
+	return slices.Contains(reg.byName[name].Permissions, perm)
+}
+
+// GetRoles lists the roles with their metadata and permissions
+func (reg *Registry) GetRoles(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(reg.roles); err != nil {
+		logging.FromContext(r.Context()).Error("encoding response", "err", err)
+	}
+}
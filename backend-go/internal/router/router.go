@@ -0,0 +1,126 @@
+package router
+
+import (
+	"fmt"
+	"net/http"
+
+	"mypremier-backend/internal/apierror"
+	"mypremier-backend/internal/logging"
+
+	semconv "go.opentelemetry.io/otel/semconv/v1.43.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Middleware wraps a handler with extra behaviour (auth, roles, rate limits)
+type Middleware func(http.Handler) http.Handler
+
+// Route is one entry of the route table
+type Route struct {
+	// Method is the HTTP method. GET routes also answer HEAD.
+	Method string
+	// Path is a net/http ServeMux path pattern, e.g. /admin/products/{id}.
+	// Wildcards match a single path segment.
+	Path    string
+	Handler http.HandlerFunc
+	// Middleware wraps Handler for this route only. The first entry is the
+	// outermost, so it runs first.
+	Middleware []Middleware
+}
+
+// Pattern returns the ServeMux pattern for the route, e.g. "GET /products"
+func (rt Route) Pattern() string {
+	return rt.Method + " " + rt.Path
+}
+
+// Router dispatches requests using a declarative route table on top of
+// http.ServeMux. Paths that match a route under another method get a 405
+// with an Allow header, and anything else a 404, both as JSON errors.
+type Router struct {
+	mux     *http.ServeMux
+	routes  []Route
+	methods []string
+}
+
+// New builds a router from the route table. It panics on invalid or
+// conflicting patterns, which are programming errors.
+func New(routes []Route) *Router {
+	r := &Router{
+		mux: http.NewServeMux(),
+	}
+	for _, rt := range routes {
+		r.Handle(rt)
+	}
+
+	return r
+}
+
+// Handle adds a route
+func (r *Router) Handle(rt Route) {
+	if rt.Method == "" || rt.Path == "" || rt.Handler == nil {
+		panic(fmt.Sprintf("router: incomplete route %q", rt.Pattern()))
+	}
+
+	pattern := rt.Pattern()
+	handler := Chain(rt.Middleware...)(rt.Handler)
+	r.mux.Handle(pattern, http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		logging.Info(req.Context()).SetRoute(pattern)
+		span := trace.SpanFromContext(req.Context())
+		span.SetName(pattern)
+		span.SetAttributes(semconv.HTTPRoute(rt.Path))
+		handler.ServeHTTP(w, req)
+	}))
+	r.routes = append(r.routes, rt)
+
+	for _, m := range r.methods {
+		if m == rt.Method {
+			return
+		}
+	}
+	r.methods = append(r.methods, rt.Method)
+	if rt.Method == http.MethodGet {
+		r.methods = append(r.methods, http.MethodHead)
+	}
+}
+
+// Routes returns the registered route table
+func (r *Router) Routes() []Route {
+	return append([]Route(nil), r.routes...)
+}
+
+func (r *Router) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	// Let ServeMux handle matches, including redirects for unclean paths
+	if _, pattern := r.mux.Handler(req); pattern != "" {
+		r.mux.ServeHTTP(w, req)
+		return
+	}
+
+	if allow := r.allowedMethods(req); len(allow) > 0 {
+		apierror.MethodNotAllowed(w, req, allow)
+		return
+	}
+	apierror.NotFoundHandler(w, req)
+}
+
+// allowedMethods lists the methods that have a route for the request path
+func (r *Router) allowedMethods(req *http.Request) []string {
+	var allow []string
+	probe := req.Clone(req.Context())
+	for _, m := range r.methods {
+		probe.Method = m
+		if _, pattern := r.mux.Handler(probe); pattern != "" {
+			allow = append(allow, m)
+		}
+	}
+
+	return allow
+}
+
+// Chain composes middleware so that the first one runs first
+func Chain(middleware ...Middleware) Middleware {
+	return func(next http.Handler) http.Handler {
+		for i := len(middleware) - 1; i >= 0; i-- {
+			next = middleware[i](next)
+		}
+		return next
+	}
+}
@@ -0,0 +1,175 @@
+package router_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"mypremier-backend/internal/router"
+)
+
+// echo answers with the route it was registered as and its path parameters
+func echo(name string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Route", name)
+		w.Write([]byte(r.PathValue("id") + "|" + r.PathValue("code")))
+	}
+}
+
+func newRouter() *router.Router {
+	return router.New([]router.Route{
+		{Method: http.MethodGet, Path: "/admin/products", Handler: echo("list")},
+		{Method: http.MethodPost, Path: "/admin/products", Handler: echo("create")},
+		{Method: http.MethodGet, Path: "/admin/products/{id}", Handler: echo("get")},
+		{Method: http.MethodPut, Path: "/admin/products/{id}", Handler: echo("update")},
+		{Method: http.MethodDelete, Path: "/admin/products/{id}", Handler: echo("delete")},
+		{Method: http.MethodGet, Path: "/products/{id}/variants/{code}", Handler: echo("variant")},
+	})
+}
+
+func TestRouting(t *testing.T) {
+	tests := []struct {
+		method    string
+		path      string
+		wantRoute string
+		wantBody  string
+	}{
+		{http.MethodGet, "/admin/products", "list", "|"},
+		{http.MethodPost, "/admin/products", "create", "|"},
+		{http.MethodGet, "/admin/products/abc", "get", "abc|"},
+		{http.MethodPut, "/admin/products/abc", "update", "abc|"},
+		{http.MethodDelete, "/admin/products/a%20b", "delete", "a b|"},
+		{http.MethodGet, "/products/p1/variants/red", "variant", "p1|red"},
+		{http.MethodHead, "/admin/products/abc", "get", "abc|"},
+	}
+	r := newRouter()
+	for _, tt := range tests {
+		t.Run(tt.method+" "+tt.path, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			r.ServeHTTP(rec, httptest.NewRequest(tt.method, tt.path, nil))
+			if rec.Code != http.StatusOK || rec.Header().Get("X-Route") != tt.wantRoute {
+				t.Fatalf("status %d, route %q; want 200 from %q", rec.Code, rec.Header().Get("X-Route"), tt.wantRoute)
+			}
+			if rec.Body.String() != tt.wantBody {
+				t.Errorf("body %q, want %q", rec.Body, tt.wantBody)
+			}
+		})
+	}
+}
+
+func TestUnmatched(t *testing.T) {
+	tests := []struct {
+		method    string
+		path      string
+		wantCode  int
+		wantErr   string
+		wantAllow string
+	}{
+		{http.MethodPatch, "/admin/products", http.StatusMethodNotAllowed, "method_not_allowed", "GET, HEAD, POST"},
+		{http.MethodPost, "/admin/products/abc", http.StatusMethodNotAllowed, "method_not_allowed", "GET, HEAD, PUT, DELETE"},
+		{http.MethodDelete, "/products/p1/variants/red", http.StatusMethodNotAllowed, "method_not_allowed", "GET, HEAD"},
+		{http.MethodGet, "/admin/products/abc/def", http.StatusNotFound, "not_found", ""},
+		{http.MethodGet, "/admin/orders", http.StatusNotFound, "not_found", ""},
+		{http.MethodGet, "/products/p1/variants", http.StatusNotFound, "not_found", ""},
+		{http.MethodDelete, "/nowhere", http.StatusNotFound, "not_found", ""},
+	}
+	r := newRouter()
+	for _, tt := range tests {
+		t.Run(tt.method+" "+tt.path, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			r.ServeHTTP(rec, httptest.NewRequest(tt.method, tt.path, nil))
+			if rec.Code != tt.wantCode {
+				t.Fatalf("status %d, want %d", rec.Code, tt.wantCode)
+			}
+			if got := rec.Header().Get("Allow"); got != tt.wantAllow {
+				t.Errorf("Allow = %q, want %q", got, tt.wantAllow)
+			}
+			if ct := rec.Header().Get("Content-Type"); !strings.HasPrefix(ct, "application/json") {
+				t.Errorf("Content-Type = %q, want JSON", ct)
+			}
+			var body map[string]interface{}
+			if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+				t.Fatalf("body is not JSON: %s", rec.Body)
+			}
+			if !strings.Contains(rec.Body.String(), `"code":"`+tt.wantErr+`"`) {
+				t.Errorf("body %s, want code %s", rec.Body, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestUncleanPathRedirects(t *testing.T) {
+	rec := httptest.NewRecorder()
+	newRouter().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/admin//products/../products/abc", nil))
+	if rec.Code/100 != 3 || rec.Header().Get("Location") != "/admin/products/abc" {
+		t.Errorf("status %d, Location %q; want a redirect to the clean path", rec.Code, rec.Header().Get("Location"))
+	}
+}
+
+func TestMiddlewareOrder(t *testing.T) {
+	var order []string
+	tag := func(name string) router.Middleware {
+		return func(next http.Handler) http.Handler {
+			return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				order = append(order, name)
+				next.ServeHTTP(w, r)
+			})
+		}
+	}
+	r := router.New([]router.Route{{
+		Method:     http.MethodGet,
+		Path:       "/x",
+		Handler:    func(w http.ResponseWriter, r *http.Request) { order = append(order, "handler") },
+		Middleware: []router.Middleware{tag("auth"), tag("role")},
+	}, {
+		Method:  http.MethodGet,
+		Path:    "/y",
+		Handler: func(w http.ResponseWriter, r *http.Request) { order = append(order, "other") },
+	}})
+
+	r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/x", nil))
+	r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/y", nil))
+	if got := strings.Join(order, ","); got != "auth,role,handler,other" {
+		t.Errorf("ran %s, want auth,role,handler,other", got)
+	}
+}
+
+func TestNewPanics(t *testing.T) {
+	noop := func(w http.ResponseWriter, r *http.Request) {}
+	tests := []struct {
+		name   string
+		routes []router.Route
+	}{
+		{"no method", []router.Route{{Path: "/x", Handler: noop}}},
+		{"no path", []router.Route{{Method: http.MethodGet, Handler: noop}}},
+		{"no handler", []router.Route{{Method: http.MethodGet, Path: "/x"}}},
+		{"duplicate", []router.Route{
+			{Method: http.MethodGet, Path: "/x/{id}", Handler: noop},
+			{Method: http.MethodGet, Path: "/x/{key}", Handler: noop},
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			defer func() {
+				if recover() == nil {
+					t.Error("New did not panic")
+				}
+			}()
+			router.New(tt.routes)
+		})
+	}
+}
+
+func TestRoutes(t *testing.T) {
+	r := newRouter()
+	routes := r.Routes()
+	if len(routes) != 6 || routes[2].Pattern() != "GET /admin/products/{id}" {
+		t.Fatalf("Routes = %v", routes)
+	}
+	routes[0].Path = "/changed"
+	if r.Routes()[0].Path != "/admin/products" {
+		t.Error("Routes returned the router's own table")
+	}
+}
@@ -0,0 +1,83 @@
+package store
+
+import (
+	"errors"
+	"fmt"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// Sentinel error kinds returned by every repository. Check them with
+// errors.Is; use errors.As with *Error for the entity and field involved.
+var (
+	ErrNotFound = errors.New("not found")
+	ErrConflict = errors.New("conflict")
+	ErrInvalid  = errors.New("invalid")
+)
+
+// Error is a domain error raised by a repository
+type Error struct {
+	// Kind is ErrNotFound, ErrConflict or ErrInvalid
+	Kind error
+	// Entity is the kind of document involved, e.g. "product"
+	Entity string
+	ID     string
+	// Field is the offending input field for ErrInvalid
+	Field   string
+	Message string
+	// Err is the underlying cause, if any
+	Err error
+}
+
+func (e *Error) Error() string {
+	msg := e.Entity + " " + e.Kind.Error()
+	if e.ID != "" {
+		msg += ": " + e.ID
+	}
+	if e.Message != "" {
+		msg += ": " + e.Message
+	}
+	if e.Err != nil {
+		msg += ": " + e.Err.Error()
+	}
+	return msg
+}
+
+func (e *Error) Is(target error) bool {
+	return target == e.Kind
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// NotFound reports a missing document
+func NotFound(entity, id string) error {
+	return &Error{Kind: ErrNotFound, Entity: entity, ID: id}
+}
+
+// Invalid reports an input value the repository refuses to store
+func Invalid(entity, field, message string) error {
+	return &Error{Kind: ErrInvalid, Entity: entity, Field: field, Message: message}
+}
+
+// Conflict reports a write that clashes with existing data
+func Conflict(entity, id, message string) error {
+	return &Error{Kind: ErrConflict, Entity: entity, ID: id, Message: message}
+}
+
+// FromFirestore translates Firestore status codes into domain errors. Other
+// errors are wrapped with op for context.
+func FromFirestore(err error, entity, id, op string) error {
+	switch status.Code(err) {
+	case codes.NotFound:
+		return &Error{Kind: ErrNotFound, Entity: entity, ID: id, Err: err}
+	case codes.AlreadyExists:
+		return &Error{Kind: ErrConflict, Entity: entity, ID: id, Err: err}
+	case codes.InvalidArgument:
+		return &Error{Kind: ErrInvalid, Entity: entity, ID: id, Err: err}
+	default:
+		return fmt.Errorf("failed to %s %s: %w", op, entity, err)
+	}
+}
@@ -0,0 +1,73 @@
+package store
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestFromFirestore(t *testing.T) {
+	tests := []struct {
+		name     string
+		err      error
+		wantKind error
+	}{
+		{"not found", status.Error(codes.NotFound, "no document"), ErrNotFound},
+		{"already exists", status.Error(codes.AlreadyExists, "exists"), ErrConflict},
+		{"invalid argument", status.Error(codes.InvalidArgument, "bad field"), ErrInvalid},
+		{"unavailable", status.Error(codes.Unavailable, "try later"), nil},
+		{"deadline", status.Error(codes.DeadlineExceeded, "slow"), nil},
+		{"permission denied", status.Error(codes.PermissionDenied, "no"), nil},
+		{"not a status", errors.New("boom"), nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := FromFirestore(tt.err, "product", "p1", "get")
+			if !errors.Is(err, tt.err) {
+				t.Errorf("%v does not wrap the cause", err)
+			}
+
+			var storeErr *Error
+			if tt.wantKind == nil {
+				if errors.As(err, &storeErr) {
+					t.Fatalf("FromFirestore = %v, want an error that is not a domain error", err)
+				}
+				if !strings.HasPrefix(err.Error(), "failed to get product: ") {
+					t.Errorf("error %q lacks the operation", err)
+				}
+				return
+			}
+			if !errors.Is(err, tt.wantKind) {
+				t.Fatalf("FromFirestore = %v, want %v", err, tt.wantKind)
+			}
+			for _, other := range []error{ErrNotFound, ErrConflict, ErrInvalid} {
+				if other != tt.wantKind && errors.Is(err, other) {
+					t.Errorf("%v is also %v", err, other)
+				}
+			}
+			if !errors.As(err, &storeErr) || storeErr.Entity != "product" || storeErr.ID != "p1" {
+				t.Errorf("error %+v, want entity product and ID p1", storeErr)
+			}
+		})
+	}
+}
+
+func TestErrorMessage(t *testing.T) {
+	tests := []struct {
+		err  error
+		want string
+	}{
+		{NotFound("product", "p1"), "product not found: p1"},
+		{Conflict("user", "u1", "Email is taken"), "user conflict: u1: Email is taken"},
+		{Invalid("user", "email", "must be an email address"), "user invalid: must be an email address"},
+		{&Error{Kind: ErrNotFound, Entity: "product", Err: errors.New("rpc")}, "product not found: rpc"},
+	}
+	for _, tt := range tests {
+		if got := tt.err.Error(); got != tt.want {
+			t.Errorf("Error() = %q, want %q", got, tt.want)
+		}
+	}
+}
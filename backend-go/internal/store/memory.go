@@ -0,0 +1,149 @@
+package store
+
+import (
+	"crypto/rand"
+	"io"
+	"sort"
+	"sync"
+	"time"
+)
+
+// idAlphabet matches the character set Firestore uses for auto-generated document IDs
+const idAlphabet = "ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz0123456789"
+
+// NewID returns a random 20 character document ID, like firestore.CollectionRef.NewDoc
+func NewID() string {
+	id, err := newID(rand.Reader)
+	if err != nil {
+		panic(err)
+	}
+	return id
+}
+
+// newID draws each character uniformly from idAlphabet. Bytes at or above
+// the largest multiple of the alphabet size are skipped, as taking them
+// modulo the size would favour the first characters.
+func newID(r io.Reader) (string, error) {
+	const limit = 256 - 256%len(idAlphabet)
+
+	id := make([]byte, 0, 20)
+	buf := make([]byte, 32)
+	for len(id) < cap(id) {
+		if _, err := io.ReadFull(r, buf); err != nil {
+			return "", err
+		}
+		for _, b := range buf {
+			if int(b) < limit && len(id) < cap(id) {
+				id = append(id, idAlphabet[int(b)%len(idAlphabet)])
+			}
+		}
+	}
+	return string(id), nil
+}
+
+// Collection is a thread-safe in-memory document collection used by the
+// in-memory repositories. Documents are stored by value and keyed by ID.
+type Collection[T any] struct {
+	mu   sync.RWMutex
+	docs map[string]T
+	now  func() time.Time
+}
+
+// NewCollection creates an empty in-memory collection
+func NewCollection[T any]() *Collection[T] {
+	return &Collection[T]{
+		docs: make(map[string]T),
+		now:  time.Now,
+	}
+}
+
+// ServerTimestamp returns the time used in place of firestore.ServerTimestamp
+func (c *Collection[T]) ServerTimestamp() time.Time {
+	return c.now().UTC()
+}
+
+// Get returns the document with the given ID
+func (c *Collection[T]) Get(id string) (T, bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	doc, ok := c.docs[id]
+	return doc, ok
+}
+
+// Set creates or replaces the document with the given ID
+func (c *Collection[T]) Set(id string, doc T) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.docs[id] = doc
+}
+
+// Update applies fn to an existing document. It reports false if the
+// document does not exist.
+func (c *Collection[T]) Update(id string, fn func(doc *T)) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	doc, ok := c.docs[id]
+	if !ok {
+		return false
+	}
+	fn(&doc)
+	c.docs[id] = doc
+	return true
+}
+
+// Delete removes the document with the given ID. It reports false if the
+// document did not exist.
+func (c *Collection[T]) Delete(id string) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if _, ok := c.docs[id]; !ok {
+		return false
+	}
+	delete(c.docs, id)
+	return true
+}
+
+// Take removes the document with the given ID and returns it. Of several
+// concurrent calls for one document, only one gets it.
+func (c *Collection[T]) Take(id string) (T, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	doc, ok := c.docs[id]
+	if ok {
+		delete(c.docs, id)
+	}
+	return doc, ok
+}
+
+// Query returns the documents matching where (nil matches all), sorted by
+// less. When less is nil, documents are returned in document ID order, which
+// is how Firestore orders an unordered query.
+func (c *Collection[T]) Query(where func(doc T) bool, less func(a, b T) bool) []T {
+	c.mu.RLock()
+	ids := make([]string, 0, len(c.docs))
+	for id, doc := range c.docs {
+		if where == nil || where(doc) {
+			ids = append(ids, id)
+		}
+	}
+	sort.Strings(ids)
+
+	var docs []T
+	for _, id := range ids {
+		docs = append(docs, c.docs[id])
+	}
+	c.mu.RUnlock()
+
+	if less != nil {
+		sort.SliceStable(docs, func(i, j int) bool {
+			return less(docs[i], docs[j])
+		})
+	}
+
+	return docs
+}
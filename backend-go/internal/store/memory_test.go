@@ -0,0 +1,173 @@
+package store
+
+import (
+	"bytes"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+type doc struct {
+	ID   string
+	Name string
+	Rank int
+}
+
+func names(docs []doc) string {
+	var s []string
+	for _, d := range docs {
+		s = append(s, d.Name)
+	}
+	return strings.Join(s, ",")
+}
+
+func TestNewID(t *testing.T) {
+	seen := make(map[string]bool)
+	for i := 0; i < 1000; i++ {
+		id := NewID()
+		if len(id) != 20 || strings.Trim(id, idAlphabet) != "" {
+			t.Fatalf("NewID = %q, want 20 characters from the Firestore alphabet", id)
+		}
+		if seen[id] {
+			t.Fatalf("NewID repeated %q", id)
+		}
+		seen[id] = true
+	}
+}
+
+// TestNewIDSkipsBiasedBytes feeds newID bytes that would wrap around the
+// alphabet; they must not pick a character
+func TestNewIDSkipsBiasedBytes(t *testing.T) {
+	// 248 to 255 would map to "A" to "H" a fifth time
+	var src []byte
+	for i := 0; i < 20; i++ {
+		src = append(src, 248+byte(i%8), byte(61-i))
+	}
+	for len(src)%32 != 0 {
+		src = append(src, 255)
+	}
+
+	id, err := newID(bytes.NewReader(src))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "9876543210zyxwvutsrq"; id != want {
+		t.Errorf("newID = %q, want %q", id, want)
+	}
+}
+
+func TestCollection(t *testing.T) {
+	c := NewCollection[doc]()
+
+	if _, ok := c.Get("a"); ok {
+		t.Error("Get of a missing document reported it found")
+	}
+	if c.Update("a", func(d *doc) { d.Name = "x" }) {
+		t.Error("Update of a missing document reported success")
+	}
+	if c.Delete("a") {
+		t.Error("Delete of a missing document reported success")
+	}
+	if _, ok := c.Get("a"); ok {
+		t.Error("Update created a missing document")
+	}
+
+	c.Set("a", doc{ID: "a", Name: "Pump"})
+	if d, ok := c.Get("a"); !ok || d.Name != "Pump" {
+		t.Fatalf("Get = %+v, %v", d, ok)
+	}
+	c.Set("a", doc{ID: "a", Name: "Valve"})
+	if d, _ := c.Get("a"); d.Name != "Valve" {
+		t.Errorf("Set did not replace the document: %+v", d)
+	}
+
+	if !c.Update("a", func(d *doc) { d.Rank = 3 }) {
+		t.Fatal("Update of an existing document failed")
+	}
+	if d, _ := c.Get("a"); d.Name != "Valve" || d.Rank != 3 {
+		t.Errorf("after Update: %+v", d)
+	}
+
+	// Documents are stored by value
+	d, _ := c.Get("a")
+	d.Name = "changed"
+	if stored, _ := c.Get("a"); stored.Name != "Valve" {
+		t.Error("changing a returned document changed the stored one")
+	}
+
+	if !c.Delete("a") {
+		t.Fatal("Delete of an existing document failed")
+	}
+	if _, ok := c.Get("a"); ok {
+		t.Error("deleted document still found")
+	}
+}
+
+func TestCollectionQuery(t *testing.T) {
+	c := NewCollection[doc]()
+	for _, d := range []doc{
+		{ID: "c", Name: "C", Rank: 1},
+		{ID: "a", Name: "A", Rank: 2},
+		{ID: "d", Name: "D", Rank: 1},
+		{ID: "b", Name: "B", Rank: 2},
+	} {
+		c.Set(d.ID, d)
+	}
+	byRank := func(a, b doc) bool { return a.Rank < b.Rank }
+	rankTwo := func(d doc) bool { return d.Rank == 2 }
+
+	tests := []struct {
+		name  string
+		where func(doc) bool
+		less  func(a, b doc) bool
+		want  string
+	}{
+		{"all in ID order", nil, nil, "A,B,C,D"},
+		{"where", rankTwo, nil, "A,B"},
+		{"where nothing", func(doc) bool { return false }, nil, ""},
+		{"ordered, ties in ID order", nil, byRank, "C,D,A,B"},
+		{"where and ordered", rankTwo, func(a, b doc) bool { return a.Name > b.Name }, "B,A"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := names(c.Query(tt.where, tt.less)); got != tt.want {
+				t.Errorf("Query = %s, want %s", got, tt.want)
+			}
+		})
+	}
+
+	if docs := NewCollection[doc]().Query(nil, nil); len(docs) != 0 {
+		t.Errorf("Query of an empty collection = %v", docs)
+	}
+}
+
+func TestCollectionServerTimestamp(t *testing.T) {
+	c := NewCollection[doc]()
+	local := time.Date(2026, 3, 1, 19, 0, 0, 0, time.FixedZone("WIB", 7*3600))
+	c.now = func() time.Time { return local }
+
+	got := c.ServerTimestamp()
+	if !got.Equal(local) || got.Location() != time.UTC {
+		t.Errorf("ServerTimestamp = %s, want %s in UTC", got, local)
+	}
+}
+
+func TestCollectionConcurrentUse(t *testing.T) {
+	c := NewCollection[doc]()
+	c.Set("counter", doc{})
+
+	var wg sync.WaitGroup
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			c.Update("counter", func(d *doc) { d.Rank++ })
+			c.Query(nil, nil)
+		}()
+	}
+	wg.Wait()
+	if d, _ := c.Get("counter"); d.Rank != 50 {
+		t.Errorf("Rank = %d after 50 concurrent updates", d.Rank)
+	}
+}
@@ -0,0 +1,42 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"mypremier-backend/internal/metrics"
+	"mypremier-backend/internal/tracing"
+
+	"go.opentelemetry.io/otel/codes"
+	semconv "go.opentelemetry.io/otel/semconv/v1.43.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Track instruments one Firestore operation, e.g. ("products", "get"), with
+// a child span and metrics. Use the returned context for the operation and
+// call the returned function with its error once it completes:
+//
+//	ctx, done := store.Track(ctx, r.collection, "get")
+//	defer func() { done(err) }()
+func Track(ctx context.Context, collection, op string) (context.Context, func(error)) {
+	start := time.Now()
+	ctx, span := tracing.Tracer().Start(ctx, "firestore "+op+" "+collection,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			semconv.DBSystemNameKey.String("gcp.firestore"),
+			semconv.DBCollectionName(collection),
+			semconv.DBOperationName(op),
+		),
+	)
+
+	return ctx, func(err error) {
+		metrics.ObserveFirestore(collection, op, err, time.Since(start))
+		// A missing document is an answer, not a failed call
+		if err != nil && !errors.Is(err, ErrNotFound) {
+			span.RecordError(err)
+			span.SetStatus(codes.Error, err.Error())
+		}
+		span.End()
+	}
+}
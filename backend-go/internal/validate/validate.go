@@ -0,0 +1,302 @@
+// Package validate checks input structs against rules declared in
+// `validate` struct tags, collecting every failure instead of stopping at the
+// first. Rules are separated by commas:
+//
+//	required      the value is not blank (strings), empty (slices, maps) or nil
+//	min=N, max=N  length bounds: characters for strings, items for slices and maps
+//	oneof=a b c   the string is one of the listed values
+//	url           an absolute http or https URL
+//	email         an email address
+//	phone         a phone number, optionally international
+//	dive          the rules after it apply to each slice item
+//
+// Format rules accept empty strings, so optional fields combine them with
+// nothing and required fields add required. Fields are reported by their
+// JSON names.
+package validate
+
+import (
+	"fmt"
+	"net/mail"
+	"net/url"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"unicode/utf8"
+
+	"mypremier-backend/internal/apierror"
+)
+
+// Rule is one parsed rule of a validate tag
+type Rule struct {
+	Name  string
+	Param string
+}
+
+// Int returns the parameter of min and max rules
+func (r Rule) Int() int {
+	n, _ := strconv.Atoi(r.Param)
+	return n
+}
+
+// Options returns the values of a oneof rule
+func (r Rule) Options() []string {
+	return strings.Fields(r.Param)
+}
+
+// Validator is implemented by inputs with checks that tags cannot express.
+// Struct runs it after the tag rules and reports its errors with theirs.
+type Validator interface {
+	Validate() []apierror.FieldError
+}
+
+// ParseTag splits a validate tag into the rules for the field itself and,
+// after dive, the rules for each of its items. It panics on unknown rules,
+// which are programming errors.
+func ParseTag(tag string) (field, items []Rule) {
+	if tag == "" {
+		return nil, nil
+	}
+	dst := &field
+	for _, part := range strings.Split(tag, ",") {
+		name, param, _ := strings.Cut(strings.TrimSpace(part), "=")
+		switch name {
+		case "dive":
+			dst = &items
+			continue
+		case "required", "url", "email", "phone":
+		case "min", "max":
+			if _, err := strconv.Atoi(param); err != nil {
+				panic(fmt.Sprintf("validate: %s needs an integer, got %q", name, param))
+			}
+		case "oneof":
+			if param == "" {
+				panic("validate: oneof needs values")
+			}
+		default:
+			panic(fmt.Sprintf("validate: unknown rule %q", name))
+		}
+		*dst = append(*dst, Rule{Name: name, Param: param})
+	}
+	return field, items
+}
+
+// field is a struct field with rules
+type field struct {
+	index []int
+	name  string
+	rules []Rule
+	items []Rule
+	// nested is set for struct fields, which are validated recursively
+	nested bool
+}
+
+var cache sync.Map // reflect.Type -> []field
+
+func fieldsOf(t reflect.Type) []field {
+	if cached, ok := cache.Load(t); ok {
+		return cached.([]field)
+	}
+
+	var fields []field
+	for _, f := range reflect.VisibleFields(t) {
+		if !f.IsExported() || f.Anonymous {
+			continue
+		}
+		jsonName, _, _ := strings.Cut(f.Tag.Get("json"), ",")
+		if jsonName == "-" {
+			continue
+		}
+		if jsonName == "" {
+			jsonName = f.Name
+		}
+
+		rules, items := ParseTag(f.Tag.Get("validate"))
+		ft := f.Type
+		for ft.Kind() == reflect.Pointer {
+			ft = ft.Elem()
+		}
+		nested := ft.Kind() == reflect.Struct
+		if len(rules) == 0 && len(items) == 0 && !nested {
+			continue
+		}
+		fields = append(fields, field{index: f.Index, name: jsonName, rules: rules, items: items, nested: nested})
+	}
+
+	cache.Store(t, fields)
+	return fields
+}
+
+// Struct checks v, a struct or pointer to one, and returns every failure
+func Struct(v interface{}) []apierror.FieldError {
+	var errs []apierror.FieldError
+	structValue(reflect.ValueOf(v), "", &errs)
+	return errs
+}
+
+func structValue(v reflect.Value, prefix string, errs *[]apierror.FieldError) {
+	for v.Kind() == reflect.Pointer {
+		if v.IsNil() {
+			return
+		}
+		v = v.Elem()
+	}
+	if v.Kind() != reflect.Struct {
+		return
+	}
+
+	for _, f := range fieldsOf(v.Type()) {
+		fv := v.FieldByIndex(f.index)
+		name := prefix + f.name
+		check(fv, name, f.rules, errs)
+
+		if len(f.items) > 0 && fv.Kind() == reflect.Slice {
+			for i := 0; i < fv.Len(); i++ {
+				check(fv.Index(i), fmt.Sprintf("%s[%d]", name, i), f.items, errs)
+			}
+		}
+		if f.nested {
+			structValue(fv, name+".", errs)
+		}
+	}
+
+	var iface interface{}
+	if v.CanAddr() {
+		iface = v.Addr().Interface()
+	} else {
+		iface = v.Interface()
+	}
+	if val, ok := iface.(Validator); ok {
+		for _, e := range val.Validate() {
+			e.Field = prefix + e.Field
+			*errs = append(*errs, e)
+		}
+	}
+}
+
+// check applies rules to v, reporting at most one failure per value
+func check(v reflect.Value, name string, rules []Rule, errs *[]apierror.FieldError) {
+	for _, r := range rules {
+		if msg := apply(v, r); msg != "" {
+			*errs = append(*errs, apierror.FieldError{Field: name, Message: msg})
+			return
+		}
+	}
+}
+
+var phonePattern = regexp.MustCompile(`^\+?[0-9][0-9 ().-]*[0-9]$`)
+
+// apply returns the failure message of rule r on v, or "" if v passes
+func apply(v reflect.Value, r Rule) string {
+	if !v.IsValid() {
+		// A missing map key
+		if r.Name == "required" {
+			return "is required"
+		}
+		return ""
+	}
+	for v.Kind() == reflect.Pointer || v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			if r.Name == "required" {
+				return "is required"
+			}
+			return ""
+		}
+		v = v.Elem()
+	}
+
+	switch r.Name {
+	case "required":
+		switch v.Kind() {
+		case reflect.String:
+			if strings.TrimSpace(v.String()) == "" {
+				return "is required"
+			}
+		case reflect.Slice, reflect.Map:
+			if v.Len() == 0 {
+				return "is required"
+			}
+		}
+	case "min", "max":
+		n, unit := length(v)
+		if unit == "" {
+			return ""
+		}
+		if r.Name == "min" && n < r.Int() {
+			return fmt.Sprintf("must have at least %d %s", r.Int(), unit)
+		}
+		if r.Name == "max" && n > r.Int() {
+			return fmt.Sprintf("must have at most %d %s", r.Int(), unit)
+		}
+	default:
+		if v.Kind() != reflect.String {
+			return "must be a string"
+		}
+		return String(v.String(), r)
+	}
+	return ""
+}
+
+func length(v reflect.Value) (int, string) {
+	switch v.Kind() {
+	case reflect.String:
+		return utf8.RuneCountInString(v.String()), "characters"
+	case reflect.Slice, reflect.Map:
+		return v.Len(), "items"
+	}
+	return 0, ""
+}
+
+// String applies a format rule to s. Empty strings pass.
+func String(s string, r Rule) string {
+	if s == "" {
+		return ""
+	}
+	switch r.Name {
+	case "oneof":
+		for _, opt := range r.Options() {
+			if s == opt {
+				return ""
+			}
+		}
+		return "must be one of: " + strings.Join(r.Options(), ", ")
+	case "url":
+		u, err := url.Parse(s)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return "must be an http or https URL"
+		}
+	case "email":
+		addr, err := mail.ParseAddress(s)
+		if err != nil || addr.Address != s {
+			return "must be an email address"
+		}
+	case "phone":
+		digits := 0
+		for _, c := range s {
+			if c >= '0' && c <= '9' {
+				digits++
+			}
+		}
+		if !phonePattern.MatchString(s) || digits < 6 || digits > 15 {
+			return "must be a phone number"
+		}
+	}
+	return ""
+}
+
+// Map checks the string values of a free-form JSON object. rules holds a
+// validate tag per key; keys without rules are not checked. Fields are
+// reported as prefix.key.
+func Map(prefix string, m map[string]interface{}, rules map[string]string) []apierror.FieldError {
+	var errs []apierror.FieldError
+	for key, tag := range rules {
+		field, _ := ParseTag(tag)
+		check(reflect.ValueOf(m[key]), prefix+"."+key, field, &errs)
+	}
+	// Map iteration order is random; keep responses stable
+	sort.Slice(errs, func(i, j int) bool { return errs[i].Field < errs[j].Field })
+	return errs
+}
@@ -0,0 +1,157 @@
+package validate
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"mypremier-backend/internal/apierror"
+)
+
+type contact struct {
+	Email string `json:"email" validate:"required,email"`
+	Phone string `json:"phone" validate:"phone"`
+}
+
+type form struct {
+	Name     string            `json:"name" validate:"required,min=2,max=5"`
+	Status   string            `json:"status" validate:"oneof=new done"`
+	Website  string            `json:"website" validate:"url"`
+	Images   []string          `json:"images" validate:"max=2,dive,required,url"`
+	Tags     []string          `json:"tags" validate:"required"`
+	Extra    map[string]string `json:"extra" validate:"max=1"`
+	Note     *string           `json:"note" validate:"required"`
+	Contact  contact           `json:"contact"`
+	Backup   *contact          `json:"backup"`
+	Internal string            `json:"-" validate:"required"`
+	Untagged string
+}
+
+func fe(field, message string) apierror.FieldError {
+	return apierror.FieldError{Field: field, Message: message}
+}
+
+func valid() form {
+	note := "n"
+	return form{
+		Name:    "Amal",
+		Tags:    []string{"a"},
+		Note:    &note,
+		Contact: contact{Email: "amal@example.com"},
+	}
+}
+
+func TestStruct(t *testing.T) {
+	tests := []struct {
+		name   string
+		change func(f *form)
+		want   []apierror.FieldError
+	}{
+		{"valid", func(f *form) {}, nil},
+		{"required blank", func(f *form) { f.Name = "   " }, []apierror.FieldError{fe("name", "is required")}},
+		{"min", func(f *form) { f.Name = "A" }, []apierror.FieldError{fe("name", "must have at least 2 characters")}},
+		{"max", func(f *form) { f.Name = "Amalia" }, []apierror.FieldError{fe("name", "must have at most 5 characters")}},
+		{"max counts characters", func(f *form) { f.Name = "ÄÖÜßé" }, nil},
+		{"oneof", func(f *form) { f.Status = "old" }, []apierror.FieldError{fe("status", "must be one of: new, done")}},
+		{"oneof ok", func(f *form) { f.Status = "done" }, nil},
+		{"url", func(f *form) { f.Website = "example.com" }, []apierror.FieldError{fe("website", "must be an http or https URL")}},
+		{"url scheme", func(f *form) { f.Website = "javascript:alert(1)" }, []apierror.FieldError{fe("website", "must be an http or https URL")}},
+		{"url ok", func(f *form) { f.Website = "https://example.com/a" }, nil},
+		{"dive", func(f *form) { f.Images = []string{"https://a.example/1.png", "", "ftp://x"} }, []apierror.FieldError{
+			fe("images", "must have at most 2 items"), fe("images[1]", "is required"), fe("images[2]", "must be an http or https URL"),
+		}},
+		{"required slice", func(f *form) { f.Tags = []string{} }, []apierror.FieldError{fe("tags", "is required")}},
+		{"max map", func(f *form) { f.Extra = map[string]string{"a": "1", "b": "2"} }, []apierror.FieldError{fe("extra", "must have at most 1 items")}},
+		{"required pointer", func(f *form) { f.Note = nil }, []apierror.FieldError{fe("note", "is required")}},
+		{"email", func(f *form) { f.Contact.Email = "amal" }, []apierror.FieldError{fe("contact.email", "must be an email address")}},
+		{"email with a name", func(f *form) { f.Contact.Email = "Amal <amal@example.com>" }, []apierror.FieldError{fe("contact.email", "must be an email address")}},
+		{"phone", func(f *form) { f.Contact.Phone = "call me" }, []apierror.FieldError{fe("contact.phone", "must be a phone number")}},
+		{"phone too short", func(f *form) { f.Contact.Phone = "12345" }, []apierror.FieldError{fe("contact.phone", "must be a phone number")}},
+		{"phone ok", func(f *form) { f.Contact.Phone = "+62 (21) 555-0100" }, nil},
+		{"nil nested pointer", func(f *form) { f.Backup = nil }, nil},
+		{"nested pointer", func(f *form) { f.Backup = &contact{} }, []apierror.FieldError{fe("backup.email", "is required")}},
+		{"one error per field", func(f *form) { f.Name = "" }, []apierror.FieldError{fe("name", "is required")}},
+		{"every field", func(f *form) { f.Name = ""; f.Status = "x"; f.Contact.Email = "" }, []apierror.FieldError{
+			fe("name", "is required"), fe("status", "must be one of: new, done"), fe("contact.email", "is required"),
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := valid()
+			tt.change(&f)
+			if got := Struct(&f); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Struct = %v, want %v", got, tt.want)
+			}
+			// Values are checked like pointers
+			if got := Struct(f); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Struct of a value = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+// ranged checks its fields together
+type ranged struct {
+	From int `json:"from"`
+	To   int `json:"to"`
+}
+
+func (r *ranged) Validate() []apierror.FieldError {
+	if r.To < r.From {
+		return []apierror.FieldError{{Field: "to", Message: "must not be before from"}}
+	}
+	return nil
+}
+
+func TestStructValidator(t *testing.T) {
+	type query struct {
+		Name  string `json:"name" validate:"required"`
+		Range ranged `json:"range"`
+	}
+	got := Struct(&query{Range: ranged{From: 2, To: 1}})
+	want := []apierror.FieldError{fe("name", "is required"), fe("range.to", "must not be before from")}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Struct = %v, want %v", got, want)
+	}
+}
+
+func TestParseTagPanics(t *testing.T) {
+	for _, tag := range []string{"requird", "max=x", "min", "oneof", "email,dive,sizes"} {
+		t.Run(tag, func(t *testing.T) {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("ParseTag(%q) did not panic", tag)
+				}
+			}()
+			ParseTag(tag)
+		})
+	}
+}
+
+func TestMap(t *testing.T) {
+	rules := map[string]string{
+		"email": "required,email",
+		"phone": "phone",
+		"name":  "max=3",
+	}
+	tests := []struct {
+		name string
+		data map[string]interface{}
+		want []apierror.FieldError
+	}{
+		{"valid", map[string]interface{}{"email": "a@example.com", "other": 1}, nil},
+		{"missing", map[string]interface{}{}, []apierror.FieldError{fe("data.email", "is required")}},
+		{"null", map[string]interface{}{"email": nil}, []apierror.FieldError{fe("data.email", "is required")}},
+		{"not a string", map[string]interface{}{"email": "a@example.com", "phone": 12345678.0},
+			[]apierror.FieldError{fe("data.phone", "must be a string")}},
+		{"sorted", map[string]interface{}{"email": "x", "name": strings.Repeat("a", 4)},
+			[]apierror.FieldError{fe("data.email", "must be an email address"), fe("data.name", "must have at most 3 characters")}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Map("data", tt.data, rules); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Map = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}